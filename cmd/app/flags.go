@@ -3,6 +3,7 @@ package app
 import (
 	"flag"
 	"path/filepath"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/homedir"
@@ -21,6 +22,26 @@ type CMDFlags struct {
 	//Development bool
 	MetricsListenAddr string `yaml:"metricslistenaddr"`
 	MetricsPath       string `yaml:"metricspath"`
+
+	WorkerStallTimeout  time.Duration `yaml:"workerstalltimeout"`
+	ShutdownGracePeriod time.Duration `yaml:"shutdowngraceperiod"`
+
+	EnableSecrets   bool `yaml:"enablesecrets"`
+	EnableInjection bool `yaml:"enableinjection"`
+
+	PublishDir             string        `yaml:"publishdir"`
+	HTTPPublishAllowedURLs string        `yaml:"httppublishallowedurls"`
+	HTTPPublishTimeout     time.Duration `yaml:"httppublishtimeout"`
+
+	WebhookListenAddr string `yaml:"webhooklistenaddr"`
+	WebhookCertDir    string `yaml:"webhookcertdir"`
+
+	LeaderElect                 bool          `yaml:"leaderelect"`
+	LeaderElectionLeaseName     string        `yaml:"leaderelectionleasename"`
+	LeaderElectionNamespace     string        `yaml:"leaderelectionnamespace"`
+	LeaderElectionLeaseDuration time.Duration `yaml:"leaderelectionleaseduration"`
+	LeaderElectionRenewDeadline time.Duration `yaml:"leaderelectionrenewdeadline"`
+	LeaderElectionRetryPeriod   time.Duration `yaml:"leaderelectionretryperiod"`
 }
 
 // Init initializes and parse the flags
@@ -39,6 +60,26 @@ func (c *CMDFlags) Init() {
 	flag.StringVar(&c.MetricsListenAddr, "metrics-address", ":9710", "Address to listen on for metrics.")
 	flag.StringVar(&c.MetricsPath, "metrics-path", "/metrics", "Path to serve the metrics.")
 
+	flag.DurationVar(&c.ShutdownGracePeriod, "shutdown-grace-period", 25*time.Second, "How long the operator waits on shutdown for the controllers to drain their workqueue and the servers their connections.")
+	flag.DurationVar(&c.WorkerStallTimeout, "worker-stall-timeout", 2*time.Minute, "How long a worker may process a single item before the liveness probe fails.")
+
+	flag.BoolVar(&c.EnableSecrets, "enable-secrets", false, "Let FeatureFlags be published to Secrets and read variation values from Secrets. The operator must be granted access to Secrets.")
+	flag.BoolVar(&c.EnableInjection, "enable-injection", false, "Let FeatureFlags be injected as environment variables into the Deployments, StatefulSets and DaemonSets they select. The operator must be granted access to patch them.")
+
+	flag.StringVar(&c.PublishDir, "publish-dir", "", "Directory under which file publishers write the payload of FeatureFlags, in a directory per namespace. File publishers are not enabled when empty.")
+	flag.StringVar(&c.HTTPPublishAllowedURLs, "http-publish-allowed-urls", "", "Comma-separated URLs that HTTP publishers may send the payload of FeatureFlags to, including any URL under their path. HTTP publishers are not enabled when empty.")
+	flag.DurationVar(&c.HTTPPublishTimeout, "http-publish-timeout", 3*time.Second, "How long an HTTP publisher waits for a response, at most 5s.")
+
+	flag.StringVar(&c.WebhookListenAddr, "webhook-address", ":9443", "Address to serve the webhooks on.")
+	flag.StringVar(&c.WebhookCertDir, "webhook-cert-dir", "", "Directory holding the tls.crt and tls.key used to serve the webhooks. The webhooks are not served when empty.")
+
+	flag.BoolVar(&c.LeaderElect, "leader-elect", false, "Elect a leader through a Lease so that only one replica runs the controllers at a time. Required when running more than one replica.")
+	flag.StringVar(&c.LeaderElectionLeaseName, "leader-election-lease-name", "featured-operator", "The name of the Lease used for leader election.")
+	flag.StringVar(&c.LeaderElectionNamespace, "leader-election-namespace", "", "The namespace of the Lease used for leader election. Defaults to $POD_NAMESPACE, then to the watched namespace.")
+	flag.DurationVar(&c.LeaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second, "How long standbys wait after the last renewal before taking over the Lease.")
+	flag.DurationVar(&c.LeaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second, "How long the leader retries renewing the Lease before giving it up.")
+	flag.DurationVar(&c.LeaderElectionRetryPeriod, "leader-election-retry-period", 2*time.Second, "How long to wait between attempts to acquire or renew the Lease.")
+
 	// Parse flags
 	flag.Parse()
 }
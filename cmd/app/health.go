@@ -0,0 +1,83 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+	// LivenessPath is the path of the liveness probe endpoint
+	LivenessPath = "/healthz"
+	// ReadinessPath is the path of the readiness probe endpoint
+	ReadinessPath = "/readyz"
+)
+
+// healthCheck is a named check, returning an error when unhealthy.
+type healthCheck struct {
+	name  string
+	check func() error
+}
+
+// healthHandler runs every check and answers 200 when all pass, or 503
+// listing the failed checks. The checks are listed with ?verbose.
+func healthHandler(checks ...healthCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, verbose := r.URL.Query()["verbose"]
+		status := http.StatusOK
+		var body string
+		for _, c := range checks {
+			if err := c.check(); err != nil {
+				status = http.StatusServiceUnavailable
+				body += fmt.Sprintf("[-]%s failed: %v\n", c.name, err)
+			} else if verbose {
+				body += fmt.Sprintf("[+]%s ok\n", c.name)
+			}
+		}
+		if status == http.StatusOK {
+			body += "ok\n"
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+}
+
+// leaderStatus records the role of this replica, which is known once the
+// leader election has observed who holds the Lease.
+type leaderStatus struct {
+	mu      sync.Mutex
+	known   bool
+	leading bool
+}
+
+func (s *leaderStatus) observed(leading bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.known = true
+	s.leading = leading
+}
+
+// roleKnown is the readiness check of the leader election.
+func (s *leaderStatus) roleKnown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.known {
+		return fmt.Errorf("leader election role not known yet")
+	}
+	return nil
+}
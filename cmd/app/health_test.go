@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestHealthHandler tests that the probes only succeed once every check passes
+func TestHealthHandler(t *testing.T) {
+	leader := &leaderStatus{}
+	synced := false
+	handler := healthHandler(
+		healthCheck{name: "informers", check: func() error {
+			if !synced {
+				return fmt.Errorf("informer caches not synced")
+			}
+			return nil
+		}},
+		healthCheck{name: "leader-election", check: leader.roleKnown},
+	)
+
+	probe := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := probe(ReadinessPath)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Equal(t, "[-]informers failed: informer caches not synced\n[-]leader-election failed: leader election role not known yet\n", w.Body.String())
+
+	synced = true
+	leader.observed(false)
+	w = probe(ReadinessPath)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "ok\n", w.Body.String())
+
+	w = probe(ReadinessPath + "?verbose")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "[+]informers ok\n[+]leader-election ok\nok\n", w.Body.String())
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaderGauge is 1 on the replica holding the Lease and 0 on standbys, so
+// the leader is the pod whose identity label has the value 1.
+var leaderGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "featured_operator",
+		Subsystem: "leader_election",
+		Name:      "leader",
+		Help:      "Whether this replica is the leader (1) or a standby (0)",
+	},
+	[]string{"identity"},
+)
+
+// leaderElectionConfig is the Lease the replicas of the operator compete for
+// and how long it is held.
+type leaderElectionConfig struct {
+	identity      string
+	leaseName     string
+	namespace     string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+}
+
+// newLeaderElectionConfig returns the leader election configuration set by
+// the flags. The Lease lives in the namespace of the operator pod unless
+// set, and the replica is identified by its pod name.
+func newLeaderElectionConfig(flags *CMDFlags) (leaderElectionConfig, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return leaderElectionConfig{}, fmt.Errorf("cannot get the leader election identity: %v", err)
+	}
+	namespace := flags.LeaderElectionNamespace
+	if namespace == "" {
+		namespace = os.Getenv("POD_NAMESPACE")
+	}
+	if namespace == "" {
+		namespace = flags.Namespace
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	return leaderElectionConfig{
+		identity:      identity,
+		leaseName:     flags.LeaderElectionLeaseName,
+		namespace:     namespace,
+		leaseDuration: flags.LeaderElectionLeaseDuration,
+		renewDeadline: flags.LeaderElectionRenewDeadline,
+		retryPeriod:   flags.LeaderElectionRetryPeriod,
+	}, nil
+}
+
+// runLeaderElection calls run while this replica holds the Lease. run must
+// return once its context is done, which happens when ctx is done or the
+// Lease is lost. The Lease is only released once run has returned, so two
+// replicas never run the controllers at the same time. runLeaderElection
+// returns nil once ctx is done, and an error if the Lease was lost as the
+// controllers cannot be restarted in the same process. The role of the
+// replica is recorded in status once the holder of the Lease is known.
+func runLeaderElection(ctx context.Context, client kubernetes.Interface, config leaderElectionConfig, status *leaderStatus, run func(ctx context.Context)) error {
+	logger := log.WithFields(log.Fields{"service": "leader-election", "lease": config.namespace + "/" + config.leaseName, "identity": config.identity})
+
+	// The election has its own context, cancelled once run has returned or
+	// on shutdown of a standby, which releases the Lease.
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+
+	var mu sync.Mutex
+	leading, stopping := false, false
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		stopping = true
+		if !leading {
+			cancelElection()
+		}
+	}()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: config.leaseName, Namespace: config.namespace},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: config.identity},
+		},
+		LeaseDuration:   config.leaseDuration,
+		RenewDeadline:   config.renewDeadline,
+		RetryPeriod:     config.retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            config.leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				defer cancelElection()
+				mu.Lock()
+				if stopping {
+					mu.Unlock()
+					return
+				}
+				leading = true
+				mu.Unlock()
+
+				logger.Info("started leading")
+				leaderGauge.WithLabelValues(config.identity).Set(1)
+				runCtx, cancelRun := context.WithCancel(ctx)
+				defer cancelRun()
+				go func() {
+					select {
+					case <-leaderCtx.Done():
+						cancelRun()
+					case <-runCtx.Done():
+					}
+				}()
+				run(runCtx)
+			},
+			OnStoppedLeading: func() {
+				leaderGauge.WithLabelValues(config.identity).Set(0)
+			},
+			OnNewLeader: func(identity string) {
+				status.observed(identity == config.identity)
+				logger.WithFields(log.Fields{"leader": identity}).Info("new leader elected")
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	leaderGauge.WithLabelValues(config.identity).Set(0)
+	elector.Run(electionCtx)
+	if ctx.Err() == nil {
+		return fmt.Errorf("lost the leader election lease %s/%s", config.namespace, config.leaseName)
+	}
+	logger.Info("stopped leader election")
+	return nil
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestLeaderElectionConfig(identity string) leaderElectionConfig {
+	return leaderElectionConfig{
+		identity:      identity,
+		leaseName:     "featured-operator",
+		namespace:     "featured-operator",
+		leaseDuration: 2 * time.Second,
+		renewDeadline: time.Second,
+		retryPeriod:   100 * time.Millisecond,
+	}
+}
+
+// candidate runs the leader election for one replica, recording when its
+// controllers start and stop.
+type candidate struct {
+	started chan struct{}
+	stopped chan struct{}
+	done    chan error
+	cancel  context.CancelFunc
+	status  *leaderStatus
+}
+
+func startCandidate(client *fake.Clientset, identity string) *candidate {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &candidate{started: make(chan struct{}), stopped: make(chan struct{}), done: make(chan error, 1), cancel: cancel, status: &leaderStatus{}}
+	go func() {
+		c.done <- runLeaderElection(ctx, client, newTestLeaderElectionConfig(identity), c.status, func(ctx context.Context) {
+			close(c.started)
+			<-ctx.Done()
+			close(c.stopped)
+		})
+	}()
+	return c
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+// TestLeaderElectionHandover tests that a standby only takes over once the leader has stopped and released the Lease
+func TestLeaderElectionHandover(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	a := startCandidate(client, "operator-a")
+	waitFor(t, a.started, "operator-a to lead")
+	require.Equal(t, 1.0, testutil.ToFloat64(leaderGauge.WithLabelValues("operator-a")))
+
+	b := startCandidate(client, "operator-b")
+	select {
+	case <-b.started:
+		t.Fatal("operator-b started while operator-a holds the Lease")
+	case <-time.After(500 * time.Millisecond):
+	}
+	require.Equal(t, 0.0, testutil.ToFloat64(leaderGauge.WithLabelValues("operator-b")))
+	require.NoError(t, b.status.roleKnown())
+	require.False(t, b.status.leading)
+
+	// Shutting operator-a down hands the Lease over without waiting for it
+	// to expire.
+	a.cancel()
+	require.NoError(t, <-a.done)
+	waitFor(t, b.started, "operator-b to lead")
+	select {
+	case <-a.stopped:
+	default:
+		t.Fatal("operator-b started before operator-a stopped")
+	}
+	require.Equal(t, 0.0, testutil.ToFloat64(leaderGauge.WithLabelValues("operator-a")))
+	require.Equal(t, 1.0, testutil.ToFloat64(leaderGauge.WithLabelValues("operator-b")))
+
+	lease, err := client.CoordinationV1().Leases("featured-operator").Get(context.TODO(), "featured-operator", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "operator-b", *lease.Spec.HolderIdentity)
+
+	b.cancel()
+	require.NoError(t, <-b.done)
+	waitFor(t, b.stopped, "operator-b to stop")
+}
+
+// TestLeaderElectionStandbyShutdown tests that a standby that never led shuts down cleanly
+func TestLeaderElectionStandbyShutdown(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	a := startCandidate(client, "operator-a")
+	waitFor(t, a.started, "operator-a to lead")
+	b := startCandidate(client, "operator-b")
+
+	b.cancel()
+	require.NoError(t, <-b.done)
+
+	a.cancel()
+	require.NoError(t, <-a.done)
+}
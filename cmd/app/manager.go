@@ -0,0 +1,167 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runnable is a component of the operator run by the manager. Start blocks
+// until ctx is done or the component fails, and only returns once the
+// component has stopped. An error stops the whole operator.
+type runnable interface {
+	Start(ctx context.Context) error
+}
+
+// runnableFunc lets a function be used as a runnable.
+type runnableFunc func(ctx context.Context) error
+
+// Start calls f(ctx).
+func (f runnableFunc) Start(ctx context.Context) error {
+	return f(ctx)
+}
+
+// component is a runnable registered with the manager, with the context
+// used to stop it and a channel receiving its result.
+type component struct {
+	name     string
+	runnable runnable
+	cancel   context.CancelFunc
+	done     chan error
+}
+
+// manager starts the components of the operator under one context. When
+// the context is done or the first component fails, the components are
+// stopped one at a time in the reverse order they were added, so that the
+// controllers stop before the informers and servers they depend on.
+type manager struct {
+	gracePeriod time.Duration
+	components  []*component
+	logger      *log.Entry
+}
+
+// newManager returns a manager giving its components gracePeriod in total
+// to stop.
+func newManager(gracePeriod time.Duration) *manager {
+	return &manager{
+		gracePeriod: gracePeriod,
+		logger:      log.WithFields(log.Fields{"service": "manager"}),
+	}
+}
+
+// Add registers the runnable under name. Components are started in the
+// order they are added.
+func (m *manager) Add(name string, r runnable) {
+	m.components = append(m.components, &component{name: name, runnable: r})
+}
+
+// Run starts every component and blocks until ctx is done or a component
+// fails, then stops the components. It returns the first error of a
+// component, or an error if the components did not stop within the grace
+// period.
+func (m *manager) Run(ctx context.Context) error {
+	var mu sync.Mutex
+	var firstErr error
+	failed := make(chan struct{})
+	fail := func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", name, err)
+			close(failed)
+		}
+	}
+	result := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr
+	}
+
+	for _, c := range m.components {
+		c := c
+		var componentCtx context.Context
+		componentCtx, c.cancel = context.WithCancel(context.Background())
+		c.done = make(chan error, 1)
+		m.logger.WithFields(log.Fields{"component": c.name}).Info("starting")
+		go func() {
+			err := c.runnable.Start(componentCtx)
+			if err != nil {
+				fail(c.name, err)
+			}
+			c.done <- err
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		m.logger.Info("shutting down")
+	case <-failed:
+		m.logger.Errorf("shutting down after an error: %v", result())
+	}
+
+	deadline := time.NewTimer(m.gracePeriod)
+	defer deadline.Stop()
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c := m.components[i]
+		logger := m.logger.WithFields(log.Fields{"component": c.name})
+		logger.Info("stopping")
+		c.cancel()
+		select {
+		case <-c.done:
+			logger.Info("stopped")
+		case <-deadline.C:
+			for _, c := range m.components[:i] {
+				c.cancel()
+			}
+			return fmt.Errorf("%s did not stop within the grace period of %s", c.name, m.gracePeriod)
+		}
+	}
+
+	return result()
+}
+
+// httpServer returns a runnable serving server. On shutdown it stops
+// accepting connections and waits up to gracePeriod for the requests in
+// flight.
+func httpServer(listenAndServe func() error, shutdown func(context.Context) error, gracePeriod time.Duration) runnable {
+	return runnableFunc(func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- listenAndServe()
+		}()
+
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the order in which components stop.
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) component(name string) runnable {
+	return runnableFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.stopped = append(r.stopped, name)
+		return nil
+	})
+}
+
+// TestManagerStopsInReverseOrder tests that components stop one at a time, last added first
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	r := &recorder{}
+	mgr := newManager(time.Second)
+	mgr.Add("servers", r.component("servers"))
+	mgr.Add("informers", r.component("informers"))
+	mgr.Add("controllers", r.component("controllers"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, mgr.Run(ctx))
+	require.Equal(t, []string{"controllers", "informers", "servers"}, r.stopped)
+}
+
+// TestManagerFirstError tests that the first failing component shuts the others down and its error is returned
+func TestManagerFirstError(t *testing.T) {
+	r := &recorder{}
+	mgr := newManager(time.Second)
+	mgr.Add("servers", r.component("servers"))
+	mgr.Add("webhooks", runnableFunc(func(ctx context.Context) error {
+		return errors.New("address already in use")
+	}))
+	mgr.Add("controllers", r.component("controllers"))
+
+	err := mgr.Run(context.Background())
+	require.EqualError(t, err, "webhooks: address already in use")
+	require.Equal(t, []string{"controllers", "servers"}, r.stopped)
+}
+
+// TestManagerGracePeriod tests that the manager gives up on a component that does not stop within the grace period
+func TestManagerGracePeriod(t *testing.T) {
+	r := &recorder{}
+	stuck := make(chan struct{})
+	defer close(stuck)
+	mgr := newManager(100 * time.Millisecond)
+	mgr.Add("servers", r.component("servers"))
+	mgr.Add("controllers", runnableFunc(func(ctx context.Context) error {
+		<-stuck
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.EqualError(t, mgr.Run(ctx), "controllers did not stop within the grace period of 100ms")
+}
+
+// TestHTTPServerShutdown tests that a request in flight is served before the server stops
+func TestHTTPServerShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-release
+		w.Write([]byte("ok"))
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- httpServer(func() error { return server.Serve(listener) }, server.Shutdown, time.Second).Start(ctx)
+	}()
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+		response <- resp
+	}()
+	waitFor(t, inFlight, "the request to be in flight")
+
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("the server stopped with a request in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-done)
+	resp := <-response
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -17,23 +17,31 @@ package app
 import (
 	// "math/rand"
 
+	"context"
+	"fmt"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	// "sync"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 
 	featurecontroller "github.com/featured.io/pkg/controllers/feature"
 	featureclientset "github.com/featured.io/pkg/generated/clientset/versioned"
+	featurescheme "github.com/featured.io/pkg/generated/clientset/versioned/scheme"
 	featureinformers "github.com/featured.io/pkg/generated/informers/externalversions"
+	"github.com/featured.io/pkg/webhook"
 )
 
-// Run starts the mysql-operator controllers. This should never exit.
+// Run starts the featured.io operator and blocks until it is shut down by a
+// signal or a component fails. Every component is stopped before it returns.
 func Run(flags *CMDFlags) error {
 	log.Infof("options: %v", flags)
 
@@ -45,11 +53,12 @@ func Run(flags *CMDFlags) error {
 
 	// Initialise the operator metrics.
 	featurecontroller.RegisterMetrics()
-	http.Handle(flags.MetricsPath, promhttp.Handler())
-	go http.ListenAndServe(flags.MetricsListenAddr, nil)
+	prometheus.MustRegister(leaderGauge)
 
-	// Set up signals so we handle the first shutdown signal gracefully.
-	stopCh := SetupSignalHandler()
+	// Every component runs under the manager, which stops them in the reverse
+	// order they are added: the controllers first, then the informers they
+	// read from, and the servers last so probes are answered until the end.
+	mgr := newManager(flags.ShutdownGracePeriod)
 
 	// Set up clients
 	kubeClient := kubernetes.NewForConfigOrDie(kubeconfig)
@@ -72,24 +81,129 @@ func Run(flags *CMDFlags) error {
 	// 10 hours is the resync period used by sigs.k8s.io/controller-runtime.
 	var noResyncPeriodFunc = func() time.Duration { return 10 * time.Minute }
 	i := featureinformers.NewFilteredSharedInformerFactory(featureClient, noResyncPeriodFunc(), flags.Namespace, nil)
-	k8sI := kubeinformers.NewFilteredSharedInformerFactory(kubeClient, noResyncPeriodFunc(), flags.Namespace, nil)
+	// Only the ConfigMaps labelled by the operator are watched, rather than
+	// every ConfigMap of the cluster.
+	k8sI := kubeinformers.NewFilteredSharedInformerFactory(kubeClient, noResyncPeriodFunc(), flags.Namespace, featurecontroller.ManagedListOptions)
+	// Namespaces are cluster scoped, so when a single namespace is watched
+	// ClusterFeatureFlags are only published to it by selecting it by name.
+	namespaceI := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, noResyncPeriodFunc(),
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			if flags.Namespace != "" {
+				options.FieldSelector = fields.OneTermEqualSelector("metadata.name", flags.Namespace).String()
+			}
+		}))
+
+	// Every app is watched for FeatureFlags to be injected into, as their
+	// labels are what FeatureFlags select. Nothing is watched unless
+	// injection is enabled.
+	appsI := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, noResyncPeriodFunc(), kubeinformers.WithNamespace(flags.Namespace))
+
+	// Every Secret is watched for the values FeatureFlags read from them, as
+	// those Secrets are not labelled by the operator. Nothing is watched
+	// unless Secrets are enabled.
+	secretI := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, noResyncPeriodFunc(), kubeinformers.WithNamespace(flags.Namespace))
 
 	featureController := featurecontroller.NewFeatureController(
 		kubeClient,
 		featureClient,
 		k8sI.Core().V1().ConfigMaps(),
+		namespaceI.Core().V1().Namespaces(),
 		i.Featurecontroller().V1alpha1().FeatureFlags(),
+		i.Featurecontroller().V1alpha1().FeatureSegments(),
+		i.Featurecontroller().V1alpha1().ClusterFeatureFlags(),
 	)
+	if flags.EnableSecrets {
+		featureController.EnableSecrets(secretI.Core().V1().Secrets())
+	}
+	if flags.EnableInjection {
+		featureController.EnableInjection(appsI.Apps().V1().Deployments(), appsI.Apps().V1().StatefulSets(), appsI.Apps().V1().DaemonSets())
+	}
+	if flags.PublishDir != "" {
+		featureController.EnableFilePublishers(flags.PublishDir)
+	}
+	if flags.HTTPPublishAllowedURLs != "" {
+		allowedURLs := strings.Split(flags.HTTPPublishAllowedURLs, ",")
+		if err := featureController.EnableHTTPPublishers(allowedURLs, flags.HTTPPublishTimeout); err != nil {
+			return fmt.Errorf("invalid --http-publish-allowed-urls: %v", err)
+		}
+	}
+
+	// Serve the metrics and the probes. The operator is live while its
+	// workers make progress, and ready once its caches have synced and, under
+	// leader election, it knows whether it leads or stands by.
+	leader := &leaderStatus{}
+	readiness := []healthCheck{{name: "informers", check: func() error {
+		if !featureController.HasSynced() {
+			return fmt.Errorf("informer caches not synced")
+		}
+		return nil
+	}}}
+	if flags.LeaderElect {
+		readiness = append(readiness, healthCheck{name: "leader-election", check: leader.roleKnown})
+	}
+	mux := http.NewServeMux()
+	mux.Handle(flags.MetricsPath, promhttp.Handler())
+	mux.Handle(LivenessPath, healthHandler(healthCheck{name: "workers", check: func() error {
+		return featureController.CheckWorkers(flags.WorkerStallTimeout)
+	}}))
+	mux.Handle(ReadinessPath, healthHandler(readiness...))
+	metricsServer := &http.Server{Addr: flags.MetricsListenAddr, Handler: mux}
+	mgr.Add("metrics", httpServer(metricsServer.ListenAndServe, metricsServer.Shutdown, flags.ShutdownGracePeriod))
+
+	// Serve the webhooks, which the API server calls to convert FeatureFlags
+	// between the versions of the CRD and to default and validate them on
+	// admission.
+	if flags.WebhookCertDir != "" {
+		webhookServer := webhook.NewServer(flags.WebhookListenAddr, flags.WebhookCertDir)
+		webhookServer.Handle(webhook.ConversionPath, webhook.NewConverter(featurescheme.Scheme))
+		webhookServer.Handle(webhook.ValidatingPath, webhook.NewFeatureFlagValidator(featurescheme.Scheme))
+		webhookServer.Handle(webhook.DefaultingPath, webhook.NewFeatureFlagDefaulter(featurescheme.Scheme))
+		mgr.Add("webhooks", httpServer(webhookServer.ListenAndServe, webhookServer.Shutdown, flags.ShutdownGracePeriod))
+	}
 
 	// notice that there is no need to run Start methods in a separate goroutine. (i.e. go kubeInformerFactory.Start(stopCh)
 	// Start method is non-blocking and runs all registered informers in a dedicated goroutine.
-	k8sI.Start(stopCh)
-	i.Start(stopCh)
-
-	if err = featureController.Run(2, stopCh); err != nil {
-		log.Fatalf("Error running controller: %s", err.Error())
-		os.Exit(1)
+	// The informers are started on every replica, so that standbys keep warm
+	// caches and take over quickly once elected.
+	mgr.Add("informers", runnableFunc(func(ctx context.Context) error {
+		k8sI.Start(ctx.Done())
+		namespaceI.Start(ctx.Done())
+		appsI.Start(ctx.Done())
+		secretI.Start(ctx.Done())
+		i.Start(ctx.Done())
+		featureController.WaitForCacheSync(ctx.Done())
+		<-ctx.Done()
+		return nil
+	}))
+
+	// Only the replica holding the Lease runs the controllers, so replicas
+	// never fight over the same ConfigMaps and statuses. Run returns once the
+	// workers have drained the workqueue.
+	var config leaderElectionConfig
+	if flags.LeaderElect {
+		if config, err = newLeaderElectionConfig(flags); err != nil {
+			return err
+		}
 	}
+	mgr.Add("controllers", runnableFunc(func(ctx context.Context) error {
+		if !flags.LeaderElect {
+			return featureController.Run(2, ctx.Done())
+		}
+		return runLeaderElection(ctx, kubeClient, config, leader, func(ctx context.Context) {
+			if err := featureController.Run(2, ctx.Done()); err != nil {
+				log.Errorf("Error running controller: %s", err.Error())
+			}
+		})
+	}))
 
-	return nil
+	// Set up signals so we handle the first shutdown signal gracefully.
+	stopCh := SetupSignalHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	return mgr.Run(ctx)
 }
@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/coreos/go-semver v0.3.0
+	github.com/google/gofuzz v1.1.0
 	github.com/gruntwork-io/terratest v0.26.3
 	github.com/prometheus/client_golang v1.5.1
 	github.com/sirupsen/logrus v1.5.0
@@ -17,4 +18,5 @@ require (
 	k8s.io/client-go v0.18.1
 	k8s.io/klog v1.0.0
 	k8s.io/utils v0.0.0-20200410160548-5d1a62b6259f // indirect
+	sigs.k8s.io/yaml v1.2.0
 )
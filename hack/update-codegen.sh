@@ -30,9 +30,31 @@ echo -e "SCRIPT_ROOT: ${SCRIPT_ROOT}"
 #                  instead of the $GOPATH directly. For normal projects this can be dropped.
 bash "${CODEGEN_PKG}"/generate-groups.sh "deepcopy,client,informer,lister" \
   github.com/featured.io/pkg/generated github.com/featured.io/pkg/apis \
-  feature:v1alpha1 \
+  feature:v1alpha1,v1beta1 \
   --output-base "$(dirname "${BASH_SOURCE[0]}")/../../.." \
   --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate.go.txt
 
+# v1beta1 converts to and from the v1alpha1 hub with generated functions, and
+# v1alpha1 registers the defaults applied by the mutating webhook.
+(cd "${CODEGEN_PKG}" && go install ./cmd/{conversion-gen,defaulter-gen})
+"${GOBIN:-$(go env GOPATH)/bin}"/conversion-gen \
+  --input-dirs github.com/featured.io/pkg/apis/feature/v1beta1 \
+  -O zz_generated.conversion \
+  --output-base "$(dirname "${BASH_SOURCE[0]}")/../../.." \
+  --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate.go.txt
+"${GOBIN:-$(go env GOPATH)/bin}"/defaulter-gen \
+  --input-dirs github.com/featured.io/pkg/apis/feature/v1alpha1 \
+  -O zz_generated.defaults \
+  --output-base "$(dirname "${BASH_SOURCE[0]}")/../../.." \
+  --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate.go.txt
+
+# The CRDs are generated from the API types and their kubebuilder markers. The
+# FeatureFlag CRD is moved to files/, from which templates/crd-featureflags.yaml
+# installs it with the conversion webhook set from the values.
+CHART_DIR="${SCRIPT_ROOT}/helm/featured-operator"
+(cd "${SCRIPT_ROOT}" && go run sigs.k8s.io/controller-tools/cmd/controller-gen@v0.17.3 \
+  crd:crdVersions=v1 paths=./pkg/apis/feature/... output:crd:dir=./helm/featured-operator/crds)
+mv "${CHART_DIR}"/crds/featurecontroller.featured.io_featureflags.yaml "${CHART_DIR}"/files/
+
 # To use your own boilerplate text append:
 #   --go-header-file "${SCRIPT_ROOT}"/hack/custom-boilerplate.go.txt
@@ -0,0 +1,34 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featurecontroller
+
+import "k8s.io/apimachinery/pkg/runtime"
+
+// Hub is implemented by the kinds of the version every other version
+// converts through. Converting between two spokes goes through the hub.
+type Hub interface {
+	runtime.Object
+	Hub()
+}
+
+// Convertible is implemented by the kinds of every version other than the
+// hub.
+type Convertible interface {
+	runtime.Object
+	// ConvertTo converts the object to the hub version
+	ConvertTo(hub Hub) error
+	// ConvertFrom converts the object from the hub version
+	ConvertFrom(hub Hub) error
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// ManagedByLabel is the standard label naming the tool that manages an object
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedBy is the ManagedByLabel value set on FeatureFlags without one
+	ManagedBy = "featured-operator"
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_FeatureFlag fills in what a FeatureFlag leaves implicit, so the
+// stored object says exactly what the controller publishes. Fields that are
+// already set are never changed.
+func SetDefaults_FeatureFlag(obj *FeatureFlag) {
+	if _, ok := obj.Labels[ManagedByLabel]; !ok {
+		if obj.Labels == nil {
+			obj.Labels = map[string]string{}
+		}
+		obj.Labels[ManagedByLabel] = ManagedBy
+	}
+
+	spec := &obj.Spec
+	if spec.Target == "" {
+		spec.Target = PublishTargetConfigMap
+	}
+	switch {
+	case spec.Target == PublishTargetSecret && spec.SecretName == "":
+		spec.SecretName = obj.Name
+	case spec.Target == PublishTargetConfigMap && spec.ConfigMapName == "":
+		spec.ConfigMapName = obj.Name
+	}
+	if spec.Format == "" {
+		spec.Format = PayloadFormatJSON
+	}
+	for i := range spec.Publishers {
+		if http := spec.Publishers[i].HTTP; http != nil && http.Method == "" {
+			http.Method = HTTPMethodPut
+		}
+	}
+	if spec.AppSelector != nil && spec.Injection == "" {
+		spec.Injection = InjectionValue
+	}
+
+	// A boolean flag only has two possible values, so its variations can be
+	// generated.
+	if spec.Type == FlagTypeBoolean && len(spec.Variations) == 0 {
+		spec.Variations = []Variation{
+			{Name: "on", Value: "true"},
+			{Name: "off", Value: "false"},
+		}
+		if spec.DefaultVariation == "" {
+			spec.DefaultVariation = "on"
+		}
+		if spec.OffVariation == "" {
+			spec.OffVariation = "off"
+		}
+	}
+
+	// Rollouts are salted with the flag name unless they set their own salt.
+	// The name is unknown until the API server generates it.
+	if obj.Name == "" {
+		return
+	}
+	setDefaultSalt(spec.DefaultRollout, obj.Name)
+	for i := range spec.Rules {
+		setDefaultSalt(spec.Rules[i].Rollout, obj.Name)
+	}
+	for i := range spec.Schedule {
+		setDefaultSalt(spec.Schedule[i].DefaultRollout, obj.Name)
+	}
+}
+
+func setDefaultSalt(rollout *Rollout, name string) {
+	if rollout != nil && rollout.Salt == "" {
+		rollout.Salt = name
+	}
+}
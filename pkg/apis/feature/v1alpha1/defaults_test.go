@@ -0,0 +1,115 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestSetDefaultsFeatureFlag tests that a boolean FeatureFlag without variations is made explicit
+func TestSetDefaultsFeatureFlag(t *testing.T) {
+	featureflag := &FeatureFlag{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-checkout"},
+		Spec: FeatureFlagSpec{
+			FlagSpec: FlagSpec{
+				Type:           FlagTypeBoolean,
+				DefaultRollout: &Rollout{},
+				Rules:          []Rule{{Variation: "on"}, {Rollout: &Rollout{Salt: "keep"}}},
+			},
+		},
+	}
+
+	SetObjectDefaults_FeatureFlag(featureflag)
+
+	require.Equal(t, map[string]string{ManagedByLabel: ManagedBy}, featureflag.Labels)
+	require.Equal(t, PublishTargetConfigMap, featureflag.Spec.Target)
+	require.Equal(t, "new-checkout", featureflag.Spec.ConfigMapName)
+	require.Empty(t, featureflag.Spec.SecretName)
+	require.Equal(t, PayloadFormatJSON, featureflag.Spec.Format)
+	require.Equal(t, []Variation{{Name: "on", Value: "true"}, {Name: "off", Value: "false"}}, featureflag.Spec.Variations)
+	require.Equal(t, "on", featureflag.Spec.DefaultVariation)
+	require.Equal(t, "off", featureflag.Spec.OffVariation)
+	require.Equal(t, "new-checkout", featureflag.Spec.DefaultRollout.Salt)
+	require.Nil(t, featureflag.Spec.Rules[0].Rollout)
+	require.Equal(t, "keep", featureflag.Spec.Rules[1].Rollout.Salt)
+}
+
+// TestSetDefaultsFeatureFlagExplicit tests that fields already set are never changed
+func TestSetDefaultsFeatureFlagExplicit(t *testing.T) {
+	featureflag := &FeatureFlag{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "flag-", Labels: map[string]string{ManagedByLabel: "helm"}},
+		Spec: FeatureFlagSpec{
+			ConfigMapName: "flags",
+			FlagSpec: FlagSpec{
+				Type:             FlagTypeString,
+				Format:           PayloadFormatYAML,
+				Target:           PublishTargetConfigMap,
+				Variations:       []Variation{{Name: "blue", Value: "blue"}},
+				DefaultVariation: "blue",
+				DefaultRollout:   &Rollout{},
+			},
+		},
+	}
+	expected := featureflag.DeepCopy()
+
+	SetObjectDefaults_FeatureFlag(featureflag)
+
+	require.Equal(t, expected, featureflag)
+}
+
+// TestSetDefaultsFeatureFlagSecret tests that a FeatureFlag published to a Secret is given a Secret name rather than a ConfigMap name
+func TestSetDefaultsFeatureFlagSecret(t *testing.T) {
+	featureflag := &FeatureFlag{
+		ObjectMeta: metav1.ObjectMeta{Name: "partner-api"},
+		Spec: FeatureFlagSpec{
+			FlagSpec: FlagSpec{
+				Target:     PublishTargetSecret,
+				Type:       FlagTypeString,
+				Variations: []Variation{{Name: "primary", ValueFrom: &VariationSource{}}},
+			},
+		},
+	}
+
+	SetObjectDefaults_FeatureFlag(featureflag)
+
+	require.Equal(t, "partner-api", featureflag.Spec.SecretName)
+	require.Empty(t, featureflag.Spec.ConfigMapName)
+}
+
+// TestSetDefaultsFeatureFlagPublishers tests that HTTP publishers default to PUT
+func TestSetDefaultsFeatureFlagPublishers(t *testing.T) {
+	featureflag := &FeatureFlag{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-checkout"},
+		Spec: FeatureFlagSpec{
+			FlagSpec: FlagSpec{
+				Type: FlagTypeBoolean,
+				Publishers: []Publisher{
+					{Name: "edge", HTTP: &HTTPPublisher{URL: "https://edge.example.com/flags"}},
+					{Name: "legacy", HTTP: &HTTPPublisher{URL: "https://legacy.example.com/flags", Method: HTTPMethodPost}},
+					{Name: "volume", File: &FilePublisher{}},
+				},
+			},
+		},
+	}
+
+	SetObjectDefaults_FeatureFlag(featureflag)
+
+	require.Equal(t, HTTPMethodPut, featureflag.Spec.Publishers[0].HTTP.Method)
+	require.Equal(t, HTTPMethodPost, featureflag.Spec.Publishers[1].HTTP.Method)
+	require.Equal(t, &FilePublisher{}, featureflag.Spec.Publishers[2].File)
+}
+
+// TestSetDefaultsFeatureFlagInjection tests that flags with an app selector are injected by value
+func TestSetDefaultsFeatureFlagInjection(t *testing.T) {
+	featureflag := &FeatureFlag{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-checkout"},
+		Spec:       FeatureFlagSpec{FlagSpec: FlagSpec{Type: FlagTypeBoolean}},
+	}
+	SetObjectDefaults_FeatureFlag(featureflag)
+	require.Empty(t, featureflag.Spec.Injection)
+
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	SetObjectDefaults_FeatureFlag(featureflag)
+	require.Equal(t, InjectionValue, featureflag.Spec.Injection)
+}
@@ -15,7 +15,10 @@ limitations under the License.
 */
 
 // +k8s:deepcopy-gen=package
+// +k8s:defaulter-gen=TypeMeta
 // +groupName=featurecontroller.featured.io
 
-// Package v1alpha1 is the v1alpha1 version of the API.
+// Package v1alpha1 is the v1alpha1 version of the API. It is the hub that
+// every other version converts through, and the version stored by the API
+// server.
 package v1alpha1 // import "github.com/featured.io/pkg/apis/featurecontroller/v1alpha1"
@@ -17,30 +17,507 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=ff
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Enabled",type=boolean,JSONPath=`.spec.enabled`
+// +kubebuilder:printcolumn:name="Rollout %",type=integer,JSONPath=`.status.rolloutPlan.currentWeight`,description="Percentage of contexts served the rollout plan variation"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // FeatureFlag is a specification for a FeatureFlag resource
 type FeatureFlag struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   FeatureFlagSpec   `json:"spec"`
+	Spec FeatureFlagSpec `json:"spec"`
+	// +optional
 	Status FeatureFlagStatus `json:"status"`
 }
 
+// FlagType is the type of value served by a FeatureFlag
+// +kubebuilder:validation:Enum=boolean;string;number;json
+type FlagType string
+
+const (
+	// FlagTypeBoolean flags serve "true" or "false"
+	FlagTypeBoolean FlagType = "boolean"
+	// FlagTypeString flags serve arbitrary strings
+	FlagTypeString FlagType = "string"
+	// FlagTypeNumber flags serve integer or floating point numbers
+	FlagTypeNumber FlagType = "number"
+	// FlagTypeJSON flags serve a JSON document
+	FlagTypeJSON FlagType = "json"
+)
+
+// PayloadFormat is the serialization used when publishing a FeatureFlag
+// +kubebuilder:validation:Enum=json;yaml;dotenv;properties
+type PayloadFormat string
+
+const (
+	// PayloadFormatJSON publishes the flag as a JSON document
+	PayloadFormatJSON PayloadFormat = "json"
+	// PayloadFormatYAML publishes the flag as a YAML document
+	PayloadFormatYAML PayloadFormat = "yaml"
+	// PayloadFormatDotenv publishes the flag as KEY=value lines
+	PayloadFormatDotenv PayloadFormat = "dotenv"
+	// PayloadFormatProperties publishes the flag as a Java properties file
+	PayloadFormatProperties PayloadFormat = "properties"
+)
+
+// PublishTarget is the kind of object a FeatureFlag is published to
+// +kubebuilder:validation:Enum=ConfigMap;Secret
+type PublishTarget string
+
+const (
+	// PublishTargetConfigMap publishes the flag to the ConfigMap named by
+	// ConfigMapName
+	PublishTargetConfigMap PublishTarget = "ConfigMap"
+	// PublishTargetSecret publishes the flag to the Secret named by
+	// SecretName, for payloads holding sensitive values
+	PublishTargetSecret PublishTarget = "Secret"
+)
+
+// Publisher is an additional target the payload of a FeatureFlag is
+// published to, for consumers outside of Kubernetes. Exactly one of File and
+// HTTP must be set.
+type Publisher struct {
+	// Name identifies the publisher in the status of the FeatureFlag
+	Name string `json:"name"`
+	// File writes the payload to a file on the volume the operator publishes
+	// files to
+	// +optional
+	File *FilePublisher `json:"file,omitempty"`
+	// HTTP sends the payload to a URL whenever it changes
+	// +optional
+	HTTP *HTTPPublisher `json:"http,omitempty"`
+}
+
+// FilePublisher writes the payload to a file in the directory of the
+// namespace of the FeatureFlag
+type FilePublisher struct {
+	// Path of the file, relative to the directory of the namespace. Defaults
+	// to the key the payload is published under, e.g. new-checkout.json.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// HTTPMethod is the method of the requests sent by an HTTPPublisher
+// +kubebuilder:validation:Enum=PUT;POST
+type HTTPMethod string
+
+const (
+	// HTTPMethodPut replaces the resource at the URL with the payload
+	HTTPMethodPut HTTPMethod = "PUT"
+	// HTTPMethodPost posts the payload to the URL
+	HTTPMethodPost HTTPMethod = "POST"
+)
+
+// HTTPPublisher sends the payload as the body of a request to URL
+type HTTPPublisher struct {
+	// URL the payload is sent to. It must be allowed by the operator.
+	URL string `json:"url"`
+	// Method of the request. Defaults to PUT.
+	// +optional
+	Method HTTPMethod `json:"method,omitempty"`
+}
+
+// InjectionMode is how a FeatureFlag is injected into the containers of
+// the apps it selects
+// +kubebuilder:validation:Enum=Value;Reference
+type InjectionMode string
+
+const (
+	// InjectionValue sets the variable to the value served to contexts
+	// matching no rule, so the apps are rolled out when it changes
+	InjectionValue InjectionMode = "Value"
+	// InjectionReference reads the variable from the key the payload is
+	// published under, so running pods keep the payload they started with
+	InjectionReference InjectionMode = "Reference"
+)
+
 // FeatureFlagSpec is the spec for a FeatureFlag resource
 type FeatureFlagSpec struct {
+	// ConfigMapName is the ConfigMap the flag is published to when Target
+	// is ConfigMap. Defaults to the flag name.
+	// +optional
 	ConfigMapName string `json:"configmapName"`
-	Replicas      *int32 `json:"replicas"`
+
+	FlagSpec `json:",inline"`
+}
+
+// FlagSpec is the part of the FeatureFlagSpec that is the same in every
+// version of the API. Only the fields that differ between versions are kept
+// out of it.
+type FlagSpec struct {
+	// Format is the serialization of the flag in the ConfigMap. Defaults to json.
+	// +optional
+	Format PayloadFormat `json:"format,omitempty"`
+	// Target is the kind of object the flag is published to. Defaults to
+	// ConfigMap.
+	// +optional
+	Target PublishTarget `json:"target,omitempty"`
+	// SecretName is the Secret the flag is published to when Target is
+	// Secret. Defaults to the flag name.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// Publishers are additional targets the payload is published to. A
+	// failing publisher does not hold back the others. Flags published to a
+	// Secret cannot have publishers.
+	// +optional
+	Publishers []Publisher `json:"publishers,omitempty"`
+	// AppSelector selects the Deployments, StatefulSets and DaemonSets in the
+	// namespace of the flag whose containers are given the flag as an
+	// environment variable, e.g. FEATURE_NEW_CHECKOUT. The variable is
+	// removed from apps that are no longer selected.
+	// +optional
+	AppSelector *metav1.LabelSelector `json:"appSelector,omitempty"`
+	// Injection is how the flag is injected into the apps selected by
+	// AppSelector. Defaults to Value.
+	// +optional
+	Injection InjectionMode `json:"injection,omitempty"`
+
+	// Type is the type of every variation value of the flag
+	Type FlagType `json:"type"`
+	// Enabled switches the flag on. When off the flag always serves OffVariation.
+	// +optional
+	Enabled bool `json:"enabled"`
+	// Variations are the named values the flag can serve. Boolean flags
+	// default to "on" and "off".
+	// +optional
+	Variations []Variation `json:"variations"`
+	// DefaultVariation is the name of the variation served when the flag is on
+	// +optional
+	DefaultVariation string `json:"defaultVariation"`
+	// DefaultRollout, when set, splits the contexts that match no rule between
+	// variations. Contexts without the bucketing attribute, and consumers that
+	// do not evaluate rules, are served DefaultVariation.
+	DefaultRollout *Rollout `json:"defaultRollout,omitempty"`
+	// OffVariation is the name of the variation served when the flag is off
+	// +optional
+	OffVariation string `json:"offVariation"`
+	// Prerequisites are flags in the same namespace that must resolve to a
+	// given variation before the rules are evaluated. The flag serves
+	// OffVariation while any prerequisite is not met.
+	Prerequisites []Prerequisite `json:"prerequisites,omitempty"`
+	// Rules are evaluated in order when the flag is on. The first rule that
+	// matches the evaluation context decides the variation served.
+	Rules []Rule `json:"rules,omitempty"`
+	// Schedule lists changes applied over the spec once they are due.
+	Schedule []ScheduledChange `json:"schedule,omitempty"`
+	// RolloutPlan, when set, rolls Variation out to contexts matching no rule
+	// in steps, replacing DefaultRollout.
+	RolloutPlan *RolloutPlan `json:"rolloutPlan,omitempty"`
+}
+
+// RolloutPlanAnnotation can be set on a FeatureFlag to pause, resume or abort
+// its RolloutPlan without editing the spec. It overrides Paused and Aborted.
+const RolloutPlanAnnotation = "featureflags.featured.io/rollout-plan"
+
+const (
+	// RolloutPlanPause pauses the rollout plan at its current step
+	RolloutPlanPause = "pause"
+	// RolloutPlanResume resumes a paused rollout plan
+	RolloutPlanResume = "resume"
+	// RolloutPlanAbort aborts the rollout plan
+	RolloutPlanAbort = "abort"
+)
+
+// AdoptAnnotation can be set to "true" on a FeatureFlag, or on the ConfigMap
+// it names, to let the FeatureFlag take over an existing ConfigMap without a
+// controller, such as one created by hand or by Helm. The original data of
+// the ConfigMap is backed up when it is adopted.
+const AdoptAnnotation = "featureflags.featured.io/adopt"
+
+// OnDeleteAnnotation can be set on a FeatureFlag to choose what happens to
+// the ConfigMap it controls when the FeatureFlag is deleted.
+const OnDeleteAnnotation = "featureflags.featured.io/on-delete"
+
+const (
+	// OnDeleteDelete deletes the ConfigMap, which is the default
+	OnDeleteDelete = "delete"
+	// OnDeleteRelease hands the ConfigMap back: the FeatureFlag stops owning
+	// it and the data backed up on adoption is restored
+	OnDeleteRelease = "release"
+)
+
+// DriftPolicyAnnotation can be set on a FeatureFlag to choose what happens
+// when its payload is modified in the ConfigMap outside of the operator,
+// e.g. with kubectl edit.
+const DriftPolicyAnnotation = "featureflags.featured.io/drift-policy"
+
+const (
+	// DriftPolicyRevert publishes the payload again, which is the default
+	DriftPolicyRevert = "revert"
+	// DriftPolicyReport leaves the edited payload in place and reports it
+	DriftPolicyReport = "report"
+	// DriftPolicyAccept updates the spec of the FeatureFlag from the edited
+	// payload
+	DriftPolicyAccept = "accept"
+)
+
+// RolloutPlan serves Variation to a growing percentage of contexts, moving to
+// the next step once the Duration of the current step has elapsed.
+type RolloutPlan struct {
+	// Variation is the variation being rolled out
+	Variation string `json:"variation"`
+	// Baseline is served to the remaining contexts. Defaults to OffVariation.
+	Baseline string `json:"baseline,omitempty"`
+	// BucketBy is the context attribute to bucket by. Defaults to "key".
+	BucketBy string        `json:"bucketBy,omitempty"`
+	Steps    []RolloutStep `json:"steps"`
+
+	// Paused holds the plan at its current step
+	Paused bool `json:"paused,omitempty"`
+	// Aborted serves OffVariation until cleared, which restarts the plan
+	Aborted bool `json:"aborted,omitempty"`
+}
+
+// RolloutStep serves the plan variation to Weight percent of contexts for
+// Duration. The Duration of the last step is ignored.
+type RolloutStep struct {
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight   int32           `json:"weight"`
+	Duration metav1.Duration `json:"duration,omitempty"`
+}
+
+// ScheduledChange overrides parts of the spec from At onwards. Due changes are
+// applied over the spec in order of At, so a later change wins. At least one
+// of Enabled, DefaultVariation, OffVariation and DefaultRollout must be set.
+type ScheduledChange struct {
+	// Name identifies the change in the status of the FeatureFlag
+	Name string      `json:"name"`
+	At   metav1.Time `json:"at"`
+
+	Enabled          *bool    `json:"enabled,omitempty"`
+	DefaultVariation string   `json:"defaultVariation,omitempty"`
+	OffVariation     string   `json:"offVariation,omitempty"`
+	DefaultRollout   *Rollout `json:"defaultRollout,omitempty"`
+}
+
+// Prerequisite is met when the FeatureFlag named Flag resolves to Variation
+type Prerequisite struct {
+	Flag      string `json:"flag"`
+	Variation string `json:"variation"`
+}
+
+// Variation is a named value a FeatureFlag can serve
+type Variation struct {
+	Name string `json:"name"`
+	// Value is the string encoding of the variation, e.g. "true", "1.5" or
+	// `{"color": "blue"}`. It must parse as the flag Type.
+	// +optional
+	Value string `json:"value,omitempty"`
+	// ValueFrom reads the value from a Secret instead. Only flags published
+	// to a Secret may use it.
+	// +optional
+	ValueFrom *VariationSource `json:"valueFrom,omitempty"`
+}
+
+// VariationSource is where the value of a Variation is read from
+type VariationSource struct {
+	// SecretKeyRef selects a key of a Secret in the namespace of the
+	// FeatureFlag holding the string encoding of the value
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// Rule serves Variation, or a Rollout, to evaluation contexts matching all of
+// its Clauses. Exactly one of Variation and Rollout must be set.
+type Rule struct {
+	Name      string   `json:"name,omitempty"`
+	Clauses   []Clause `json:"clauses"`
+	Variation string   `json:"variation,omitempty"`
+	Rollout   *Rollout `json:"rollout,omitempty"`
+}
+
+// Rollout splits evaluation contexts between variations by weight. Contexts
+// are assigned to a bucket by hashing the flag name, Salt and the BucketBy
+// attribute, so a context is always served the same variation. Variations
+// cover consecutive ranges of buckets in order, so moving weight between two
+// adjacent variations only moves contexts between them, while changing any
+// other weight also moves contexts between the variations after it.
+type Rollout struct {
+	// Variations and their weights in percent. Weights must add up to 100.
+	Variations []WeightedVariation `json:"variations"`
+	// BucketBy is the context attribute to bucket by. Defaults to "key".
+	BucketBy string `json:"bucketBy,omitempty"`
+	// Salt is mixed into the bucketing hash. Defaults to the flag name.
+	Salt string `json:"salt,omitempty"`
+}
+
+// WeightedVariation is a variation and the percentage of contexts it is served to
+type WeightedVariation struct {
+	Variation string `json:"variation"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+}
+
+// Operator compares an evaluation context attribute with the values of a Clause
+// +kubebuilder:validation:Enum=in;notIn;startsWith;endsWith;matches;lessThan;lessThanOrEqual;greaterThan;greaterThanOrEqual;semVerEqual;semVerLessThan;semVerGreaterThan;inSegment;notInSegment
+type Operator string
+
+const (
+	// OperatorIn matches attributes equal to one of the values
+	OperatorIn Operator = "in"
+	// OperatorNotIn matches attributes equal to none of the values
+	OperatorNotIn Operator = "notIn"
+	// OperatorStartsWith matches attributes starting with one of the values
+	OperatorStartsWith Operator = "startsWith"
+	// OperatorEndsWith matches attributes ending with one of the values
+	OperatorEndsWith Operator = "endsWith"
+	// OperatorMatches matches attributes matching one of the regular expressions
+	OperatorMatches Operator = "matches"
+	// OperatorLessThan matches numeric attributes less than one of the values
+	OperatorLessThan Operator = "lessThan"
+	// OperatorLessThanOrEqual matches numeric attributes less than or equal to one of the values
+	OperatorLessThanOrEqual Operator = "lessThanOrEqual"
+	// OperatorGreaterThan matches numeric attributes greater than one of the values
+	OperatorGreaterThan Operator = "greaterThan"
+	// OperatorGreaterThanOrEqual matches numeric attributes greater than or equal to one of the values
+	OperatorGreaterThanOrEqual Operator = "greaterThanOrEqual"
+	// OperatorSemVerEqual matches semantic versions equal to one of the values
+	OperatorSemVerEqual Operator = "semVerEqual"
+	// OperatorSemVerLessThan matches semantic versions lower than one of the values
+	OperatorSemVerLessThan Operator = "semVerLessThan"
+	// OperatorSemVerGreaterThan matches semantic versions higher than one of the values
+	OperatorSemVerGreaterThan Operator = "semVerGreaterThan"
+	// OperatorInSegment matches contexts in one of the FeatureSegments named by the values
+	OperatorInSegment Operator = "inSegment"
+	// OperatorNotInSegment matches contexts in none of the FeatureSegments named by the values
+	OperatorNotInSegment Operator = "notInSegment"
+)
+
+// Clause matches when Attribute of the evaluation context satisfies Operator
+// for at least one of Values. A context without the attribute never matches.
+// Segment operators match on the whole context and ignore Attribute.
+type Clause struct {
+	Attribute string   `json:"attribute,omitempty"`
+	Operator  Operator `json:"operator"`
+	Values    []string `json:"values"`
+	// Negate inverts the result of the clause
+	Negate bool `json:"negate,omitempty"`
+}
+
+// FeatureFlagConditionType is a valid value for FeatureFlagCondition.Type
+type FeatureFlagConditionType string
+
+const (
+	// FeatureFlagReady means the flag is valid and published
+	FeatureFlagReady FeatureFlagConditionType = "Ready"
+	// FeatureFlagConfigMapSynced means the ConfigMap holds the current payload
+	FeatureFlagConfigMapSynced FeatureFlagConditionType = "ConfigMapSynced"
+	// FeatureFlagValid means the spec passed validation and its rules compiled
+	FeatureFlagValid FeatureFlagConditionType = "Valid"
+	// FeatureFlagConflict means another FeatureFlag publishes the same key
+	// into the shared ConfigMap, so the payload is not written
+	FeatureFlagConflict FeatureFlagConditionType = "Conflict"
+	// FeatureFlagSecretSynced means the Secret holds the current payload
+	FeatureFlagSecretSynced FeatureFlagConditionType = "SecretSynced"
+	// FeatureFlagInjected means every app selected by the AppSelector has
+	// the flag injected into its containers
+	FeatureFlagInjected FeatureFlagConditionType = "Injected"
+)
+
+// FeatureFlagCondition describes the state of a FeatureFlag at a certain point
+type FeatureFlagCondition struct {
+	Type   FeatureFlagConditionType `json:"type"`
+	Status corev1.ConditionStatus   `json:"status"`
+	// LastTransitionTime is the last time the condition changed status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a one word CamelCase reason for the last transition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message about the last transition
+	Message string `json:"message,omitempty"`
 }
 
 // FeatureFlagStatus is the status for a FeatureFlag resource
 type FeatureFlagStatus struct {
-	AvailableReplicas int32 `json:"availableReplicas"`
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ContentHash is the SHA-256 of the payload last published
+	ContentHash string `json:"contentHash,omitempty"`
+	// LastSyncTime is the last time a sync changed the status
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	Conditions []FeatureFlagCondition `json:"conditions,omitempty"`
+	// AppliedChanges are the scheduled changes currently applied over the
+	// spec, in the order they were applied.
+	AppliedChanges []AppliedChange `json:"appliedChanges,omitempty"`
+	// RolloutPlan is the progress of the rollout plan
+	RolloutPlan *RolloutPlanStatus `json:"rolloutPlan,omitempty"`
+	// Overrides is the name of the ClusterFeatureFlag this flag overrides in
+	// its namespace
+	Overrides string `json:"overrides,omitempty"`
+	// Publishers is the outcome of the last publication by each of the
+	// publishers of the spec
+	Publishers []PublisherStatus `json:"publishers,omitempty"`
+}
+
+// PublisherStatus is the outcome of the last publication of the payload by
+// a Publisher
+type PublisherStatus struct {
+	Name string `json:"name"`
+	// Published is True when the target holds the current payload
+	Published corev1.ConditionStatus `json:"published"`
+	Reason    string                 `json:"reason,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	// ContentHash is the SHA-256 of the payload last published
+	ContentHash string `json:"contentHash,omitempty"`
+	// LastPublishTime is the last time a changed payload was published
+	LastPublishTime *metav1.Time `json:"lastPublishTime,omitempty"`
+}
+
+// RolloutPlanPhase is the state of a rollout plan
+type RolloutPlanPhase string
+
+const (
+	// RolloutPlanProgressing means the plan moves to its next step when due
+	RolloutPlanProgressing RolloutPlanPhase = "Progressing"
+	// RolloutPlanPaused means the plan is held at its current step
+	RolloutPlanPaused RolloutPlanPhase = "Paused"
+	// RolloutPlanCompleted means the plan reached its last step
+	RolloutPlanCompleted RolloutPlanPhase = "Completed"
+	// RolloutPlanAborted means the flag serves its off variation
+	RolloutPlanAborted RolloutPlanPhase = "Aborted"
+)
+
+// RolloutPlanStatus is the progress of a RolloutPlan
+type RolloutPlanStatus struct {
+	Phase RolloutPlanPhase `json:"phase"`
+	// PlanHash is the hash of the plan the progress was made on. The plan
+	// restarts from its first step when its variation, baseline, bucketing
+	// or steps change.
+	PlanHash string `json:"planHash,omitempty"`
+	// CurrentStep is the index of the step being served
+	CurrentStep int32 `json:"currentStep"`
+	// CurrentWeight is the weight of the step being served
+	CurrentWeight int32 `json:"currentWeight"`
+	// StepStartTime is when the current step started, moved forward by the
+	// time spent paused
+	StepStartTime metav1.Time `json:"stepStartTime"`
+	// PausedTime is when the plan was paused
+	PausedTime *metav1.Time `json:"pausedTime,omitempty"`
+	// NextTransitionTime is when the plan moves to the next step
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+}
+
+// AppliedChange records when the controller applied a scheduled change
+type AppliedChange struct {
+	Name string `json:"name"`
+	// At is the time the change was scheduled for
+	At metav1.Time `json:"at"`
+	// AppliedTime is the time the controller applied the change, which is
+	// later than At if the controller was down when it was due
+	AppliedTime metav1.Time `json:"appliedTime"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -52,3 +529,110 @@ type FeatureFlagList struct {
 
 	Items []FeatureFlag `json:"items"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:storageversion
+
+// FeatureSegment is a reusable audience that FeatureFlag rules in the same
+// namespace can target with the inSegment and notInSegment operators
+type FeatureSegment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec FeatureSegmentSpec `json:"spec"`
+}
+
+// FeatureSegmentSpec is the spec for a FeatureSegment resource. A context is
+// in the segment if its "key" attribute is Included, or if it is not Excluded
+// and matches any of the Rules.
+type FeatureSegmentSpec struct {
+	Included []string      `json:"included,omitempty"`
+	Excluded []string      `json:"excluded,omitempty"`
+	Rules    []SegmentRule `json:"rules,omitempty"`
+}
+
+// SegmentRule matches contexts matching all of its Clauses. Segment rules
+// cannot use the segment operators.
+type SegmentRule struct {
+	Clauses []Clause `json:"clauses"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FeatureSegmentList is a list of FeatureSegment resources
+type FeatureSegmentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []FeatureSegment `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,shortName=cff
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Enabled",type=boolean,JSONPath=`.spec.enabled`
+// +kubebuilder:printcolumn:name="Namespaces",type=integer,JSONPath=`.status.namespaces`,description="Number of namespaces the flag is published to"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterFeatureFlag is a FeatureFlag published into every namespace, or into
+// the namespaces matching its NamespaceSelector. A FeatureFlag of the same
+// name overrides it in its namespace.
+type ClusterFeatureFlag struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterFeatureFlagSpec `json:"spec"`
+	// +optional
+	Status ClusterFeatureFlagStatus `json:"status"`
+}
+
+// ClusterFeatureFlagSpec is the spec for a ClusterFeatureFlag resource.
+// Prerequisites and FeatureSegments are namespaced, so a ClusterFeatureFlag
+// cannot use them.
+type ClusterFeatureFlagSpec struct {
+	FeatureFlagSpec `json:",inline"`
+
+	// NamespaceSelector selects the namespaces the flag is published to.
+	// Defaults to every namespace watched by the operator.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// ClusterFeatureFlagStatus is the status for a ClusterFeatureFlag resource
+type ClusterFeatureFlagStatus struct {
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ContentHash is the SHA-256 of the payload last published
+	ContentHash string `json:"contentHash,omitempty"`
+	// LastSyncTime is the last time a sync changed the status
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	Conditions []FeatureFlagCondition `json:"conditions,omitempty"`
+	// AppliedChanges are the scheduled changes currently applied over the
+	// spec, in the order they were applied.
+	AppliedChanges []AppliedChange `json:"appliedChanges,omitempty"`
+	// RolloutPlan is the progress of the rollout plan
+	RolloutPlan *RolloutPlanStatus `json:"rolloutPlan,omitempty"`
+
+	// Namespaces is the number of namespaces the flag is published to
+	Namespaces int32 `json:"namespaces,omitempty"`
+	// OverriddenNamespaces are the selected namespaces where a FeatureFlag of
+	// the same name is published instead
+	OverriddenNamespaces []string `json:"overriddenNamespaces,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterFeatureFlagList is a list of ClusterFeatureFlag resources
+type ClusterFeatureFlagList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterFeatureFlag `json:"items"`
+}
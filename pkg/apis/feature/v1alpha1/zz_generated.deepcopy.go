@@ -21,16 +21,184 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedChange) DeepCopyInto(out *AppliedChange) {
+	*out = *in
+	in.At.DeepCopyInto(&out.At)
+	in.AppliedTime.DeepCopyInto(&out.AppliedTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedChange.
+func (in *AppliedChange) DeepCopy() *AppliedChange {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Clause) DeepCopyInto(out *Clause) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Clause.
+func (in *Clause) DeepCopy() *Clause {
+	if in == nil {
+		return nil
+	}
+	out := new(Clause)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterFeatureFlag) DeepCopyInto(out *ClusterFeatureFlag) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterFeatureFlag.
+func (in *ClusterFeatureFlag) DeepCopy() *ClusterFeatureFlag {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterFeatureFlag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterFeatureFlag) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterFeatureFlagList) DeepCopyInto(out *ClusterFeatureFlagList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterFeatureFlag, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterFeatureFlagList.
+func (in *ClusterFeatureFlagList) DeepCopy() *ClusterFeatureFlagList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterFeatureFlagList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterFeatureFlagList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterFeatureFlagSpec) DeepCopyInto(out *ClusterFeatureFlagSpec) {
+	*out = *in
+	in.FeatureFlagSpec.DeepCopyInto(&out.FeatureFlagSpec)
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterFeatureFlagSpec.
+func (in *ClusterFeatureFlagSpec) DeepCopy() *ClusterFeatureFlagSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterFeatureFlagSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterFeatureFlagStatus) DeepCopyInto(out *ClusterFeatureFlagStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]FeatureFlagCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AppliedChanges != nil {
+		in, out := &in.AppliedChanges, &out.AppliedChanges
+		*out = make([]AppliedChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RolloutPlan != nil {
+		in, out := &in.RolloutPlan, &out.RolloutPlan
+		*out = new(RolloutPlanStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OverriddenNamespaces != nil {
+		in, out := &in.OverriddenNamespaces, &out.OverriddenNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterFeatureFlagStatus.
+func (in *ClusterFeatureFlagStatus) DeepCopy() *ClusterFeatureFlagStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterFeatureFlagStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeatureFlag) DeepCopyInto(out *FeatureFlag) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -52,6 +220,23 @@ func (in *FeatureFlag) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureFlagCondition) DeepCopyInto(out *FeatureFlagCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureFlagCondition.
+func (in *FeatureFlagCondition) DeepCopy() *FeatureFlagCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureFlagCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeatureFlagList) DeepCopyInto(out *FeatureFlagList) {
 	*out = *in
@@ -88,11 +273,7 @@ func (in *FeatureFlagList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeatureFlagSpec) DeepCopyInto(out *FeatureFlagSpec) {
 	*out = *in
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
-		*out = new(int32)
-		**out = **in
-	}
+	in.FlagSpec.DeepCopyInto(&out.FlagSpec)
 	return
 }
 
@@ -109,6 +290,36 @@ func (in *FeatureFlagSpec) DeepCopy() *FeatureFlagSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeatureFlagStatus) DeepCopyInto(out *FeatureFlagStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]FeatureFlagCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AppliedChanges != nil {
+		in, out := &in.AppliedChanges, &out.AppliedChanges
+		*out = make([]AppliedChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RolloutPlan != nil {
+		in, out := &in.RolloutPlan, &out.RolloutPlan
+		*out = new(RolloutPlanStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Publishers != nil {
+		in, out := &in.Publishers, &out.Publishers
+		*out = make([]PublisherStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -121,3 +332,474 @@ func (in *FeatureFlagStatus) DeepCopy() *FeatureFlagStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureSegment) DeepCopyInto(out *FeatureSegment) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureSegment.
+func (in *FeatureSegment) DeepCopy() *FeatureSegment {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureSegment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FeatureSegment) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureSegmentList) DeepCopyInto(out *FeatureSegmentList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FeatureSegment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureSegmentList.
+func (in *FeatureSegmentList) DeepCopy() *FeatureSegmentList {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureSegmentList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FeatureSegmentList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureSegmentSpec) DeepCopyInto(out *FeatureSegmentSpec) {
+	*out = *in
+	if in.Included != nil {
+		in, out := &in.Included, &out.Included
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Excluded != nil {
+		in, out := &in.Excluded, &out.Excluded
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]SegmentRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureSegmentSpec.
+func (in *FeatureSegmentSpec) DeepCopy() *FeatureSegmentSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureSegmentSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilePublisher) DeepCopyInto(out *FilePublisher) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilePublisher.
+func (in *FilePublisher) DeepCopy() *FilePublisher {
+	if in == nil {
+		return nil
+	}
+	out := new(FilePublisher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlagSpec) DeepCopyInto(out *FlagSpec) {
+	*out = *in
+	if in.Publishers != nil {
+		in, out := &in.Publishers, &out.Publishers
+		*out = make([]Publisher, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AppSelector != nil {
+		in, out := &in.AppSelector, &out.AppSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Variations != nil {
+		in, out := &in.Variations, &out.Variations
+		*out = make([]Variation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DefaultRollout != nil {
+		in, out := &in.DefaultRollout, &out.DefaultRollout
+		*out = new(Rollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prerequisites != nil {
+		in, out := &in.Prerequisites, &out.Prerequisites
+		*out = make([]Prerequisite, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]Rule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = make([]ScheduledChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RolloutPlan != nil {
+		in, out := &in.RolloutPlan, &out.RolloutPlan
+		*out = new(RolloutPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlagSpec.
+func (in *FlagSpec) DeepCopy() *FlagSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FlagSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPPublisher) DeepCopyInto(out *HTTPPublisher) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPPublisher.
+func (in *HTTPPublisher) DeepCopy() *HTTPPublisher {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPPublisher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Prerequisite) DeepCopyInto(out *Prerequisite) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Prerequisite.
+func (in *Prerequisite) DeepCopy() *Prerequisite {
+	if in == nil {
+		return nil
+	}
+	out := new(Prerequisite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Publisher) DeepCopyInto(out *Publisher) {
+	*out = *in
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(FilePublisher)
+		**out = **in
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPPublisher)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Publisher.
+func (in *Publisher) DeepCopy() *Publisher {
+	if in == nil {
+		return nil
+	}
+	out := new(Publisher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublisherStatus) DeepCopyInto(out *PublisherStatus) {
+	*out = *in
+	if in.LastPublishTime != nil {
+		in, out := &in.LastPublishTime, &out.LastPublishTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublisherStatus.
+func (in *PublisherStatus) DeepCopy() *PublisherStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PublisherStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rollout) DeepCopyInto(out *Rollout) {
+	*out = *in
+	if in.Variations != nil {
+		in, out := &in.Variations, &out.Variations
+		*out = make([]WeightedVariation, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rollout.
+func (in *Rollout) DeepCopy() *Rollout {
+	if in == nil {
+		return nil
+	}
+	out := new(Rollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutPlan) DeepCopyInto(out *RolloutPlan) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]RolloutStep, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutPlan.
+func (in *RolloutPlan) DeepCopy() *RolloutPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutPlanStatus) DeepCopyInto(out *RolloutPlanStatus) {
+	*out = *in
+	in.StepStartTime.DeepCopyInto(&out.StepStartTime)
+	if in.PausedTime != nil {
+		in, out := &in.PausedTime, &out.PausedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextTransitionTime != nil {
+		in, out := &in.NextTransitionTime, &out.NextTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutPlanStatus.
+func (in *RolloutPlanStatus) DeepCopy() *RolloutPlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutPlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStep) DeepCopyInto(out *RolloutStep) {
+	*out = *in
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStep.
+func (in *RolloutStep) DeepCopy() *RolloutStep {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rule) DeepCopyInto(out *Rule) {
+	*out = *in
+	if in.Clauses != nil {
+		in, out := &in.Clauses, &out.Clauses
+		*out = make([]Clause, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(Rollout)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rule.
+func (in *Rule) DeepCopy() *Rule {
+	if in == nil {
+		return nil
+	}
+	out := new(Rule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduledChange) DeepCopyInto(out *ScheduledChange) {
+	*out = *in
+	in.At.DeepCopyInto(&out.At)
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DefaultRollout != nil {
+		in, out := &in.DefaultRollout, &out.DefaultRollout
+		*out = new(Rollout)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduledChange.
+func (in *ScheduledChange) DeepCopy() *ScheduledChange {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduledChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SegmentRule) DeepCopyInto(out *SegmentRule) {
+	*out = *in
+	if in.Clauses != nil {
+		in, out := &in.Clauses, &out.Clauses
+		*out = make([]Clause, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SegmentRule.
+func (in *SegmentRule) DeepCopy() *SegmentRule {
+	if in == nil {
+		return nil
+	}
+	out := new(SegmentRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Variation) DeepCopyInto(out *Variation) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(VariationSource)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Variation.
+func (in *Variation) DeepCopy() *Variation {
+	if in == nil {
+		return nil
+	}
+	out := new(Variation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VariationSource) DeepCopyInto(out *VariationSource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VariationSource.
+func (in *VariationSource) DeepCopy() *VariationSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VariationSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WeightedVariation) DeepCopyInto(out *WeightedVariation) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WeightedVariation.
+func (in *WeightedVariation) DeepCopy() *WeightedVariation {
+	if in == nil {
+		return nil
+	}
+	out := new(WeightedVariation)
+	in.DeepCopyInto(out)
+	return out
+}
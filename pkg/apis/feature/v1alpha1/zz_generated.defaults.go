@@ -0,0 +1,45 @@
+// +build !ignore_autogenerated
+
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by defaulter-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// RegisterDefaults adds defaulters functions to the given scheme.
+// Public to allow building arbitrary schemes.
+// All generated defaulters are covering - they call all nested defaulters.
+func RegisterDefaults(scheme *runtime.Scheme) error {
+	scheme.AddTypeDefaultingFunc(&FeatureFlag{}, func(obj interface{}) { SetObjectDefaults_FeatureFlag(obj.(*FeatureFlag)) })
+	scheme.AddTypeDefaultingFunc(&FeatureFlagList{}, func(obj interface{}) { SetObjectDefaults_FeatureFlagList(obj.(*FeatureFlagList)) })
+	return nil
+}
+
+func SetObjectDefaults_FeatureFlag(in *FeatureFlag) {
+	SetDefaults_FeatureFlag(in)
+}
+
+func SetObjectDefaults_FeatureFlagList(in *FeatureFlagList) {
+	for i := range in.Items {
+		a := &in.Items[i]
+		SetObjectDefaults_FeatureFlag(a)
+	}
+}
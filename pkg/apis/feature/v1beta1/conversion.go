@@ -0,0 +1,74 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"fmt"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature"
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// ConvertTo converts the FeatureFlag to the v1alpha1 hub.
+func (in *FeatureFlag) ConvertTo(hub featurecontroller.Hub) error {
+	out, ok := hub.(*v1alpha1.FeatureFlag)
+	if !ok {
+		return fmt.Errorf("cannot convert FeatureFlag to %T", hub)
+	}
+	if err := Convert_v1beta1_FeatureFlag_To_v1alpha1_FeatureFlag(in, out, nil); err != nil {
+		return err
+	}
+	out.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("FeatureFlag"))
+	return nil
+}
+
+// ConvertFrom converts the FeatureFlag from the v1alpha1 hub.
+func (in *FeatureFlag) ConvertFrom(hub featurecontroller.Hub) error {
+	src, ok := hub.(*v1alpha1.FeatureFlag)
+	if !ok {
+		return fmt.Errorf("cannot convert FeatureFlag from %T", hub)
+	}
+	if err := Convert_v1alpha1_FeatureFlag_To_v1beta1_FeatureFlag(src, in, nil); err != nil {
+		return err
+	}
+	in.SetGroupVersionKind(SchemeGroupVersion.WithKind("FeatureFlag"))
+	return nil
+}
+
+// ConvertTo converts the FeatureSegment to the v1alpha1 hub.
+func (in *FeatureSegment) ConvertTo(hub featurecontroller.Hub) error {
+	out, ok := hub.(*v1alpha1.FeatureSegment)
+	if !ok {
+		return fmt.Errorf("cannot convert FeatureSegment to %T", hub)
+	}
+	if err := Convert_v1beta1_FeatureSegment_To_v1alpha1_FeatureSegment(in, out, nil); err != nil {
+		return err
+	}
+	out.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("FeatureSegment"))
+	return nil
+}
+
+// ConvertFrom converts the FeatureSegment from the v1alpha1 hub.
+func (in *FeatureSegment) ConvertFrom(hub featurecontroller.Hub) error {
+	src, ok := hub.(*v1alpha1.FeatureSegment)
+	if !ok {
+		return fmt.Errorf("cannot convert FeatureSegment from %T", hub)
+	}
+	if err := Convert_v1alpha1_FeatureSegment_To_v1beta1_FeatureSegment(src, in, nil); err != nil {
+		return err
+	}
+	in.SetGroupVersionKind(SchemeGroupVersion.WithKind("FeatureSegment"))
+	return nil
+}
@@ -0,0 +1,92 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+func newFuzzer(seed int64) *fuzz.Fuzzer {
+	return fuzz.NewWithSeed(seed).NilChance(0.2).NumElements(0, 3).Funcs(
+		// gofuzz cannot fill the unexported fields of time.Time
+		func(t *metav1.Time, c fuzz.Continue) {
+			*t = metav1.Unix(c.Int63n(1<<32), 0)
+		},
+	)
+}
+
+// TestFeatureFlagRoundTrip tests that v1beta1 FeatureFlags convert to the v1alpha1 hub and back without loss
+func TestFeatureFlagRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		in := &FeatureFlag{}
+		newFuzzer(seed).Fuzz(in)
+		in.SetGroupVersionKind(SchemeGroupVersion.WithKind("FeatureFlag"))
+
+		hub := &v1alpha1.FeatureFlag{}
+		require.NoError(t, in.ConvertTo(hub))
+		out := &FeatureFlag{}
+		require.NoError(t, out.ConvertFrom(hub))
+
+		require.Equal(t, in, out, "seed %d", seed)
+	}
+}
+
+// TestFeatureFlagHubRoundTrip tests that v1alpha1 FeatureFlags convert to v1beta1 and back without loss
+func TestFeatureFlagHubRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		in := &v1alpha1.FeatureFlag{}
+		newFuzzer(seed).Fuzz(in)
+		in.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("FeatureFlag"))
+
+		spoke := &FeatureFlag{}
+		require.NoError(t, spoke.ConvertFrom(in))
+		out := &v1alpha1.FeatureFlag{}
+		require.NoError(t, spoke.ConvertTo(out))
+
+		require.Equal(t, in, out, "seed %d", seed)
+	}
+}
+
+// TestFeatureSegmentRoundTrip tests that FeatureSegments convert to the v1alpha1 hub and back without loss
+func TestFeatureSegmentRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		in := &FeatureSegment{}
+		newFuzzer(seed).Fuzz(in)
+		in.SetGroupVersionKind(SchemeGroupVersion.WithKind("FeatureSegment"))
+
+		hub := &v1alpha1.FeatureSegment{}
+		require.NoError(t, in.ConvertTo(hub))
+		out := &FeatureSegment{}
+		require.NoError(t, out.ConvertFrom(hub))
+
+		require.Equal(t, in, out, "seed %d", seed)
+	}
+}
+
+// TestConfigMapNameRenamed tests that a v1alpha1 manifest keeps its ConfigMap name in v1beta1
+func TestConfigMapNameRenamed(t *testing.T) {
+	hub := &v1alpha1.FeatureFlag{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"apiVersion": "featurecontroller.featured.io/v1alpha1",
+		"kind": "FeatureFlag",
+		"metadata": {"name": "new-checkout"},
+		"spec": {"configmapName": "checkout-flags", "type": "boolean", "enabled": true}
+	}`), hub))
+
+	out := &FeatureFlag{}
+	require.NoError(t, out.ConvertFrom(hub))
+	manifest, err := json.Marshal(out)
+	require.NoError(t, err)
+
+	var converted map[string]interface{}
+	require.NoError(t, json.Unmarshal(manifest, &converted))
+	require.Equal(t, "featurecontroller.featured.io/v1beta1", converted["apiVersion"])
+	spec := converted["spec"].(map[string]interface{})
+	require.Equal(t, "checkout-flags", spec["configMapName"])
+	require.Equal(t, "boolean", spec["type"])
+}
@@ -0,0 +1,166 @@
+package v1beta1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// chartDir holds the CRDs generated from the API types by hack/update-codegen.sh,
+// in crds/ or, for the FeatureFlag CRD templated by the chart, in files/
+var chartDir = filepath.Join("..", "..", "..", "..", "helm", "featured-operator")
+
+func loadCRD(t *testing.T, file string) *apiextensionsv1.CustomResourceDefinition {
+	data, err := ioutil.ReadFile(filepath.Join(chartDir, file))
+	require.NoError(t, err)
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	require.NoError(t, yaml.UnmarshalStrict(data, crd))
+	return crd
+}
+
+// schemaDiff returns every difference between the fields of typ and the
+// schema describing it.
+func schemaDiff(path string, typ reflect.Type, schema *apiextensionsv1.JSONSchemaProps) []string {
+	if schema == nil {
+		return []string{fmt.Sprintf("%s: no schema", path)}
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	expType := ""
+	switch {
+	case typ == reflect.TypeOf(metav1.Time{}), typ == reflect.TypeOf(metav1.Duration{}):
+		expType = "string"
+	case typ == reflect.TypeOf(metav1.ObjectMeta{}):
+		expType = "object"
+	case typ.Kind() == reflect.Struct, typ.Kind() == reflect.Map:
+		expType = "object"
+	case typ.Kind() == reflect.Slice:
+		expType = "array"
+	case typ.Kind() == reflect.String:
+		expType = "string"
+	case typ.Kind() == reflect.Bool:
+		expType = "boolean"
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Uint64:
+		expType = "integer"
+	case typ.Kind() == reflect.Float32, typ.Kind() == reflect.Float64:
+		expType = "number"
+	}
+	if schema.Type != expType {
+		return []string{fmt.Sprintf("%s: type %q in the CRD, %q in the Go types", path, schema.Type, expType)}
+	}
+
+	switch {
+	case expType == "string", typ == reflect.TypeOf(metav1.ObjectMeta{}):
+		return nil
+	case typ.Kind() == reflect.Slice:
+		if schema.Items == nil {
+			return []string{fmt.Sprintf("%s: no items in the CRD", path)}
+		}
+		return schemaDiff(path+"[]", typ.Elem(), schema.Items.Schema)
+	case typ.Kind() == reflect.Map:
+		if schema.AdditionalProperties == nil {
+			return []string{fmt.Sprintf("%s: no additionalProperties in the CRD", path)}
+		}
+		return schemaDiff(path+"{}", typ.Elem(), schema.AdditionalProperties.Schema)
+	case typ.Kind() != reflect.Struct:
+		return nil
+	}
+
+	var diffs []string
+	fields := jsonFields(typ)
+	for name, field := range fields {
+		property, ok := schema.Properties[name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s.%s: missing from the CRD", path, name))
+			continue
+		}
+		diffs = append(diffs, schemaDiff(path+"."+name, field.Type, &property)...)
+	}
+	for name := range schema.Properties {
+		if _, ok := fields[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s.%s: missing from the Go types", path, name))
+		}
+	}
+	for _, name := range schema.Required {
+		if field, ok := fields[name]; ok && strings.Contains(field.Tag.Get("json"), ",omitempty") {
+			diffs = append(diffs, fmt.Sprintf("%s.%s: required by the CRD but omitted when empty", path, name))
+		}
+	}
+	return diffs
+}
+
+// jsonFields returns the fields of a struct by JSON name, including the
+// fields of inlined structs.
+func jsonFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" && field.Anonymous {
+			for name, inlined := range jsonFields(field.Type) {
+				fields[name] = inlined
+			}
+			continue
+		}
+		if name != "-" {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// TestCRDSchema tests that the generated CRDs describe exactly the fields of the Go types of every version
+func TestCRDSchema(t *testing.T) {
+	tests := []struct {
+		file string
+		obj  interface{}
+	}{
+		{file: "files/featurecontroller.featured.io_featureflags.yaml", obj: v1alpha1.FeatureFlag{}},
+		{file: "files/featurecontroller.featured.io_featureflags.yaml", obj: FeatureFlag{}},
+		{file: "crds/featurecontroller.featured.io_featuresegments.yaml", obj: v1alpha1.FeatureSegment{}},
+		{file: "crds/featurecontroller.featured.io_featuresegments.yaml", obj: FeatureSegment{}},
+		{file: "crds/featurecontroller.featured.io_clusterfeatureflags.yaml", obj: v1alpha1.ClusterFeatureFlag{}},
+	}
+
+	for _, test := range tests {
+		typ := reflect.TypeOf(test.obj)
+		version := filepath.Base(typ.PkgPath())
+		t.Run(version+"/"+typ.Name(), func(t *testing.T) {
+			crd := loadCRD(t, test.file)
+			require.Equal(t, typ.Name(), crd.Spec.Names.Kind)
+
+			var schema *apiextensionsv1.JSONSchemaProps
+			for _, v := range crd.Spec.Versions {
+				if v.Name == version && v.Schema != nil {
+					schema = v.Schema.OpenAPIV3Schema
+				}
+			}
+			require.NotNil(t, schema, "version %s is not in %s", version, test.file)
+			require.Empty(t, schemaDiff(typ.Name(), typ, schema), "run hack/update-codegen.sh")
+		})
+	}
+}
+
+// TestCRDVersions tests that v1alpha1 is the storage version, and the conversion is left to the chart
+func TestCRDVersions(t *testing.T) {
+	crd := loadCRD(t, "files/featurecontroller.featured.io_featureflags.yaml")
+	require.Equal(t, []string{"ff"}, crd.Spec.Names.ShortNames)
+	require.Len(t, crd.Spec.Versions, 2)
+	for _, v := range crd.Spec.Versions {
+		require.Equal(t, v.Name == v1alpha1.SchemeGroupVersion.Version, v.Storage, v.Name)
+		require.NotNil(t, v.Subresources.Status, v.Name)
+	}
+	require.Nil(t, crd.Spec.Conversion)
+}
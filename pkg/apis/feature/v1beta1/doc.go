@@ -0,0 +1,22 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +k8s:deepcopy-gen=package
+// +k8s:conversion-gen=github.com/featured.io/pkg/apis/feature/v1alpha1
+// +groupName=featurecontroller.featured.io
+
+// Package v1beta1 is the v1beta1 version of the API. It converts to and from
+// the v1alpha1 hub, and will replace v1alpha1 as the hub and storage version
+// once every FeatureFlag is stored as v1beta1.
+package v1beta1 // import "github.com/featured.io/pkg/apis/featurecontroller/v1beta1"
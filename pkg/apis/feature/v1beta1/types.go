@@ -0,0 +1,88 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// v1beta1 only declares the types that differ from v1alpha1, and reuses the
+// others. A field is added to both versions by adding it to v1alpha1.FlagSpec.
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=ff
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Enabled",type=boolean,JSONPath=`.spec.enabled`
+// +kubebuilder:printcolumn:name="Rollout %",type=integer,JSONPath=`.status.rolloutPlan.currentWeight`,description="Percentage of contexts served the rollout plan variation"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FeatureFlag is a specification for a FeatureFlag resource
+type FeatureFlag struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec FeatureFlagSpec `json:"spec"`
+	// +optional
+	Status v1alpha1.FeatureFlagStatus `json:"status"`
+}
+
+// FeatureFlagSpec is the spec for a FeatureFlag resource
+type FeatureFlagSpec struct {
+	// ConfigMapName is the ConfigMap the flag is published to when Target
+	// is ConfigMap. It was configmapName in v1alpha1. Defaults to the flag
+	// name.
+	// +optional
+	ConfigMapName string `json:"configMapName"`
+
+	v1alpha1.FlagSpec `json:",inline"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FeatureFlagList is a list of FeatureFlag resources
+type FeatureFlagList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []FeatureFlag `json:"items"`
+}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FeatureSegment is a reusable audience that FeatureFlag rules in the same
+// namespace can target with the inSegment and notInSegment operators
+type FeatureSegment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec v1alpha1.FeatureSegmentSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FeatureSegmentList is a list of FeatureSegment resources
+type FeatureSegmentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []FeatureSegment `json:"items"`
+}
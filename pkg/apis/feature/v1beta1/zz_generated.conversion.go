@@ -0,0 +1,205 @@
+// +build !ignore_autogenerated
+
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by conversion-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	unsafe "unsafe"
+
+	v1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	conversion "k8s.io/apimachinery/pkg/conversion"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	localSchemeBuilder.Register(RegisterConversions)
+}
+
+// RegisterConversions adds conversion functions to the given scheme.
+// Public to allow building arbitrary schemes.
+func RegisterConversions(s *runtime.Scheme) error {
+	if err := s.AddGeneratedConversionFunc((*FeatureFlag)(nil), (*v1alpha1.FeatureFlag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FeatureFlag_To_v1alpha1_FeatureFlag(a.(*FeatureFlag), b.(*v1alpha1.FeatureFlag), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1alpha1.FeatureFlag)(nil), (*FeatureFlag)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_FeatureFlag_To_v1beta1_FeatureFlag(a.(*v1alpha1.FeatureFlag), b.(*FeatureFlag), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*FeatureFlagList)(nil), (*v1alpha1.FeatureFlagList)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FeatureFlagList_To_v1alpha1_FeatureFlagList(a.(*FeatureFlagList), b.(*v1alpha1.FeatureFlagList), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1alpha1.FeatureFlagList)(nil), (*FeatureFlagList)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_FeatureFlagList_To_v1beta1_FeatureFlagList(a.(*v1alpha1.FeatureFlagList), b.(*FeatureFlagList), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*FeatureFlagSpec)(nil), (*v1alpha1.FeatureFlagSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FeatureFlagSpec_To_v1alpha1_FeatureFlagSpec(a.(*FeatureFlagSpec), b.(*v1alpha1.FeatureFlagSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1alpha1.FeatureFlagSpec)(nil), (*FeatureFlagSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_FeatureFlagSpec_To_v1beta1_FeatureFlagSpec(a.(*v1alpha1.FeatureFlagSpec), b.(*FeatureFlagSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*FeatureSegment)(nil), (*v1alpha1.FeatureSegment)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FeatureSegment_To_v1alpha1_FeatureSegment(a.(*FeatureSegment), b.(*v1alpha1.FeatureSegment), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1alpha1.FeatureSegment)(nil), (*FeatureSegment)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_FeatureSegment_To_v1beta1_FeatureSegment(a.(*v1alpha1.FeatureSegment), b.(*FeatureSegment), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*FeatureSegmentList)(nil), (*v1alpha1.FeatureSegmentList)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FeatureSegmentList_To_v1alpha1_FeatureSegmentList(a.(*FeatureSegmentList), b.(*v1alpha1.FeatureSegmentList), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1alpha1.FeatureSegmentList)(nil), (*FeatureSegmentList)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_FeatureSegmentList_To_v1beta1_FeatureSegmentList(a.(*v1alpha1.FeatureSegmentList), b.(*FeatureSegmentList), scope)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func autoConvert_v1beta1_FeatureFlag_To_v1alpha1_FeatureFlag(in *FeatureFlag, out *v1alpha1.FeatureFlag, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if err := Convert_v1beta1_FeatureFlagSpec_To_v1alpha1_FeatureFlagSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
+	out.Status = in.Status
+	return nil
+}
+
+// Convert_v1beta1_FeatureFlag_To_v1alpha1_FeatureFlag is an autogenerated conversion function.
+func Convert_v1beta1_FeatureFlag_To_v1alpha1_FeatureFlag(in *FeatureFlag, out *v1alpha1.FeatureFlag, s conversion.Scope) error {
+	return autoConvert_v1beta1_FeatureFlag_To_v1alpha1_FeatureFlag(in, out, s)
+}
+
+func autoConvert_v1alpha1_FeatureFlag_To_v1beta1_FeatureFlag(in *v1alpha1.FeatureFlag, out *FeatureFlag, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if err := Convert_v1alpha1_FeatureFlagSpec_To_v1beta1_FeatureFlagSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
+	out.Status = in.Status
+	return nil
+}
+
+// Convert_v1alpha1_FeatureFlag_To_v1beta1_FeatureFlag is an autogenerated conversion function.
+func Convert_v1alpha1_FeatureFlag_To_v1beta1_FeatureFlag(in *v1alpha1.FeatureFlag, out *FeatureFlag, s conversion.Scope) error {
+	return autoConvert_v1alpha1_FeatureFlag_To_v1beta1_FeatureFlag(in, out, s)
+}
+
+func autoConvert_v1beta1_FeatureFlagList_To_v1alpha1_FeatureFlagList(in *FeatureFlagList, out *v1alpha1.FeatureFlagList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	out.Items = *(*[]v1alpha1.FeatureFlag)(unsafe.Pointer(&in.Items))
+	return nil
+}
+
+// Convert_v1beta1_FeatureFlagList_To_v1alpha1_FeatureFlagList is an autogenerated conversion function.
+func Convert_v1beta1_FeatureFlagList_To_v1alpha1_FeatureFlagList(in *FeatureFlagList, out *v1alpha1.FeatureFlagList, s conversion.Scope) error {
+	return autoConvert_v1beta1_FeatureFlagList_To_v1alpha1_FeatureFlagList(in, out, s)
+}
+
+func autoConvert_v1alpha1_FeatureFlagList_To_v1beta1_FeatureFlagList(in *v1alpha1.FeatureFlagList, out *FeatureFlagList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	out.Items = *(*[]FeatureFlag)(unsafe.Pointer(&in.Items))
+	return nil
+}
+
+// Convert_v1alpha1_FeatureFlagList_To_v1beta1_FeatureFlagList is an autogenerated conversion function.
+func Convert_v1alpha1_FeatureFlagList_To_v1beta1_FeatureFlagList(in *v1alpha1.FeatureFlagList, out *FeatureFlagList, s conversion.Scope) error {
+	return autoConvert_v1alpha1_FeatureFlagList_To_v1beta1_FeatureFlagList(in, out, s)
+}
+
+func autoConvert_v1beta1_FeatureFlagSpec_To_v1alpha1_FeatureFlagSpec(in *FeatureFlagSpec, out *v1alpha1.FeatureFlagSpec, s conversion.Scope) error {
+	out.ConfigMapName = in.ConfigMapName
+	out.FlagSpec = in.FlagSpec
+	return nil
+}
+
+// Convert_v1beta1_FeatureFlagSpec_To_v1alpha1_FeatureFlagSpec is an autogenerated conversion function.
+func Convert_v1beta1_FeatureFlagSpec_To_v1alpha1_FeatureFlagSpec(in *FeatureFlagSpec, out *v1alpha1.FeatureFlagSpec, s conversion.Scope) error {
+	return autoConvert_v1beta1_FeatureFlagSpec_To_v1alpha1_FeatureFlagSpec(in, out, s)
+}
+
+func autoConvert_v1alpha1_FeatureFlagSpec_To_v1beta1_FeatureFlagSpec(in *v1alpha1.FeatureFlagSpec, out *FeatureFlagSpec, s conversion.Scope) error {
+	out.ConfigMapName = in.ConfigMapName
+	out.FlagSpec = in.FlagSpec
+	return nil
+}
+
+// Convert_v1alpha1_FeatureFlagSpec_To_v1beta1_FeatureFlagSpec is an autogenerated conversion function.
+func Convert_v1alpha1_FeatureFlagSpec_To_v1beta1_FeatureFlagSpec(in *v1alpha1.FeatureFlagSpec, out *FeatureFlagSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_FeatureFlagSpec_To_v1beta1_FeatureFlagSpec(in, out, s)
+}
+
+func autoConvert_v1beta1_FeatureSegment_To_v1alpha1_FeatureSegment(in *FeatureSegment, out *v1alpha1.FeatureSegment, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Spec = in.Spec
+	return nil
+}
+
+// Convert_v1beta1_FeatureSegment_To_v1alpha1_FeatureSegment is an autogenerated conversion function.
+func Convert_v1beta1_FeatureSegment_To_v1alpha1_FeatureSegment(in *FeatureSegment, out *v1alpha1.FeatureSegment, s conversion.Scope) error {
+	return autoConvert_v1beta1_FeatureSegment_To_v1alpha1_FeatureSegment(in, out, s)
+}
+
+func autoConvert_v1alpha1_FeatureSegment_To_v1beta1_FeatureSegment(in *v1alpha1.FeatureSegment, out *FeatureSegment, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	out.Spec = in.Spec
+	return nil
+}
+
+// Convert_v1alpha1_FeatureSegment_To_v1beta1_FeatureSegment is an autogenerated conversion function.
+func Convert_v1alpha1_FeatureSegment_To_v1beta1_FeatureSegment(in *v1alpha1.FeatureSegment, out *FeatureSegment, s conversion.Scope) error {
+	return autoConvert_v1alpha1_FeatureSegment_To_v1beta1_FeatureSegment(in, out, s)
+}
+
+func autoConvert_v1beta1_FeatureSegmentList_To_v1alpha1_FeatureSegmentList(in *FeatureSegmentList, out *v1alpha1.FeatureSegmentList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	out.Items = *(*[]v1alpha1.FeatureSegment)(unsafe.Pointer(&in.Items))
+	return nil
+}
+
+// Convert_v1beta1_FeatureSegmentList_To_v1alpha1_FeatureSegmentList is an autogenerated conversion function.
+func Convert_v1beta1_FeatureSegmentList_To_v1alpha1_FeatureSegmentList(in *FeatureSegmentList, out *v1alpha1.FeatureSegmentList, s conversion.Scope) error {
+	return autoConvert_v1beta1_FeatureSegmentList_To_v1alpha1_FeatureSegmentList(in, out, s)
+}
+
+func autoConvert_v1alpha1_FeatureSegmentList_To_v1beta1_FeatureSegmentList(in *v1alpha1.FeatureSegmentList, out *FeatureSegmentList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	out.Items = *(*[]FeatureSegment)(unsafe.Pointer(&in.Items))
+	return nil
+}
+
+// Convert_v1alpha1_FeatureSegmentList_To_v1beta1_FeatureSegmentList is an autogenerated conversion function.
+func Convert_v1alpha1_FeatureSegmentList_To_v1beta1_FeatureSegmentList(in *v1alpha1.FeatureSegmentList, out *FeatureSegmentList, s conversion.Scope) error {
+	return autoConvert_v1alpha1_FeatureSegmentList_To_v1beta1_FeatureSegmentList(in, out, s)
+}
@@ -0,0 +1,498 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validation checks FeatureFlag resources for semantic errors that
+// the CRD schema cannot express.
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+var supportedFlagTypes = []string{
+	string(featurev1alpha1.FlagTypeBoolean),
+	string(featurev1alpha1.FlagTypeString),
+	string(featurev1alpha1.FlagTypeNumber),
+	string(featurev1alpha1.FlagTypeJSON),
+}
+
+var supportedPublishTargets = []string{
+	string(featurev1alpha1.PublishTargetConfigMap),
+	string(featurev1alpha1.PublishTargetSecret),
+}
+
+var supportedHTTPMethods = []string{
+	string(featurev1alpha1.HTTPMethodPut),
+	string(featurev1alpha1.HTTPMethodPost),
+}
+
+var supportedInjectionModes = []string{
+	string(featurev1alpha1.InjectionValue),
+	string(featurev1alpha1.InjectionReference),
+}
+
+var supportedPayloadFormats = []string{
+	string(featurev1alpha1.PayloadFormatJSON),
+	string(featurev1alpha1.PayloadFormatYAML),
+	string(featurev1alpha1.PayloadFormatDotenv),
+	string(featurev1alpha1.PayloadFormatProperties),
+}
+
+// ValidateFeatureFlag validates a FeatureFlag and returns every error found.
+// Prerequisite cycles through other flags can only be detected against the
+// whole namespace, so only a flag listing itself is rejected here.
+func ValidateFeatureFlag(featureflag *featurev1alpha1.FeatureFlag) field.ErrorList {
+	allErrs := ValidateFeatureFlagSpec(&featureflag.Spec, field.NewPath("spec"))
+	for i, prerequisite := range featureflag.Spec.Prerequisites {
+		if prerequisite.Flag == featureflag.Name {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "prerequisites").Index(i).Child("flag"), prerequisite.Flag, "a flag cannot be its own prerequisite"))
+		}
+	}
+	if onDelete, ok := featureflag.Annotations[featurev1alpha1.OnDeleteAnnotation]; ok && onDelete != featurev1alpha1.OnDeleteDelete && onDelete != featurev1alpha1.OnDeleteRelease {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("metadata", "annotations").Key(featurev1alpha1.OnDeleteAnnotation), onDelete, []string{featurev1alpha1.OnDeleteDelete, featurev1alpha1.OnDeleteRelease}))
+	}
+	if policy, ok := featureflag.Annotations[featurev1alpha1.DriftPolicyAnnotation]; ok && policy != featurev1alpha1.DriftPolicyRevert && policy != featurev1alpha1.DriftPolicyReport && policy != featurev1alpha1.DriftPolicyAccept {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("metadata", "annotations").Key(featurev1alpha1.DriftPolicyAnnotation), policy, []string{featurev1alpha1.DriftPolicyRevert, featurev1alpha1.DriftPolicyReport, featurev1alpha1.DriftPolicyAccept}))
+	}
+	return allErrs
+}
+
+// ValidateClusterFeatureFlag validates a ClusterFeatureFlag and returns every
+// error found. Prerequisites and FeatureSegments are namespaced, so cluster
+// flags cannot refer to them.
+func ValidateClusterFeatureFlag(featureflag *featurev1alpha1.ClusterFeatureFlag) field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := ValidateFeatureFlagSpec(&featureflag.Spec.FeatureFlagSpec, specPath)
+	if len(featureflag.Spec.Prerequisites) > 0 {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("prerequisites"), "cluster flags cannot have prerequisites"))
+	}
+	for i, rule := range featureflag.Spec.Rules {
+		for j, clause := range rule.Clauses {
+			if clause.Operator == featurev1alpha1.OperatorInSegment || clause.Operator == featurev1alpha1.OperatorNotInSegment {
+				allErrs = append(allErrs, field.Forbidden(specPath.Child("rules").Index(i).Child("clauses").Index(j).Child("operator"), "cluster flags cannot reference FeatureSegments"))
+			}
+		}
+	}
+	if featureflag.Spec.Target == featurev1alpha1.PublishTargetSecret {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("target"), "cluster flags cannot be published to Secrets"))
+	}
+	for i, variation := range featureflag.Spec.Variations {
+		if variation.ValueFrom != nil {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("variations").Index(i).Child("valueFrom"), "cluster flags cannot read values from Secrets"))
+		}
+	}
+	if len(featureflag.Spec.Publishers) > 0 {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("publishers"), "cluster flags cannot have publishers"))
+	}
+	if featureflag.Spec.AppSelector != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("appSelector"), "cluster flags cannot be injected into apps"))
+	}
+	if featureflag.Spec.NamespaceSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(featureflag.Spec.NamespaceSelector, specPath.Child("namespaceSelector"))...)
+	}
+	return allErrs
+}
+
+// ValidateFeatureFlagSpec validates the target the flag is published to, the
+// flag type, its variations and the variations it refers to.
+func ValidateFeatureFlagSpec(spec *featurev1alpha1.FeatureFlagSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	switch spec.Target {
+	case "", featurev1alpha1.PublishTargetConfigMap:
+		allErrs = append(allErrs, validateObjectName(spec.ConfigMapName, fldPath.Child("configmapName"))...)
+	case featurev1alpha1.PublishTargetSecret:
+		allErrs = append(allErrs, validateObjectName(spec.SecretName, fldPath.Child("secretName"))...)
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("target"), spec.Target, supportedPublishTargets))
+	}
+
+	// Payloads holding values read from Secrets are only published to the
+	// Secret of the flag.
+	if len(spec.Publishers) > 0 && spec.Target == featurev1alpha1.PublishTargetSecret {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("publishers"), "flags published to a Secret cannot have publishers"))
+	}
+	publishers := map[string]bool{}
+	for i, publisher := range spec.Publishers {
+		idxPath := fldPath.Child("publishers").Index(i)
+		if publisher.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else if publishers[publisher.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), publisher.Name))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(publisher.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), publisher.Name, msg))
+			}
+		}
+		publishers[publisher.Name] = true
+		allErrs = append(allErrs, validatePublisher(&publisher, idxPath)...)
+	}
+
+	if spec.AppSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(spec.AppSelector, fldPath.Child("appSelector"))...)
+	}
+	switch spec.Injection {
+	case "":
+	case featurev1alpha1.InjectionValue, featurev1alpha1.InjectionReference:
+		if spec.AppSelector == nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("injection"), "may only be set together with appSelector"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("injection"), spec.Injection, supportedInjectionModes))
+	}
+	// The value of a flag published to a Secret would be readable by anyone
+	// allowed to read the apps.
+	if spec.AppSelector != nil && spec.Target == featurev1alpha1.PublishTargetSecret && spec.Injection != featurev1alpha1.InjectionReference {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("injection"), "flags published to a Secret can only be injected by Reference"))
+	}
+
+	typeValid := true
+	switch spec.Type {
+	case featurev1alpha1.FlagTypeBoolean, featurev1alpha1.FlagTypeString, featurev1alpha1.FlagTypeNumber, featurev1alpha1.FlagTypeJSON:
+	case "":
+		typeValid = false
+		allErrs = append(allErrs, field.Required(fldPath.Child("type"), ""))
+	default:
+		typeValid = false
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), spec.Type, supportedFlagTypes))
+	}
+
+	switch spec.Format {
+	case "", featurev1alpha1.PayloadFormatJSON, featurev1alpha1.PayloadFormatYAML, featurev1alpha1.PayloadFormatDotenv, featurev1alpha1.PayloadFormatProperties:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("format"), spec.Format, supportedPayloadFormats))
+	}
+
+	if len(spec.Variations) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("variations"), "at least one variation must be specified"))
+	}
+
+	names := map[string]bool{}
+	for i, variation := range spec.Variations {
+		idxPath := fldPath.Child("variations").Index(i)
+		if variation.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else if names[variation.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), variation.Name))
+		}
+		names[variation.Name] = true
+
+		// Values read from Secrets are only known to the controller, which
+		// validates them once read.
+		if variation.ValueFrom != nil {
+			allErrs = append(allErrs, validateVariationSource(&variation, spec.Target, idxPath)...)
+		} else if typeValid {
+			if err := ValidateValue(spec.Type, variation.Value); err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("value"), variation.Value, err.Error()))
+			}
+		}
+	}
+
+	allErrs = append(allErrs, validateVariationRef(spec.DefaultVariation, names, fldPath.Child("defaultVariation"))...)
+	allErrs = append(allErrs, validateVariationRef(spec.OffVariation, names, fldPath.Child("offVariation"))...)
+	if spec.DefaultRollout != nil {
+		allErrs = append(allErrs, validateRollout(spec.DefaultRollout, names, fldPath.Child("defaultRollout"))...)
+	}
+
+	prerequisites := map[string]bool{}
+	for i, prerequisite := range spec.Prerequisites {
+		idxPath := fldPath.Child("prerequisites").Index(i)
+		if prerequisite.Flag == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("flag"), ""))
+		} else if prerequisites[prerequisite.Flag] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("flag"), prerequisite.Flag))
+		}
+		prerequisites[prerequisite.Flag] = true
+
+		// The variations of the prerequisite are only known to the
+		// controller, which treats an unknown variation as not met.
+		if prerequisite.Variation == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("variation"), ""))
+		}
+	}
+
+	for i, rule := range spec.Rules {
+		allErrs = append(allErrs, validateRule(&rule, names, fldPath.Child("rules").Index(i))...)
+	}
+
+	if spec.RolloutPlan != nil {
+		planPath := fldPath.Child("rolloutPlan")
+		if spec.DefaultRollout != nil {
+			allErrs = append(allErrs, field.Forbidden(planPath, "may not be set together with defaultRollout"))
+		}
+		baseline := spec.RolloutPlan.Baseline
+		if baseline == "" {
+			baseline = spec.OffVariation
+		}
+		allErrs = append(allErrs, validateRolloutPlan(spec.RolloutPlan, baseline, names, planPath)...)
+	}
+
+	changes := map[string]bool{}
+	for i, change := range spec.Schedule {
+		idxPath := fldPath.Child("schedule").Index(i)
+		if change.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else if changes[change.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), change.Name))
+		}
+		changes[change.Name] = true
+		allErrs = append(allErrs, validateScheduledChange(&change, names, idxPath)...)
+	}
+
+	return allErrs
+}
+
+// validateObjectName checks the name of the object the flag is published to.
+func validateObjectName(name string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if name == "" {
+		return append(allErrs, field.Required(fldPath, ""))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, msg))
+	}
+	return allErrs
+}
+
+// validatePublisher checks that exactly one kind of publisher is set, that
+// a file stays within the directory of the namespace of the flag and that a
+// URL is absolute and holds no credentials, which would end up in the status.
+func validatePublisher(publisher *featurev1alpha1.Publisher, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch {
+	case publisher.File == nil && publisher.HTTP == nil:
+		allErrs = append(allErrs, field.Required(fldPath, "one of file or http must be specified"))
+	case publisher.File != nil && publisher.HTTP != nil:
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("http"), "may not be set together with file"))
+	}
+
+	if publisher.File != nil && publisher.File.Path != "" {
+		pathPath := fldPath.Child("file", "path")
+		if path.IsAbs(publisher.File.Path) {
+			allErrs = append(allErrs, field.Invalid(pathPath, publisher.File.Path, "must be relative"))
+		} else {
+			for _, element := range strings.Split(publisher.File.Path, "/") {
+				if element == "" || element == "." || element == ".." {
+					allErrs = append(allErrs, field.Invalid(pathPath, publisher.File.Path, "must not contain empty, '.' or '..' elements"))
+					break
+				}
+			}
+		}
+	}
+
+	if publisher.HTTP != nil {
+		httpPath := fldPath.Child("http")
+		if publisher.HTTP.URL == "" {
+			allErrs = append(allErrs, field.Required(httpPath.Child("url"), ""))
+		} else if u, err := url.Parse(publisher.HTTP.URL); err != nil {
+			allErrs = append(allErrs, field.Invalid(httpPath.Child("url"), publisher.HTTP.URL, err.Error()))
+		} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(httpPath.Child("url"), publisher.HTTP.URL, "must be an absolute http or https URL"))
+		} else if u.User != nil {
+			allErrs = append(allErrs, field.Forbidden(httpPath.Child("url"), "must not hold credentials"))
+		}
+		switch publisher.HTTP.Method {
+		case "", featurev1alpha1.HTTPMethodPut, featurev1alpha1.HTTPMethodPost:
+		default:
+			allErrs = append(allErrs, field.NotSupported(httpPath.Child("method"), publisher.HTTP.Method, supportedHTTPMethods))
+		}
+	}
+	return allErrs
+}
+
+// validateVariationSource checks that a variation reading its value from a
+// Secret names a key of it, and is only published to a Secret so the value
+// never lands in a ConfigMap.
+func validateVariationSource(variation *featurev1alpha1.Variation, target featurev1alpha1.PublishTarget, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	sourcePath := fldPath.Child("valueFrom")
+	if variation.Value != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("value"), "may not be set together with valueFrom"))
+	}
+	if target != featurev1alpha1.PublishTargetSecret {
+		allErrs = append(allErrs, field.Forbidden(sourcePath, "only flags published to a Secret may read values from Secrets"))
+	}
+
+	ref := variation.ValueFrom.SecretKeyRef
+	if ref == nil {
+		return append(allErrs, field.Required(sourcePath.Child("secretKeyRef"), ""))
+	}
+	refPath := sourcePath.Child("secretKeyRef")
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(refPath.Child("name"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+			allErrs = append(allErrs, field.Invalid(refPath.Child("name"), ref.Name, msg))
+		}
+	}
+	if ref.Key == "" {
+		allErrs = append(allErrs, field.Required(refPath.Child("key"), ""))
+	} else {
+		for _, msg := range validation.IsConfigMapKey(ref.Key) {
+			allErrs = append(allErrs, field.Invalid(refPath.Child("key"), ref.Key, msg))
+		}
+	}
+	// A variation always has a value, so the Secret cannot be optional.
+	if ref.Optional != nil && *ref.Optional {
+		allErrs = append(allErrs, field.Invalid(refPath.Child("optional"), true, "a variation value cannot be optional"))
+	}
+	return allErrs
+}
+
+// validateRolloutPlan checks that the plan rolls out a known variation other
+// than its baseline, and that every step but the last lasts for some time.
+func validateRolloutPlan(plan *featurev1alpha1.RolloutPlan, baseline string, names map[string]bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateVariationRef(plan.Variation, names, fldPath.Child("variation"))...)
+	if plan.Baseline != "" {
+		allErrs = append(allErrs, validateVariationRef(plan.Baseline, names, fldPath.Child("baseline"))...)
+	}
+	if plan.Variation != "" && plan.Variation == baseline {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("variation"), plan.Variation, "must differ from the baseline variation"))
+	}
+
+	stepsPath := fldPath.Child("steps")
+	if len(plan.Steps) == 0 {
+		allErrs = append(allErrs, field.Required(stepsPath, "at least one step must be specified"))
+	}
+	for i, step := range plan.Steps {
+		idxPath := stepsPath.Index(i)
+		if step.Weight < 0 || step.Weight > 100 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("weight"), step.Weight, "must be between 0 and 100"))
+		}
+		if i < len(plan.Steps)-1 && step.Duration.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("duration"), step.Duration.Duration.String(), "must be greater than 0"))
+		}
+	}
+	return allErrs
+}
+
+// validateScheduledChange checks that the change is timed, changes something
+// and only refers to variations of the flag.
+func validateScheduledChange(change *featurev1alpha1.ScheduledChange, names map[string]bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if change.At.IsZero() {
+		allErrs = append(allErrs, field.Required(fldPath.Child("at"), ""))
+	}
+	if change.Enabled == nil && change.DefaultVariation == "" && change.OffVariation == "" && change.DefaultRollout == nil {
+		allErrs = append(allErrs, field.Required(fldPath, "at least one of enabled, defaultVariation, offVariation or defaultRollout must be specified"))
+	}
+	if change.DefaultVariation != "" {
+		allErrs = append(allErrs, validateVariationRef(change.DefaultVariation, names, fldPath.Child("defaultVariation"))...)
+	}
+	if change.OffVariation != "" {
+		allErrs = append(allErrs, validateVariationRef(change.OffVariation, names, fldPath.Child("offVariation"))...)
+	}
+	if change.DefaultRollout != nil {
+		allErrs = append(allErrs, validateRollout(change.DefaultRollout, names, fldPath.Child("defaultRollout"))...)
+	}
+	return allErrs
+}
+
+// validateRule checks the structure of a rule. Operators and clause values are
+// checked when the rule is compiled.
+func validateRule(rule *featurev1alpha1.Rule, names map[string]bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(rule.Clauses) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("clauses"), "at least one clause must be specified"))
+	}
+	for i, clause := range rule.Clauses {
+		segmentOperator := clause.Operator == featurev1alpha1.OperatorInSegment || clause.Operator == featurev1alpha1.OperatorNotInSegment
+		if clause.Attribute == "" && !segmentOperator {
+			allErrs = append(allErrs, field.Required(fldPath.Child("clauses").Index(i).Child("attribute"), ""))
+		}
+	}
+
+	switch {
+	case rule.Rollout == nil:
+		allErrs = append(allErrs, validateVariationRef(rule.Variation, names, fldPath.Child("variation"))...)
+	case rule.Variation != "":
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("variation"), rule.Variation, "may not be set together with rollout"))
+	default:
+		allErrs = append(allErrs, validateRollout(rule.Rollout, names, fldPath.Child("rollout"))...)
+	}
+	return allErrs
+}
+
+// validateRollout checks that the rollout refers to each variation at most
+// once and that its weights add up to 100%.
+func validateRollout(rollout *featurev1alpha1.Rollout, names map[string]bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	variationsPath := fldPath.Child("variations")
+	if len(rollout.Variations) == 0 {
+		return append(allErrs, field.Required(variationsPath, "at least one variation must be specified"))
+	}
+
+	var total int32
+	seen := map[string]bool{}
+	for i, wv := range rollout.Variations {
+		idxPath := variationsPath.Index(i)
+		allErrs = append(allErrs, validateVariationRef(wv.Variation, names, idxPath.Child("variation"))...)
+		if seen[wv.Variation] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("variation"), wv.Variation))
+		}
+		seen[wv.Variation] = true
+
+		if wv.Weight < 0 || wv.Weight > 100 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("weight"), wv.Weight, "must be between 0 and 100"))
+		}
+		total += wv.Weight
+	}
+	if total != 100 {
+		allErrs = append(allErrs, field.Invalid(variationsPath, total, "weights must add up to 100"))
+	}
+	return allErrs
+}
+
+// ValidateValue checks that value is a valid encoding for a flag of type t.
+func ValidateValue(t featurev1alpha1.FlagType, value string) error {
+	switch t {
+	case featurev1alpha1.FlagTypeBoolean:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("must be \"true\" or \"false\" for a %s flag", t)
+		}
+	case featurev1alpha1.FlagTypeNumber:
+		// ParseFloat accepts "NaN" and "Inf", neither of which can be published.
+		if f, err := strconv.ParseFloat(value, 64); err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("must be a number for a %s flag", t)
+		}
+	case featurev1alpha1.FlagTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("must be a valid JSON document for a %s flag", t)
+		}
+	}
+	return nil
+}
+
+func validateVariationRef(name string, names map[string]bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if name == "" {
+		allErrs = append(allErrs, field.Required(fldPath, ""))
+	} else if !names[name] {
+		allErrs = append(allErrs, field.NotFound(fldPath, name))
+	}
+	return allErrs
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// OriginalDataAnnotation holds, as JSON, the data a ConfigMap had when it
+// was adopted by a FeatureFlag, so that it can be restored on release.
+const OriginalDataAnnotation = "featureflags.featured.io/original-data"
+
+const (
+	// SuccessAdopted is used as part of the Event 'reason' when a FeatureFlag
+	// takes over an existing ConfigMap
+	SuccessAdopted = "Adopted"
+	// SuccessReleased is used as part of the Event 'reason' when a deleted
+	// FeatureFlag hands its ConfigMap back
+	SuccessReleased = "Released"
+
+	// MessageResourceAdopted is the message used for an Event fired when a
+	// FeatureFlag takes over an existing ConfigMap
+	MessageResourceAdopted = "ConfigMap %q adopted, its original data is backed up in the " + OriginalDataAnnotation + " annotation"
+	// MessageResourceReleased is the message used for an Event fired when a
+	// deleted FeatureFlag hands its ConfigMap back
+	MessageResourceReleased = "ConfigMap %q released"
+)
+
+// canAdopt reports whether the FeatureFlag may take over the ConfigMap: the
+// ConfigMap has no controller and adoption was requested on either of them.
+func canAdopt(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) bool {
+	if metav1.GetControllerOf(configmap) != nil {
+		return false
+	}
+	return featureflag.Annotations[samplev1alpha1.AdoptAnnotation] == "true" ||
+		configmap.Annotations[samplev1alpha1.AdoptAnnotation] == "true"
+}
+
+// adoptConfigMap returns a copy of the ConfigMap controlled by the
+// FeatureFlag and labelled with ManagedLabel, with its data backed up in
+// OriginalDataAnnotation. A backup
+// left by an earlier adoption is kept as it holds the data before any flag.
+func adoptConfigMap(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) (*corev1.ConfigMap, error) {
+	configmapCopy := configmap.DeepCopy()
+	if _, ok := configmapCopy.Annotations[OriginalDataAnnotation]; !ok {
+		data := configmapCopy.Data
+		if data == nil {
+			data = map[string]string{}
+		}
+		backup, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if configmapCopy.Annotations == nil {
+			configmapCopy.Annotations = map[string]string{}
+		}
+		configmapCopy.Annotations[OriginalDataAnnotation] = string(backup)
+	}
+	configmapCopy.OwnerReferences = append(configmapCopy.OwnerReferences,
+		*metav1.NewControllerRef(featureflag, samplev1alpha1.SchemeGroupVersion.WithKind("FeatureFlag")))
+	setManaged(configmapCopy)
+	return configmapCopy, nil
+}
+
+// releaseConfigMap returns a copy of the ConfigMap no longer owned by the
+// FeatureFlag, so the garbage collector leaves it alone. The data backed up
+// on adoption is restored; a ConfigMap created by the operator keeps the
+// payload last published. ManagedLabel and ContentHashesAnnotation are
+// removed once no owner is left.
+func releaseConfigMap(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) (*corev1.ConfigMap, error) {
+	configmapCopy := configmap.DeepCopy()
+	var ownerReferences []metav1.OwnerReference
+	for _, ref := range configmapCopy.OwnerReferences {
+		if ref.UID != featureflag.UID {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	configmapCopy.OwnerReferences = ownerReferences
+	if len(ownerReferences) == 0 {
+		delete(configmapCopy.Labels, ManagedLabel)
+		delete(configmapCopy.Annotations, ContentHashesAnnotation)
+	}
+
+	if backup, ok := configmapCopy.Annotations[OriginalDataAnnotation]; ok {
+		var data map[string]string
+		if err := json.Unmarshal([]byte(backup), &data); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %v", OriginalDataAnnotation, err)
+		}
+		configmapCopy.Data = data
+		delete(configmapCopy.Annotations, OriginalDataAnnotation)
+	}
+	return configmapCopy, nil
+}
+
+// releaseRequested reports whether the FeatureFlag hands its ConfigMap back
+// when it is deleted.
+func releaseRequested(featureflag *samplev1alpha1.FeatureFlag) bool {
+	return featureflag.Annotations[samplev1alpha1.OnDeleteAnnotation] == samplev1alpha1.OnDeleteRelease
+}
@@ -0,0 +1,146 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+var isController = true
+
+// newLegacyConfigMap returns a ConfigMap created by hand for the FeatureFlag.
+func newLegacyConfigMap(featureflag *featurecontroller.FeatureFlag) *core.ConfigMap {
+	return &core.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: featureflag.Spec.ConfigMapName, Namespace: featureflag.Namespace},
+		Data:       map[string]string{"legacy.properties": "enabled=true"},
+	}
+}
+
+// newAdoptedConfigMap returns the legacy ConfigMap once adopted by the FeatureFlag.
+func newAdoptedConfigMap(featureflag *featurecontroller.FeatureFlag, t *testing.T) *core.ConfigMap {
+	configmap := newConfigMapWithPayload(featureflag, t)
+	configmap.Annotations[OriginalDataAnnotation] = `{"legacy.properties":"enabled=true"}`
+	configmap.Data["legacy.properties"] = "enabled=true"
+	return configmap
+}
+
+// TestAdoptWithFlagAnnotation tests that a FeatureFlag annotated for adoption takes over a ConfigMap without a controller
+func TestAdoptWithFlagAnnotation(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	featureflag.Annotations = map[string]string{featurecontroller.AdoptAnnotation: "true"}
+	d := newLegacyConfigMap(featureflag)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newAdoptedConfigMap(featureflag, t)
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeNormal, SuccessAdopted, fmt.Sprintf(MessageResourceAdopted, d.Name)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestAdoptWithConfigMapAnnotation tests that a ConfigMap annotated for adoption is taken over by the FeatureFlag naming it
+func TestAdoptWithConfigMapAnnotation(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	d := newLegacyConfigMap(featureflag)
+	d.Annotations = map[string]string{featurecontroller.AdoptAnnotation: "true"}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newAdoptedConfigMap(featureflag, t)
+	expConfig.Annotations[featurecontroller.AdoptAnnotation] = "true"
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestAdoptControlledElsewhere tests that a ConfigMap controlled by another object is never adopted
+func TestAdoptControlledElsewhere(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	featureflag.Annotations = map[string]string{featurecontroller.AdoptAnnotation: "true"}
+	d := newLegacyConfigMap(featureflag)
+	d.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "other", UID: "uid-other", Controller: &isController}}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	msg := fmt.Sprintf(MessageResourceExists, d.Name)
+	expFlag := withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	expFlag = withCondition(expFlag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionFalse, ErrResourceExists, msg)
+	expFlag = withCondition(expFlag, featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrResourceExists, msg)
+	f.expectUpdateFooStatusAction(withSynced(expFlag))
+
+	f.runExpectError(getKey(featureflag, t))
+}
+
+// TestReleaseAdoptedConfigMap tests that a FeatureFlag deleted in release mode restores the data it backed up
+func TestReleaseAdoptedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newDeletedFeatureFlag("test")
+	featureflag.Annotations = map[string]string{featurecontroller.OnDeleteAnnotation: featurecontroller.OnDeleteRelease}
+	d := newAdoptedConfigMap(featureflag, t)
+
+	expConfig := newLegacyConfigMap(featureflag)
+	expConfig.Annotations = map[string]string{}
+	expConfig.Labels = map[string]string{}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(featureflag))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeNormal, SuccessReleased, fmt.Sprintf(MessageResourceReleased, d.Name)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+	f.expectDeletedEvent(d.Name)
+}
+
+// TestReleaseCreatedConfigMap tests that a ConfigMap created by the operator keeps its payload when released
+func TestReleaseCreatedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newDeletedFeatureFlag("test")
+	featureflag.Annotations = map[string]string{featurecontroller.OnDeleteAnnotation: featurecontroller.OnDeleteRelease}
+	d := newConfigMapWithPayload(featureflag, t)
+
+	expConfig := d.DeepCopy()
+	expConfig.OwnerReferences = nil
+	delete(expConfig.Labels, ManagedLabel)
+	delete(expConfig.Annotations, ContentHashesAnnotation)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(featureflag))
+
+	f.run(getKey(featureflag, t))
+}
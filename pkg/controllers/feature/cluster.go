@@ -0,0 +1,457 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/validation"
+	"github.com/featured.io/pkg/evaluation"
+)
+
+// ClusterFeatureFlagLabel is set on the ConfigMaps published by a
+// ClusterFeatureFlag to its name, so they can be found in every namespace.
+const ClusterFeatureFlagLabel = "featureflags.featured.io/cluster-feature-flag"
+
+const (
+	// nameIndex is the name of the FeatureFlag informer index listing the
+	// FeatureFlags of every namespace by name, which finds the flags
+	// overriding a ClusterFeatureFlag.
+	nameIndex = "byName"
+)
+
+const (
+	// MessageClusterResourceExists is the message used for Events when a
+	// ClusterFeatureFlag fails to sync as its ConfigMap already exists in
+	// some namespaces
+	MessageClusterResourceExists = "ConfigMap %q already exists and is not managed by ClusterFeatureFlag in namespaces %s"
+	// MessageClusterConfigMapSynced is the message used for the
+	// ConfigMapSynced condition of a published ClusterFeatureFlag
+	MessageClusterConfigMapSynced = "ConfigMap %q holds the current payload in %d namespaces"
+)
+
+// syncClusterHandler publishes the ClusterFeatureFlag into the ConfigMap of
+// every selected namespace without a FeatureFlag of the same name, and removes
+// it from the others.
+func (c *FeatureController) syncClusterHandler(name string) error {
+	clusterflag, err := c.clusterfeatureflagsLister.Get(name)
+	if err != nil {
+		// The ClusterFeatureFlag may no longer exist, in which case its
+		// ConfigMaps are deleted by the garbage collector.
+		if errors.IsNotFound(err) {
+			c.forgetCompiled(name)
+			utilruntime.HandleError(fmt.Errorf("clusterfeatureflag '%s' in work queue no longer exists", name))
+			return nil
+		}
+
+		return err
+	}
+
+	if errs := validation.ValidateClusterFeatureFlag(clusterflag); len(errs) > 0 {
+		return c.rejectClusterFeatureFlag(clusterflag, ErrInvalidSpec, fmt.Sprintf(MessageInvalidSpec, errs.ToAggregate()))
+	}
+
+	// The flag is scheduled, rolled out, compiled and rendered like a
+	// FeatureFlag. Its payload is the same in every namespace.
+	featureflag := featureFlagFor(clusterflag)
+	now := c.clock.Now()
+	effective, applied, next := applySchedule(featureflag, now)
+	rolloutPlan := advanceRolloutPlan(featureflag, now)
+	applyRolloutPlan(effective, rolloutPlan)
+	if rolloutPlan != nil && rolloutPlan.NextTransitionTime != nil && (next.IsZero() || rolloutPlan.NextTransitionTime.Time.Before(next)) {
+		next = rolloutPlan.NextTransitionTime.Time
+	}
+
+	flag, err := c.compile(name, effective, nil)
+	if err != nil {
+		return c.rejectClusterFeatureFlag(clusterflag, ErrInvalidRules, fmt.Sprintf(MessageInvalidRules, err))
+	}
+	served, _ := evaluation.NewSet(flag).Default(name)
+	_, content, err := renderPayload(effective, served.Variation, nil)
+	if err != nil {
+		return err
+	}
+
+	namespaces, err := c.selectNamespaces(clusterflag)
+	if err != nil {
+		return err
+	}
+	published := map[string]bool{}
+	var overridden, conflicts []string
+	for _, namespace := range namespaces {
+		_, err := c.featureflagsLister.FeatureFlags(namespace).Get(name)
+		if err == nil {
+			overridden = append(overridden, namespace)
+			continue
+		}
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		controlled, err := c.publishClusterFeatureFlag(clusterflag, effective, namespace, served.Variation)
+		if err != nil {
+			return err
+		}
+		if !controlled {
+			conflicts = append(conflicts, namespace)
+			continue
+		}
+		published[namespace] = true
+	}
+
+	if err := c.deleteStaleConfigMaps(clusterflag, published); err != nil {
+		return err
+	}
+
+	status := featureflag.Status.DeepCopy()
+	setAppliedChanges(status, applied, metav1.NewTime(now))
+	status.RolloutPlan = rolloutPlan
+	status.ContentHash = contentHash(content)
+
+	// Namespaces where the ConfigMap belongs to someone else are reported,
+	// and retried, while the flag is still published everywhere else.
+	if len(conflicts) > 0 {
+		msg := fmt.Sprintf(MessageClusterResourceExists, clusterflag.Spec.ConfigMapName, strings.Join(conflicts, ", "))
+		c.recorder.Event(clusterflag, corev1.EventTypeWarning, ErrResourceExists, msg)
+		err = c.updateClusterFeatureFlagStatus(clusterflag, status, int32(len(published)), overridden,
+			newCondition(samplev1alpha1.FeatureFlagValid, corev1.ConditionTrue, SuccessSynced, MessageValid),
+			newCondition(samplev1alpha1.FeatureFlagConfigMapSynced, corev1.ConditionFalse, ErrResourceExists, msg),
+			newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionFalse, ErrResourceExists, msg))
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf(msg)
+	}
+
+	err = c.updateClusterFeatureFlagStatus(clusterflag, status, int32(len(published)), overridden,
+		newCondition(samplev1alpha1.FeatureFlagValid, corev1.ConditionTrue, SuccessSynced, MessageValid),
+		newCondition(samplev1alpha1.FeatureFlagConfigMapSynced, corev1.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageClusterConfigMapSynced, clusterflag.Spec.ConfigMapName, len(published))),
+		newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionTrue, SuccessSynced, MessageResourceSynced))
+	if err != nil {
+		return err
+	}
+
+	c.recorder.Event(clusterflag, corev1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
+
+	if !next.IsZero() {
+		klog.V(4).Infof("ClusterFeatureFlag %s has a scheduled change or rollout step due at %s", name, next)
+		c.workqueue.AddAfter(name, next.Sub(now))
+	}
+	return nil
+}
+
+// publishClusterFeatureFlag renders the flag into its ConfigMap in the
+// namespace, creating the ConfigMap if needed. It reports false, and changes
+// nothing, when the ConfigMap is not controlled by the ClusterFeatureFlag.
+func (c *FeatureController) publishClusterFeatureFlag(clusterflag *samplev1alpha1.ClusterFeatureFlag, featureflag *samplev1alpha1.FeatureFlag, namespace, variation string) (bool, error) {
+	configmap, err := c.configmapsLister.ConfigMaps(namespace).Get(clusterflag.Spec.ConfigMapName)
+	if errors.IsNotFound(err) {
+		configmap = newClusterConfigMap(clusterflag, namespace)
+		if _, err = setPayload(configmap, featureflag, variation, nil); err != nil {
+			return false, err
+		}
+		var created bool
+		configmap, created, err = c.createConfigMap(namespace, configmap)
+		if err != nil || created {
+			return true, err
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	if !metav1.IsControlledBy(configmap, clusterflag) {
+		return false, nil
+	}
+
+	// NEVER modify objects from the store, so work on a copy.
+	configmapCopy := configmap.DeepCopy()
+	changed, err := setPayload(configmapCopy, featureflag, variation, nil)
+	if err != nil {
+		return true, err
+	}
+	if labelled := setManaged(configmapCopy); !changed && !labelled {
+		return true, nil
+	}
+	klog.V(4).Infof("ClusterFeatureFlag %s payload changed, updating configmap %s/%s", clusterflag.Name, namespace, configmap.Name)
+	_, err = c.configmapControl.UpdateConfigMap(namespace, configmapCopy)
+	if err == nil {
+		configmapUpdatedCount.WithLabelValues().Inc()
+	}
+	return true, err
+}
+
+// deleteStaleConfigMaps deletes the ConfigMaps of the ClusterFeatureFlag in
+// namespaces it is no longer published to, e.g. as they stopped matching the
+// selector or a FeatureFlag overrides it there, and ConfigMaps left behind by
+// a previous ConfigMap name.
+func (c *FeatureController) deleteStaleConfigMaps(clusterflag *samplev1alpha1.ClusterFeatureFlag, published map[string]bool) error {
+	configmaps, err := c.configmapsLister.List(labels.SelectorFromSet(labels.Set{ClusterFeatureFlagLabel: clusterflag.Name}))
+	if err != nil {
+		return err
+	}
+	for _, configmap := range configmaps {
+		if published[configmap.Namespace] && configmap.Name == clusterflag.Spec.ConfigMapName {
+			continue
+		}
+		if !metav1.IsControlledBy(configmap, clusterflag) {
+			continue
+		}
+		klog.V(4).Infof("ClusterFeatureFlag %s is no longer published to configmap %s/%s, deleting it", clusterflag.Name, configmap.Namespace, configmap.Name)
+		err := c.configmapControl.DeleteConfigMap(configmap.Namespace, configmap.Name)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		configmapDeletedCount.WithLabelValues().Inc()
+	}
+	return nil
+}
+
+// selectNamespaces returns the sorted names of the namespaces selected by the
+// ClusterFeatureFlag. Namespaces being deleted are left out as nothing can be
+// created in them.
+func (c *FeatureController) selectNamespaces(clusterflag *samplev1alpha1.ClusterFeatureFlag) ([]string, error) {
+	selector := labels.Everything()
+	if clusterflag.Spec.NamespaceSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(clusterflag.Spec.NamespaceSelector); err != nil {
+			return nil, err
+		}
+	}
+
+	namespaces, err := c.namespacesLister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, namespace := range namespaces {
+		if namespace.Status.Phase != corev1.NamespaceTerminating {
+			names = append(names, namespace.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// overriddenClusterFeatureFlag returns the name of the ClusterFeatureFlag the
+// FeatureFlag overrides in its namespace, or "" when no ClusterFeatureFlag of
+// the same name selects the namespace.
+func (c *FeatureController) overriddenClusterFeatureFlag(featureflag *samplev1alpha1.FeatureFlag) string {
+	clusterflag, err := c.clusterfeatureflagsLister.Get(featureflag.Name)
+	if err != nil {
+		return ""
+	}
+	namespace, err := c.namespacesLister.Get(featureflag.Namespace)
+	if err != nil {
+		return ""
+	}
+	if clusterflag.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(clusterflag.Spec.NamespaceSelector)
+		if err != nil || !selector.Matches(labels.Set(namespace.Labels)) {
+			return ""
+		}
+	}
+	return clusterflag.Name
+}
+
+// rejectClusterFeatureFlag is rejectFeatureFlag for a ClusterFeatureFlag. Its
+// ConfigMaps keep serving the last valid spec.
+func (c *FeatureController) rejectClusterFeatureFlag(clusterflag *samplev1alpha1.ClusterFeatureFlag, reason, msg string) error {
+	c.recorder.Event(clusterflag, corev1.EventTypeWarning, reason, msg)
+	utilruntime.HandleError(fmt.Errorf("%s: %s", clusterflag.Name, msg))
+	return c.updateClusterFeatureFlagStatus(clusterflag, &featureFlagFor(clusterflag).Status,
+		clusterflag.Status.Namespaces, clusterflag.Status.OverriddenNamespaces,
+		newCondition(samplev1alpha1.FeatureFlagValid, corev1.ConditionFalse, reason, msg),
+		newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionFalse, reason, msg))
+}
+
+// updateClusterFeatureFlagStatus is updateFeatureFlagStatus for a
+// ClusterFeatureFlag, whose status also counts the namespaces it is published
+// to and lists those where it is overridden.
+func (c *FeatureController) updateClusterFeatureFlagStatus(clusterflag *samplev1alpha1.ClusterFeatureFlag, status *samplev1alpha1.FeatureFlagStatus, namespaces int32, overridden []string, conditions ...samplev1alpha1.FeatureFlagCondition) error {
+	now := metav1.NewTime(c.clock.Now())
+	for _, condition := range conditions {
+		setCondition(status, condition, now)
+	}
+	clusterStatus := samplev1alpha1.ClusterFeatureFlagStatus{
+		ObservedGeneration:   clusterflag.Generation,
+		ContentHash:          status.ContentHash,
+		LastSyncTime:         clusterflag.Status.LastSyncTime,
+		Conditions:           status.Conditions,
+		AppliedChanges:       status.AppliedChanges,
+		RolloutPlan:          status.RolloutPlan,
+		Namespaces:           namespaces,
+		OverriddenNamespaces: overridden,
+	}
+	if equality.Semantic.DeepEqual(clusterflag.Status, clusterStatus) {
+		return nil
+	}
+	clusterStatus.LastSyncTime = &now
+
+	// NEVER modify objects from the store.
+	clusterflagCopy := clusterflag.DeepCopy()
+	clusterflagCopy.Status = clusterStatus
+	_, err := c.featureclientset.FeaturecontrollerV1alpha1().ClusterFeatureFlags().UpdateStatus(context.TODO(), clusterflagCopy, metav1.UpdateOptions{})
+	return err
+}
+
+// featureFlagFor returns the FeatureFlag published by a ClusterFeatureFlag,
+// so it can be scheduled, rolled out, compiled and rendered like any other.
+func featureFlagFor(clusterflag *samplev1alpha1.ClusterFeatureFlag) *samplev1alpha1.FeatureFlag {
+	clusterflag = clusterflag.DeepCopy()
+	return &samplev1alpha1.FeatureFlag{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        clusterflag.Name,
+			UID:         clusterflag.UID,
+			Generation:  clusterflag.Generation,
+			Annotations: clusterflag.Annotations,
+		},
+		Spec: clusterflag.Spec.FeatureFlagSpec,
+		Status: samplev1alpha1.FeatureFlagStatus{
+			ObservedGeneration: clusterflag.Status.ObservedGeneration,
+			ContentHash:        clusterflag.Status.ContentHash,
+			LastSyncTime:       clusterflag.Status.LastSyncTime,
+			Conditions:         clusterflag.Status.Conditions,
+			AppliedChanges:     clusterflag.Status.AppliedChanges,
+			RolloutPlan:        clusterflag.Status.RolloutPlan,
+		},
+	}
+}
+
+// newClusterConfigMap creates a new ConfigMap for a ClusterFeatureFlag in the
+// namespace, controlled by the ClusterFeatureFlag and labelled with its name
+// and ManagedLabel. The payload is added by setPayload.
+func newClusterConfigMap(clusterflag *samplev1alpha1.ClusterFeatureFlag, namespace string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterflag.Spec.ConfigMapName,
+			Namespace: namespace,
+			Labels:    map[string]string{ClusterFeatureFlagLabel: clusterflag.Name, ManagedLabel: "true"},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(clusterflag, samplev1alpha1.SchemeGroupVersion.WithKind("ClusterFeatureFlag")),
+			},
+		},
+	}
+}
+
+// enqueueClusterFeatureFlag takes a ClusterFeatureFlag, or its tombstone, and
+// enqueues it along with the FeatureFlags of the same name, whose Overrides
+// status may have changed.
+func (c *FeatureController) enqueueClusterFeatureFlag(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+
+	featureflags, err := c.featureflagsIndexer.ByIndex(nameIndex, key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, featureflag := range featureflags {
+		c.enqueueFeatureFlag(featureflag)
+	}
+}
+
+// enqueueOverridden takes a FeatureFlag, or its tombstone, and enqueues the
+// ClusterFeatureFlag of the same name, which it starts or stops overriding.
+func (c *FeatureController) enqueueOverridden(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	_, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if _, err := c.clusterfeatureflagsLister.Get(name); err == nil {
+		c.workqueue.Add(name)
+	}
+}
+
+// handleNamespace takes a Namespace, or its tombstone, and enqueues every
+// ClusterFeatureFlag, as the namespaces they select may have changed, and the
+// FeatureFlags of the namespace overriding one of them.
+func (c *FeatureController) handleNamespace(obj interface{}) {
+	var object metav1.Object
+	var ok bool
+	if object, ok = obj.(metav1.Object); !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		object, ok = tombstone.Obj.(metav1.Object)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+		klog.V(4).Infof("Recovered deleted namespace '%s' from tombstone", object.GetName())
+	}
+	klog.V(4).Infof("Processing namespace: %s", object.GetName())
+
+	clusterflags, err := c.clusterfeatureflagsLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, clusterflag := range clusterflags {
+		c.workqueue.Add(clusterflag.Name)
+		if featureflag, err := c.featureflagsLister.FeatureFlags(object.GetName()).Get(clusterflag.Name); err == nil {
+			c.enqueueFeatureFlag(featureflag)
+		}
+	}
+}
+
+// handleClusterObject enqueues the ClusterFeatureFlag controlling a ConfigMap
+// and the FeatureFlag overriding it in the namespace of the ConfigMap, which
+// may be waiting for the ConfigMap to be deleted to take its name over.
+func (c *FeatureController) handleClusterObject(object metav1.Object, ownerRef *metav1.OwnerReference) {
+	clusterflag, err := c.clusterfeatureflagsLister.Get(ownerRef.Name)
+	if err != nil {
+		klog.V(4).Infof("ignoring orphaned object '%s' of clusterfeatureflag '%s'", object.GetSelfLink(), ownerRef.Name)
+		return
+	}
+	c.workqueue.Add(clusterflag.Name)
+
+	if featureflag, err := c.featureflagsLister.FeatureFlags(object.GetNamespace()).Get(clusterflag.Name); err == nil {
+		c.enqueueFeatureFlag(featureflag)
+	}
+}
+
+// indexByName is the cache.IndexFunc of nameIndex.
+func indexByName(obj interface{}) ([]string, error) {
+	featureflag, ok := obj.(*samplev1alpha1.FeatureFlag)
+	if !ok {
+		return nil, nil
+	}
+	return []string{featureflag.Name}, nil
+}
@@ -0,0 +1,196 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubetesting "k8s.io/client-go/testing"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/validation"
+)
+
+func newClusterFeatureFlag(name string) *featurecontroller.ClusterFeatureFlag {
+	return &featurecontroller.ClusterFeatureFlag{
+		TypeMeta:   metav1.TypeMeta{APIVersion: featurecontroller.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       featurecontroller.ClusterFeatureFlagSpec{FeatureFlagSpec: newFeatureFlag(name).Spec},
+	}
+}
+
+func newNamespace(name string, labels map[string]string) *core.Namespace {
+	return &core.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+// newClusterConfigMapWithPayload returns the ConfigMap published by the
+// ClusterFeatureFlag in the namespace.
+func newClusterConfigMapWithPayload(clusterflag *featurecontroller.ClusterFeatureFlag, namespace string, t *testing.T) *core.ConfigMap {
+	featureflag := featureFlagFor(clusterflag)
+	configmap := newClusterConfigMap(clusterflag, namespace)
+	if _, err := setPayload(configmap, featureflag, defaultVariation(featureflag), nil); err != nil {
+		t.Fatalf("Unexpected error rendering clusterfeatureflag %v: %v", clusterflag.Name, err)
+	}
+	return configmap
+}
+
+// withClusterStatus returns a copy of the ClusterFeatureFlag with the status
+// written by the controller at testNow.
+func withClusterStatus(clusterflag *featurecontroller.ClusterFeatureFlag, contentHash string, namespaces int32, overridden []string, conditions ...featurecontroller.FeatureFlagCondition) *featurecontroller.ClusterFeatureFlag {
+	status := &featurecontroller.FeatureFlagStatus{}
+	for _, condition := range conditions {
+		setCondition(status, condition, metav1.NewTime(testNow))
+	}
+	clusterflag = clusterflag.DeepCopy()
+	clusterflag.Status = featurecontroller.ClusterFeatureFlagStatus{
+		ObservedGeneration:   clusterflag.Generation,
+		ContentHash:          contentHash,
+		LastSyncTime:         &metav1.Time{Time: testNow},
+		Conditions:           status.Conditions,
+		Namespaces:           namespaces,
+		OverriddenNamespaces: overridden,
+	}
+	return clusterflag
+}
+
+// withClusterValid returns a copy of the ClusterFeatureFlag as updated by a
+// successful sync publishing the ConfigMap to namespaces.
+func withClusterValid(clusterflag *featurecontroller.ClusterFeatureFlag, configmap *core.ConfigMap, namespaces int32, overridden ...string) *featurecontroller.ClusterFeatureFlag {
+	return withClusterStatus(clusterflag, contentHash(configmap.Data[payloadKey(featureFlagFor(clusterflag))]), namespaces, overridden,
+		newCondition(featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid),
+		newCondition(featurecontroller.FeatureFlagConfigMapSynced, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageClusterConfigMapSynced, configmap.Name, namespaces)),
+		newCondition(featurecontroller.FeatureFlagReady, core.ConditionTrue, SuccessSynced, MessageResourceSynced))
+}
+
+func (f *fixture) expectUpdateClusterStatusAction(clusterflag *featurecontroller.ClusterFeatureFlag) {
+	action := kubetesting.NewUpdateAction(schema.GroupVersionResource{Resource: "clusterfeatureflags"}, "", clusterflag)
+	action.Subresource = "status"
+	f.actions = append(f.actions, action)
+}
+
+// TestClusterFeatureFlagSelector tests that a ClusterFeatureFlag is only published to the namespaces it selects
+func TestClusterFeatureFlagSelector(t *testing.T) {
+	f := newFixture(t)
+	clusterflag := newClusterFeatureFlag("test")
+	clusterflag.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+	terminating := newNamespace("team-a-old", map[string]string{"team": "a"})
+	terminating.Status.Phase = core.NamespaceTerminating
+
+	f.clusterfeatureflagLister = append(f.clusterfeatureflagLister, clusterflag)
+	f.objects = append(f.objects, clusterflag)
+	f.namespaceLister = append(f.namespaceLister,
+		newNamespace("team-a", map[string]string{"team": "a"}), newNamespace("team-b", map[string]string{"team": "b"}), terminating)
+
+	expConfig := newClusterConfigMapWithPayload(clusterflag, "team-a", t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateClusterStatusAction(withClusterValid(clusterflag, expConfig, 1))
+
+	f.run(clusterflag.Name)
+}
+
+// TestClusterFeatureFlagOverridden tests that a FeatureFlag of the same name replaces the ClusterFeatureFlag in its namespace
+func TestClusterFeatureFlagOverridden(t *testing.T) {
+	f := newFixture(t)
+	clusterflag := newClusterFeatureFlag("test")
+	featureflag := newFeatureFlag("test")
+	featureflag.Namespace = "team-a"
+	published := newClusterConfigMapWithPayload(clusterflag, "team-a", t)
+
+	f.clusterfeatureflagLister = append(f.clusterfeatureflagLister, clusterflag)
+	f.objects = append(f.objects, clusterflag)
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("team-a", nil), newNamespace("team-b", nil))
+	f.configmapLister = append(f.configmapLister, published)
+	f.kubeobjects = append(f.kubeobjects, published)
+
+	expConfig := newClusterConfigMapWithPayload(clusterflag, "team-b", t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectDeleteConfigMapAction(published)
+	f.expectUpdateClusterStatusAction(withClusterValid(clusterflag, expConfig, 1, "team-a"))
+
+	f.run(clusterflag.Name)
+}
+
+// TestClusterFeatureFlagNotControlledByUs tests that a ConfigMap owned by someone else is reported without blocking other namespaces
+func TestClusterFeatureFlagNotControlledByUs(t *testing.T) {
+	f := newFixture(t)
+	clusterflag := newClusterFeatureFlag("test")
+	existing := newClusterConfigMapWithPayload(clusterflag, "team-a", t)
+	existing.OwnerReferences = nil
+
+	f.clusterfeatureflagLister = append(f.clusterfeatureflagLister, clusterflag)
+	f.objects = append(f.objects, clusterflag)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("team-a", nil), newNamespace("team-b", nil))
+	f.configmapLister = append(f.configmapLister, existing)
+	f.kubeobjects = append(f.kubeobjects, existing)
+
+	expConfig := newClusterConfigMapWithPayload(clusterflag, "team-b", t)
+	msg := fmt.Sprintf(MessageClusterResourceExists, existing.Name, "team-a")
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateClusterStatusAction(withClusterStatus(clusterflag, contentHash(expConfig.Data[payloadKey(featureFlagFor(clusterflag))]), 1, nil,
+		newCondition(featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid),
+		newCondition(featurecontroller.FeatureFlagConfigMapSynced, core.ConditionFalse, ErrResourceExists, msg),
+		newCondition(featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrResourceExists, msg)))
+
+	f.runExpectError(clusterflag.Name)
+}
+
+// TestClusterFeatureFlagInvalidSpec tests that prerequisites are rejected on a ClusterFeatureFlag without publishing it
+func TestClusterFeatureFlagInvalidSpec(t *testing.T) {
+	f := newFixture(t)
+	clusterflag := newClusterFeatureFlag("test")
+	clusterflag.Spec.Prerequisites = []featurecontroller.Prerequisite{{Flag: "other", Variation: "on"}}
+
+	f.clusterfeatureflagLister = append(f.clusterfeatureflagLister, clusterflag)
+	f.objects = append(f.objects, clusterflag)
+	f.namespaceLister = append(f.namespaceLister, newNamespace("team-a", nil))
+
+	msg := fmt.Sprintf(MessageInvalidSpec, validation.ValidateClusterFeatureFlag(clusterflag).ToAggregate())
+	f.expectUpdateClusterStatusAction(withClusterStatus(clusterflag, "", 0, nil,
+		newCondition(featurecontroller.FeatureFlagValid, core.ConditionFalse, ErrInvalidSpec, msg),
+		newCondition(featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrInvalidSpec, msg)))
+
+	f.run(clusterflag.Name)
+}
+
+// TestFeatureFlagOverrides tests that a FeatureFlag reports the ClusterFeatureFlag it overrides
+func TestFeatureFlagOverrides(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	clusterflag := newClusterFeatureFlag("test")
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.clusterfeatureflagLister = append(f.clusterfeatureflagLister, clusterflag)
+	f.namespaceLister = append(f.namespaceLister, newNamespace(featureflag.Namespace, nil))
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	expFlag := withValid(featureflag, expConfig)
+	expFlag.Status.Overrides = clusterflag.Name
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(expFlag)
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestNamespaceEnqueuesClusterFeatureFlags tests that a namespace change enqueues every ClusterFeatureFlag and the flags overriding them there
+func TestNamespaceEnqueuesClusterFeatureFlags(t *testing.T) {
+	f := newFixture(t)
+	overriding := newFeatureFlag("test")
+	overriding.Namespace = "team-a"
+	f.clusterfeatureflagLister = append(f.clusterfeatureflagLister, newClusterFeatureFlag("test"))
+	f.featureflagLister = append(f.featureflagLister, overriding, newFeatureFlag("unrelated"))
+
+	c, _, _ := f.newFeatureController()
+	c.handleNamespace(newNamespace("team-a", map[string]string{"team": "a"}))
+
+	var keys []interface{}
+	for c.workqueue.Len() > 0 {
+		key, _ := c.workqueue.Get()
+		keys = append(keys, key)
+	}
+	require.ElementsMatch(t, []interface{}{"test", "team-a/test"}, keys)
+}
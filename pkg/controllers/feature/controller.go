@@ -15,18 +15,28 @@ package feature
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
@@ -34,6 +44,8 @@ import (
 	"k8s.io/klog"
 
 	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/validation"
+	"github.com/featured.io/pkg/evaluation"
 	clientset "github.com/featured.io/pkg/generated/clientset/versioned"
 	samplescheme "github.com/featured.io/pkg/generated/clientset/versioned/scheme"
 	informers "github.com/featured.io/pkg/generated/informers/externalversions/feature/v1alpha1"
@@ -42,6 +54,15 @@ import (
 
 const controllerAgentName = "feature-controller"
 
+const (
+	// segmentIndex is the name of the FeatureFlag informer index listing the
+	// FeatureSegments, as namespace/name, referenced by each FeatureFlag.
+	segmentIndex = "bySegment"
+	// prerequisiteIndex is the name of the FeatureFlag informer index listing
+	// the prerequisite FeatureFlags, as namespace/name, of each FeatureFlag.
+	prerequisiteIndex = "byPrerequisite"
+)
+
 const (
 	// SuccessSynced is used as part of the Event 'reason' when a FeatureFlag is synced
 	SuccessSynced = "Synced"
@@ -49,12 +70,37 @@ const (
 	// to sync due to a Deployment of the same name already existing.
 	ErrResourceExists = "ErrResourceExists"
 
+	// ErrInvalidSpec is used as part of the Event 'reason' when a FeatureFlag fails
+	// to sync due to an invalid spec
+	ErrInvalidSpec = "ErrInvalidSpec"
+	// ErrInvalidRules is used as part of the Event 'reason' when the rules of a
+	// FeatureFlag fail to compile, e.g. due to an invalid regex or version
+	ErrInvalidRules = "ErrInvalidRules"
+	// ErrPrerequisiteCycle is used as part of the Event 'reason' when the
+	// prerequisites of a FeatureFlag lead back to a flag already visited
+	ErrPrerequisiteCycle = "ErrPrerequisiteCycle"
+
 	// MessageResourceExists is the message used for Events when a resource
 	// fails to sync due to a Deployment already existing
 	MessageResourceExists = "Resource %q already exists and is not managed by FeatureFlag"
+	// MessageInvalidSpec is the message used for Events when a FeatureFlag
+	// fails validation
+	MessageInvalidSpec = "FeatureFlag spec is invalid: %s"
+	// MessageInvalidRules is the message used for Events when the rules of a
+	// FeatureFlag fail to compile
+	MessageInvalidRules = "FeatureFlag rules are invalid: %s"
+	// MessagePrerequisiteCycle is the message used for Events when the
+	// prerequisites of a FeatureFlag form a cycle
+	MessagePrerequisiteCycle = "FeatureFlag prerequisites form a cycle: %s"
+	// MessageValid is the message used for the Valid condition of a FeatureFlag
+	// whose spec and rules are valid
+	MessageValid = "FeatureFlag spec is valid"
 	// MessageResourceSynced is the message used for an Event fired when a FeatureFlag
 	// is synced successfully
 	MessageResourceSynced = "FeatureFlag synced successfully"
+	// MessageConfigMapSynced is the message used for the ConfigMapSynced
+	// condition of a FeatureFlag whose payload is published
+	MessageConfigMapSynced = "ConfigMap %q holds the current payload"
 )
 
 // FeatureController is the controller implementation for Foo resources
@@ -68,9 +114,40 @@ type FeatureController struct {
 	configmapsSynced cache.InformerSynced
 	// configmapControl enables control of ConfigMaps associated with FeatureFlag
 	configmapControl ConfigMapControlInterface
-
-	featureflagsLister listers.FeatureFlagLister
-	featureflagsSynced cache.InformerSynced
+	// secretControl enables control of the Secrets FeatureFlags are
+	// published to or read values from. It is nil unless EnableSecrets was
+	// called.
+	secretControl SecretControlInterface
+	// secretsLister lists the Secrets FeatureFlags are published to or read
+	// values from. It is nil unless EnableSecrets was called.
+	secretsLister corelisters.SecretLister
+	secretsSynced cache.InformerSynced
+	// publishers publish the payload of FeatureFlags, keyed by their
+	// PublishTarget or the type of the additional publishers.
+	publishers map[string]Publisher
+
+	featureflagsLister  listers.FeatureFlagLister
+	featureflagsSynced  cache.InformerSynced
+	featureflagsIndexer cache.Indexer
+
+	featuresegmentsLister listers.FeatureSegmentLister
+	featuresegmentsSynced cache.InformerSynced
+
+	clusterfeatureflagsLister listers.ClusterFeatureFlagLister
+	clusterfeatureflagsSynced cache.InformerSynced
+
+	// namespacesLister lists the namespaces ClusterFeatureFlags are
+	// published to.
+	namespacesLister corelisters.NamespaceLister
+	namespacesSynced cache.InformerSynced
+
+	// deploymentsLister, statefulsetsLister and daemonsetsLister list the
+	// apps FeatureFlags are injected into. They are nil unless
+	// EnableInjection was called.
+	deploymentsLister  appslisters.DeploymentLister
+	statefulsetsLister appslisters.StatefulSetLister
+	daemonsetsLister   appslisters.DaemonSetLister
+	appsSynced         []cache.InformerSynced
 
 	// workqueue is a rate limited work queue. This is used to queue work to be
 	// processed instead of performing it as soon as a change happens. This
@@ -81,6 +158,31 @@ type FeatureController struct {
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
 	recorder record.EventRecorder
+	// clock is used for condition transition times so tests can control it.
+	clock clock.Clock
+
+	// compiled caches the compiled rules of each FeatureFlag, keyed by
+	// namespace/name, so rules are only compiled when the spec in effect
+	// changes.
+	compiledLock sync.Mutex
+	compiled     map[string]compiledFlag
+
+	// cachesSynced is set to 1 once the informer caches have synced.
+	cachesSynced int32
+	// inFlight holds the time each worker started processing its current
+	// item, so that stalled workers are reported by CheckWorkers.
+	inFlightLock sync.Mutex
+	inFlight     map[interface{}]time.Time
+}
+
+// compiledFlag is a FeatureFlag spec, identified by its hash, compiled
+// against given versions of the FeatureSegments it references.
+type compiledFlag struct {
+	uid      types.UID
+	specHash string
+	segments string
+	flag     *evaluation.Flag
+	err      error
 }
 
 // NewFeatureController returns a new feature controller
@@ -88,7 +190,10 @@ func NewFeatureController(
 	kubeclientset kubernetes.Interface,
 	featureclientset clientset.Interface,
 	configmapInformer coreinformers.ConfigMapInformer,
-	featureflagInformer informers.FeatureFlagInformer) *FeatureController {
+	namespaceInformer coreinformers.NamespaceInformer,
+	featureflagInformer informers.FeatureFlagInformer,
+	featuresegmentInformer informers.FeatureSegmentInformer,
+	clusterfeatureflagInformer informers.ClusterFeatureFlagInformer) *FeatureController {
 
 	// Create event broadcaster
 	// Add feature-controller types to the default Kubernetes Scheme so Events can be
@@ -101,24 +206,66 @@ func NewFeatureController(
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
 
 	controller := &FeatureController{
-		kubeclientset:      kubeclientset,
-		featureclientset:   featureclientset,
-		configmapsLister:   configmapInformer.Lister(),
-		configmapsSynced:   configmapInformer.Informer().HasSynced,
-		configmapControl:   NewConfigMapControl(kubeclientset),
-		featureflagsLister: featureflagInformer.Lister(),
-		featureflagsSynced: featureflagInformer.Informer().HasSynced,
-		workqueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "FeatureFlags"),
-		recorder:           recorder,
+		kubeclientset:         kubeclientset,
+		featureclientset:      featureclientset,
+		configmapsLister:      configmapInformer.Lister(),
+		configmapsSynced:      configmapInformer.Informer().HasSynced,
+		configmapControl:      NewConfigMapControl(kubeclientset),
+		featureflagsLister:    featureflagInformer.Lister(),
+		featureflagsSynced:    featureflagInformer.Informer().HasSynced,
+		featureflagsIndexer:   featureflagInformer.Informer().GetIndexer(),
+		featuresegmentsLister: featuresegmentInformer.Lister(),
+		featuresegmentsSynced: featuresegmentInformer.Informer().HasSynced,
+		workqueue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "FeatureFlags"),
+		recorder:              recorder,
+		clock:                 clock.RealClock{},
+		compiled:              map[string]compiledFlag{},
+		inFlight:              map[interface{}]time.Time{},
+
+		clusterfeatureflagsLister: clusterfeatureflagInformer.Lister(),
+		clusterfeatureflagsSynced: clusterfeatureflagInformer.Informer().HasSynced,
+		namespacesLister:          namespaceInformer.Lister(),
+		namespacesSynced:          namespaceInformer.Informer().HasSynced,
+	}
+
+	controller.publishers = map[string]Publisher{
+		string(samplev1alpha1.PublishTargetConfigMap): &configMapPublisher{c: controller},
 	}
 
+	// Index FeatureFlags by the FeatureSegments and FeatureFlags they
+	// reference so a change only re-enqueues the flags depending on it.
+	utilruntime.Must(featureflagInformer.Informer().AddIndexers(cache.Indexers{
+		segmentIndex:      indexBySegment,
+		prerequisiteIndex: indexByPrerequisite,
+		nameIndex:         indexByName,
+	}))
+
 	klog.Info("Setting up event handlers")
 
-	// Set up an event handler for when FeatureFlag resources change
+	// Set up an event handler for when FeatureFlag resources change. The
+	// flags depending on a FeatureFlag are re-synced whenever its spec,
+	// annotations, applied scheduled changes or rollout plan progress change
+	// as the variation it resolves to may have changed too.
 	featureflagInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: controller.enqueueFeatureFlag,
+		AddFunc: func(obj interface{}) {
+			controller.enqueueFeatureFlag(obj)
+			controller.enqueueDependents(obj)
+			controller.enqueueOverridden(obj)
+		},
 		UpdateFunc: func(old, new interface{}) {
 			controller.enqueueFeatureFlag(new)
+			oldFlag := old.(*samplev1alpha1.FeatureFlag)
+			newFlag := new.(*samplev1alpha1.FeatureFlag)
+			if !reflect.DeepEqual(oldFlag.Spec, newFlag.Spec) || !reflect.DeepEqual(oldFlag.Annotations, newFlag.Annotations) ||
+				!reflect.DeepEqual(oldFlag.Status.AppliedChanges, newFlag.Status.AppliedChanges) ||
+				!reflect.DeepEqual(oldFlag.Status.RolloutPlan, newFlag.Status.RolloutPlan) {
+				controller.enqueueDependents(new)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			controller.enqueueFeatureFlag(obj)
+			controller.enqueueDependents(obj)
+			controller.enqueueOverridden(obj)
 		},
 	})
 
@@ -147,13 +294,57 @@ func NewFeatureController(
 		DeleteFunc: controller.handleObject,
 	})
 
+	// Set up an event handler for when FeatureSegment resources change. Like
+	// handleObject for ConfigMaps, it enqueues the FeatureFlags referencing
+	// the segment so their rules are compiled against the new segment.
+	featuresegmentInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleSegment,
+		UpdateFunc: func(old, new interface{}) {
+			newSegment := new.(*samplev1alpha1.FeatureSegment)
+			oldSegment := old.(*samplev1alpha1.FeatureSegment)
+			if newSegment.ResourceVersion == oldSegment.ResourceVersion {
+				// Periodic resync will send update events for all known FeatureSegments.
+				return
+			}
+			controller.handleSegment(new)
+		},
+		DeleteFunc: controller.handleSegment,
+	})
+
+	// Set up an event handler for when ClusterFeatureFlag resources change.
+	// Their keys have no namespace. The FeatureFlags of the same name are
+	// enqueued as they report whether they override the ClusterFeatureFlag.
+	clusterfeatureflagInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueClusterFeatureFlag,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueClusterFeatureFlag(new)
+		},
+		DeleteFunc: controller.enqueueClusterFeatureFlag,
+	})
+
+	// Set up an event handler for when Namespaces are created, relabelled or
+	// deleted, which changes the namespaces ClusterFeatureFlags select.
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleNamespace,
+		UpdateFunc: func(old, new interface{}) {
+			newNamespace := new.(*corev1.Namespace)
+			oldNamespace := old.(*corev1.Namespace)
+			if reflect.DeepEqual(oldNamespace.Labels, newNamespace.Labels) && oldNamespace.Status.Phase == newNamespace.Status.Phase {
+				return
+			}
+			controller.handleNamespace(new)
+		},
+		DeleteFunc: controller.handleNamespace,
+	})
+
 	return controller
 }
 
 // Run will set up the event handlers for types we are interested in, as well
 // as syncing informer caches and starting workers. It will block until stopCh
 // is closed, at which point it will shutdown the workqueue and wait for
-// workers to finish processing their current work items.
+// workers to drain it. Items waiting to be retried after a back-off are
+// dropped, and resynced on the next start.
 func (c *FeatureController) Run(threadiness int, stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
@@ -163,21 +354,37 @@ func (c *FeatureController) Run(threadiness int, stopCh <-chan struct{}) error {
 
 	// Wait for the caches to be synced before starting workers
 	klog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.configmapsSynced, c.featureflagsSynced); !ok {
+	if ok := c.WaitForCacheSync(stopCh); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
+	c.runWorkers(threadiness, stopCh)
+	return nil
+}
+
+// runWorkers runs threadiness workers until stopCh is closed, then shuts the
+// workqueue down and returns once the workers have processed every item
+// left in it.
+func (c *FeatureController) runWorkers(threadiness int, stopCh <-chan struct{}) {
 	klog.Info("Starting workers")
-	// Launch two workers to process Foo resources
+	var wg sync.WaitGroup
 	for i := 0; i < threadiness; i++ {
-		go wait.Until(c.runWorker, time.Second, stopCh)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait.Until(c.runWorker, time.Second, stopCh)
+		}()
 	}
 
 	klog.Info("Started workers")
 	<-stopCh
 	klog.Info("Shutting down workers")
-
-	return nil
+	c.workqueue.ShutDown()
+	wg.Wait()
+	// A worker started after stopCh was closed never runs, so process what
+	// is left; runWorker returns once the shut down workqueue is empty.
+	c.runWorker()
+	klog.Info("Workers drained the workqueue")
 }
 
 // runWorker is a long-running function that will continually call the
@@ -206,6 +413,8 @@ func (c *FeatureController) processNextWorkItem() bool {
 		// put back on the workqueue and attempted again after a back-off
 		// period.
 		defer c.workqueue.Done(obj)
+		c.startWork(obj)
+		defer c.finishWork(obj)
 		var key string
 		var ok bool
 		// We expect strings to come off the workqueue. These are of the
@@ -255,12 +464,18 @@ func (c *FeatureController) syncHandler(key string) error {
 		return nil
 	}
 
+	// ClusterFeatureFlags are cluster scoped, so their keys have no namespace.
+	if namespace == "" {
+		return c.syncClusterHandler(name)
+	}
+
 	// Get the FeatureFlag resource with this namespace/name
 	featureflag, err := c.featureflagsLister.FeatureFlags(namespace).Get(name)
 	if err != nil {
 		// The FeatureFlag resource may no longer exist, in which case we stop
 		// processing.
 		if errors.IsNotFound(err) {
+			c.forgetCompiled(key)
 			utilruntime.HandleError(fmt.Errorf("featureflag '%s' in work queue no longer exists", key))
 			return nil
 		}
@@ -268,87 +483,358 @@ func (c *FeatureController) syncHandler(key string) error {
 		return err
 	}
 
-	configmapName := featureflag.Spec.ConfigMapName
-	if configmapName == "" {
-		// We choose to absorb the error here as the worker would requeue the
-		// resource otherwise. Instead, the next time the resource is updated
-		// the resource will be queued again.
-		utilruntime.HandleError(fmt.Errorf("%s: configmap name must be specified", key))
-		return nil
+	// A FeatureFlag being deleted only has its payload removed. The finalizer
+	// holds the deletion until then, as the garbage collector only deletes
+	// the ConfigMaps the flag controls.
+	if featureflag.DeletionTimestamp != nil {
+		return c.finalizeFeatureFlag(key, featureflag)
+	}
+
+	// Reject invalid specs, such as a missing ConfigMap name or variations
+	// that don't match the declared flag type. We choose to absorb the error
+	// here as the worker would requeue the resource otherwise; it is reported
+	// on the FeatureFlag instead and the ConfigMap keeps serving the last
+	// valid spec. The validating webhook rejects these specs on admission.
+	if errs := validation.ValidateFeatureFlag(featureflag); len(errs) > 0 {
+		return c.rejectFeatureFlag(key, featureflag, ErrInvalidSpec, fmt.Sprintf(MessageInvalidSpec, errs.ToAggregate()))
+	}
+	if c.secretControl == nil && usesSecrets(&featureflag.Spec) {
+		return c.rejectFeatureFlag(key, featureflag, ErrSecretsDisabled, MessageSecretsDisabled)
+	}
+
+	// Reject flags whose prerequisites lead back to a flag already visited.
+	// The cycle is reported on every flag in or depending on it.
+	if cycle := evaluation.FindCycle(name, c.prerequisitesOf(namespace)); cycle != nil {
+		return c.rejectFeatureFlag(key, featureflag, ErrPrerequisiteCycle, fmt.Sprintf(MessagePrerequisiteCycle, strings.Join(cycle, " -> ")))
+	}
+
+	// Apply the scheduled changes that are due and the current step of the
+	// rollout plan. The rest of the sync works on the resulting spec, and
+	// the flag is requeued for the next change or step.
+	now := c.clock.Now()
+	effective, applied, next := applySchedule(featureflag, now)
+	rolloutPlan := advanceRolloutPlan(featureflag, now)
+	applyRolloutPlan(effective, rolloutPlan)
+	if rolloutPlan != nil && rolloutPlan.NextTransitionTime != nil && (next.IsZero() || rolloutPlan.NextTransitionTime.Time.Before(next)) {
+		next = rolloutPlan.NextTransitionTime.Time
+	}
+
+	// Compile the rules, which is only done again when the spec or a
+	// referenced segment changes. Invalid regexes, versions or missing
+	// segments are reported in the same way.
+	segments, err := c.getFeatureSegments(effective)
+	if err != nil {
+		return err
+	}
+	flag, err := c.compile(key, effective, segments)
+	if err != nil {
+		return c.rejectFeatureFlag(key, featureflag, ErrInvalidRules, fmt.Sprintf(MessageInvalidRules, err))
+	}
+
+	// Resolve the prerequisites to find the variation served to contexts
+	// matching no rule.
+	flags, err := c.compilePrerequisites(effective, flag)
+	if err != nil {
+		return err
+	}
+	served, _ := flags.Default(name)
+
+	// Add the finalizer before anything is published, so the payload is
+	// always removed when the FeatureFlag is deleted.
+	if !hasFinalizer(featureflag, FeatureFlagFinalizer) {
+		if featureflag, err = c.addFinalizer(featureflag); err != nil {
+			return err
+		}
 	}
 
+	// Publish the payload to the target of the FeatureFlag, a ConfigMap or
+	// a Secret, and with each of its publishers.
+	return c.publishFeatureFlag(key, &snapshot{
+		featureflag: featureflag,
+		effective:   effective,
+		variation:   served.Variation,
+		segments:    segments,
+	}, applied, rolloutPlan, now, next)
+}
+
+// publishConfigMap renders the snapshot into the ConfigMap of its
+// FeatureFlag, creating the ConfigMap if needed, and sharing or adopting it
+// when allowed.
+func (c *FeatureController) publishConfigMap(snapshot *snapshot) (*publication, error) {
+	featureflag, effective := snapshot.featureflag, snapshot.effective
+
 	// Get the ConfigMap with the name specified in FeatureFlag.spec
 	// NOTE: Looking at the listers doesnt hit the API
 	// where as configmap, err := c.configmapControl.GetConfigMap(featureflag.Namespace, configmapName)
 	// would therefore it is far more efficient to do this instead
-	configmap, err := c.configmapsLister.ConfigMaps(featureflag.Namespace).Get(configmapName)
-	// If the resource doesn't exist, we'll create it
+	configmap, err := c.configmapsLister.ConfigMaps(featureflag.Namespace).Get(featureflag.Spec.ConfigMapName)
+	// If the resource doesn't exist, we'll create it with the rendered flag.
+	// The informer only watches labelled ConfigMaps, so an unlabelled one
+	// of the same name is returned by createConfigMap instead.
 	if errors.IsNotFound(err) {
-		configmap, err = c.configmapControl.CreateConfigMap(featureflag.Namespace, newConfigMap(featureflag))
+		configmap = newConfigMap(featureflag)
+		if _, err = setPayload(configmap, effective, snapshot.variation, snapshot.segments); err != nil {
+			return nil, err
+		}
+		configmap, _, err = c.createConfigMap(featureflag.Namespace, configmap)
 	}
 
 	// If an error occurs during Get/Create, we'll requeue the item so we can
 	// attempt processing again later. This could have been caused by a
 	// temporary network failure, or any other transient reason.
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// A ConfigMap other FeatureFlags publish into is shared: the FeatureFlag
+	// joins them with a non-controller owner reference. A ConfigMap without a
+	// controller, e.g. created by hand or by Helm, is taken over when
+	// adoption was requested on it or on the FeatureFlag.
+	adopted, shared := false, false
+	switch {
+	case isOwnedBy(configmap, featureflag):
+	case canShare(configmap):
+		configmap = shareConfigMap(configmap, featureflag)
+		shared = true
+	case canAdopt(configmap, featureflag):
+		if configmap, err = adoptConfigMap(configmap, featureflag); err != nil {
+			return nil, err
+		}
+		adopted = true
 	}
 
-	// If the ConfigMap is not controlled by this FeatureFlag resource, we should log
-	// a warning to the event recorder and return error msg.
-	if !metav1.IsControlledBy(configmap, featureflag) {
+	// If the ConfigMap is not owned by this FeatureFlag resource, we should log
+	// a warning to the event recorder, report it in the status and return error msg.
+	if !isOwnedBy(configmap, featureflag) {
 		msg := fmt.Sprintf(MessageResourceExists, configmap.Name)
 		c.recorder.Event(featureflag, corev1.EventTypeWarning, ErrResourceExists, msg)
-		return fmt.Errorf(msg)
+		return &publication{reason: ErrResourceExists, message: msg}, fmt.Errorf(msg)
 	}
 
-	// // If this number of the replicas on the FeatureFlag resource is specified, and the
-	// // number does not equal the current desired replicas on the Deployment, we
-	// // should update the Deployment resource.
-	// if foo.Spec.Replicas != nil && *foo.Spec.Replicas != *deployment.Spec.Replicas {
-	// 	klog.V(4).Infof("Foo %s replicas: %d, deployment replicas: %d", name, *foo.Spec.Replicas, *deployment.Spec.Replicas)
-	// 	deployment, err = c.kubeclientset.AppsV1().Deployments(foo.Namespace).Update(context.TODO(), newDeployment(foo), metav1.UpdateOptions{})
-	// }
+	// If another FeatureFlag publishes the same key, neither overwrites the
+	// other: the key stays with the first and the conflict is reported.
+	if msg := keyConflict(configmap, featureflag); msg != "" {
+		c.recorder.Event(featureflag, corev1.EventTypeWarning, ErrKeyConflict, msg)
+		return &publication{reason: ErrKeyConflict, message: msg, conflict: true}, fmt.Errorf(msg)
+	}
+
+	// If the payload was edited in the ConfigMap since it was published, the
+	// drift policy of the FeatureFlag decides whether the edit is reverted,
+	// left in place or accepted as the new spec.
+	if keys := driftedKeys(configmap, featureflag); len(keys) > 0 {
+		revert, result, err := c.handleDrift(featureflag, configmap, snapshot.variation, keys)
+		if !revert {
+			return result, err
+		}
+	}
+
+	// If the rendered flag differs from what the ConfigMap holds, e.g. because
+	// the FeatureFlag spec changed, we should update the ConfigMap. Unchanged
+	// content is never written so applications watching it are not disturbed.
+	// NEVER modify objects from the store, so work on a copy.
+	configmapCopy := configmap.DeepCopy()
+	changed, err := setPayload(configmapCopy, effective, snapshot.variation, snapshot.segments)
+	if err != nil {
+		return nil, err
+	}
+	// ConfigMaps published by older versions of the operator are labelled so
+	// the informer watches them from now on.
+	labelled := setManaged(configmapCopy)
+	if changed || adopted || shared || labelled {
+		klog.V(4).Infof("FeatureFlag %s payload changed, updating configmap %s", featureflag.Name, configmap.Name)
+		configmap, err = c.configmapControl.UpdateConfigMap(featureflag.Namespace, configmapCopy)
+		if err == nil {
+			configmapUpdatedCount.WithLabelValues().Inc()
+		}
+	}
 
 	// If an error occurs during Update, we'll requeue the item so we can
 	// attempt processing again later. This could have been caused by a
 	// temporary network failure, or any other transient reason.
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if adopted {
+		c.recorder.Event(featureflag, corev1.EventTypeNormal, SuccessAdopted, fmt.Sprintf(MessageResourceAdopted, configmap.Name))
+	}
+	if shared {
+		c.recorder.Event(featureflag, corev1.EventTypeNormal, SuccessShared, fmt.Sprintf(MessageResourceShared, configmap.Name))
 	}
 
-	// Finally, we update the status block of the Foo resource to reflect the
-	// current state of the world
-	err = c.updateFeatureFlagStatus(featureflag, configmap)
+	// Once the payload is in the ConfigMap of the spec, remove it from the
+	// ConfigMaps it was published to before configmapName changed. The
+	// publication stands if that fails, and the FeatureFlag is retried.
+	err = c.unpublishStaleConfigMaps(featureflag)
+	return published(fmt.Sprintf(MessageConfigMapSynced, configmap.Name), configmapCopy.Data[payloadKey(effective)]), err
+}
+
+// getFeatureSegments returns the FeatureSegments referenced by the rules of
+// the FeatureFlag. Missing segments are left out for compile to report.
+func (c *FeatureController) getFeatureSegments(featureflag *samplev1alpha1.FeatureFlag) ([]*samplev1alpha1.FeatureSegment, error) {
+	var segments []*samplev1alpha1.FeatureSegment
+	for _, name := range evaluation.SegmentNames(featureflag) {
+		segment, err := c.featuresegmentsLister.FeatureSegments(featureflag.Namespace).Get(name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// compile returns the compiled rules of the FeatureFlag, compiling them only
+// if its spec or FeatureSegments changed since they were last compiled. The
+// spec is hashed rather than compared by generation as scheduled changes
+// alter it without a new generation.
+func (c *FeatureController) compile(key string, featureflag *samplev1alpha1.FeatureFlag, segments []*samplev1alpha1.FeatureSegment) (*evaluation.Flag, error) {
+	specHash, err := hashSpec(&featureflag.Spec)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	c.recorder.Event(featureflag, corev1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
-	return nil
+	c.compiledLock.Lock()
+	defer c.compiledLock.Unlock()
+
+	var versions []string
+	for _, segment := range segments {
+		versions = append(versions, segment.Name+"="+segment.ResourceVersion)
+	}
+	segmentVersions := strings.Join(versions, ",")
+
+	if cached, ok := c.compiled[key]; ok && cached.uid == featureflag.UID && cached.specHash == specHash && cached.segments == segmentVersions {
+		return cached.flag, cached.err
+	}
+
+	flag, err := evaluation.Compile(featureflag, segments...)
+	c.compiled[key] = compiledFlag{
+		uid:      featureflag.UID,
+		specHash: specHash,
+		segments: segmentVersions,
+		flag:     flag,
+		err:      err,
+	}
+	return flag, err
 }
 
-func (c *FeatureController) updateFeatureFlagStatus(featureflag *samplev1alpha1.FeatureFlag, configmap *corev1.ConfigMap) error {
+// hashSpec returns a hash of the FeatureFlag spec.
+func hashSpec(spec *samplev1alpha1.FeatureFlagSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New64a()
+	hash.Write(data)
+	return strconv.FormatUint(hash.Sum64(), 16), nil
+}
+
+// compilePrerequisites returns a Set holding the compiled FeatureFlag and
+// every flag it transitively depends on. Prerequisites that are missing or
+// invalid are left out so they are never met.
+func (c *FeatureController) compilePrerequisites(featureflag *samplev1alpha1.FeatureFlag, flag *evaluation.Flag) (evaluation.Set, error) {
+	flags := evaluation.NewSet(flag)
+	queue := evaluation.PrerequisiteNames(featureflag)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := flags[name]; ok {
+			continue
+		}
+
+		prerequisite, err := c.featureflagsLister.FeatureFlags(featureflag.Namespace).Get(name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if errs := validation.ValidateFeatureFlag(prerequisite); len(errs) > 0 {
+			continue
+		}
+		now := c.clock.Now()
+		rolloutPlan := advanceRolloutPlan(prerequisite, now)
+		prerequisite, _, _ = applySchedule(prerequisite, now)
+		applyRolloutPlan(prerequisite, rolloutPlan)
+		segments, err := c.getFeatureSegments(prerequisite)
+		if err != nil {
+			return nil, err
+		}
+		compiled, err := c.compile(prerequisite.Namespace+"/"+prerequisite.Name, prerequisite, segments)
+		if err != nil {
+			continue
+		}
+
+		flags[name] = compiled
+		queue = append(queue, evaluation.PrerequisiteNames(prerequisite)...)
+	}
+	return flags, nil
+}
+
+// prerequisitesOf returns a function listing the prerequisites of the
+// FeatureFlags of the namespace, as used by evaluation.FindCycle.
+func (c *FeatureController) prerequisitesOf(namespace string) func(name string) []string {
+	return func(name string) []string {
+		featureflag, err := c.featureflagsLister.FeatureFlags(namespace).Get(name)
+		if err != nil {
+			return nil
+		}
+		return evaluation.PrerequisiteNames(featureflag)
+	}
+}
+
+// forgetCompiled drops the compiled rules of a deleted FeatureFlag.
+func (c *FeatureController) forgetCompiled(key string) {
+	c.compiledLock.Lock()
+	defer c.compiledLock.Unlock()
+	delete(c.compiled, key)
+}
+
+// rejectFeatureFlag reports a FeatureFlag that cannot be published with a
+// Warning event and its Valid and Ready conditions. The error is absorbed as
+// retrying would not help; the ConfigMap keeps serving the last valid spec.
+func (c *FeatureController) rejectFeatureFlag(key string, featureflag *samplev1alpha1.FeatureFlag, reason, msg string) error {
+	c.recorder.Event(featureflag, corev1.EventTypeWarning, reason, msg)
+	utilruntime.HandleError(fmt.Errorf("%s: %s", key, msg))
+	return c.updateFeatureFlagStatus(featureflag, featureflag.Status.DeepCopy(),
+		newCondition(samplev1alpha1.FeatureFlagValid, corev1.ConditionFalse, reason, msg),
+		newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionFalse, reason, msg))
+}
+
+// updateFeatureFlagStatus sets the conditions and observedGeneration on the
+// status and writes it through the status subresource. Nothing is written
+// when the status is unchanged, so resyncs do not update every FeatureFlag.
+func (c *FeatureController) updateFeatureFlagStatus(featureflag *samplev1alpha1.FeatureFlag, status *samplev1alpha1.FeatureFlagStatus, conditions ...samplev1alpha1.FeatureFlagCondition) error {
+	now := metav1.NewTime(c.clock.Now())
+	status.ObservedGeneration = featureflag.Generation
+	for _, condition := range conditions {
+		setCondition(status, condition, now)
+	}
+	status.LastSyncTime = featureflag.Status.LastSyncTime
+	if equality.Semantic.DeepEqual(featureflag.Status, *status) {
+		return nil
+	}
+	status.LastSyncTime = &now
+
 	// NEVER modify objects from the store. It's a read-only, local cache.
 	// You can use DeepCopy() to make a deep copy of original object and modify this copy
 	// Or create a copy manually for better performance
 	featureflagCopy := featureflag.DeepCopy()
-	//featureflagCopy.Status.AvailableReplicas = deployment.Status.AvailableReplicas
-	// If the CustomResourceSubresources feature gate is not enabled,
-	// we must use Update instead of UpdateStatus to update the Status block of the Foo resource.
+	featureflagCopy.Status = *status
 	// UpdateStatus will not allow changes to the Spec of the resource,
 	// which is ideal for ensuring nothing other than resource status has been updated.
-	_, err := c.featureclientset.FeaturecontrollerV1alpha1().FeatureFlags(featureflag.Namespace).Update(context.TODO(), featureflagCopy, metav1.UpdateOptions{})
+	_, err := c.featureclientset.FeaturecontrollerV1alpha1().FeatureFlags(featureflag.Namespace).UpdateStatus(context.TODO(), featureflagCopy, metav1.UpdateOptions{})
 	return err
 }
 
-// enqueueFeatureFlag takes a FeatureFlag resource and converts it into a namespace/name
-// string which is then put onto the work queue. This method should *not* be
-// passed resources of any type other than FeatureFlag.
+// enqueueFeatureFlag takes a FeatureFlag resource, or its tombstone, and converts it
+// into a namespace/name string which is then put onto the work queue. This method
+// should *not* be passed resources of any type other than FeatureFlag.
 func (c *FeatureController) enqueueFeatureFlag(obj interface{}) {
 	var key string
 	var err error
-	if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+	if key, err = cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err != nil {
 		utilruntime.HandleError(err)
 		return
 	}
@@ -377,41 +863,161 @@ func (c *FeatureController) handleObject(obj interface{}) {
 		klog.V(4).Infof("Recovered deleted object '%s' from tombstone", object.GetName())
 	}
 	klog.V(4).Infof("Processing object: %s", object.GetName())
-	if ownerRef := metav1.GetControllerOf(object); ownerRef != nil {
-		// If this object is not owned by a FeatureFlag, we should not do anything more
-		// with it.
-		if ownerRef.Kind != "FeatureFlag" {
-			return
+	if ownerRef := metav1.GetControllerOf(object); ownerRef != nil && ownerRef.Kind == "ClusterFeatureFlag" {
+		c.handleClusterObject(object, ownerRef)
+		return
+	}
+
+	// A ConfigMap may be shared by several FeatureFlags, its controller and
+	// contributors, all of which are enqueued. If this object is not owned by
+	// a FeatureFlag, we should not do anything more with it.
+	for _, ownerRef := range object.GetOwnerReferences() {
+		if !isFeatureFlagRef(ownerRef) {
+			continue
 		}
 
 		foo, err := c.featureflagsLister.FeatureFlags(object.GetNamespace()).Get(ownerRef.Name)
 		if err != nil {
 			klog.V(4).Infof("ignoring orphaned object '%s' of featureflag '%s'", object.GetSelfLink(), ownerRef.Name)
-			return
+			continue
 		}
 
 		c.enqueueFeatureFlag(foo)
+	}
+}
+
+// enqueueDependents takes a FeatureFlag, or its tombstone, and enqueues every
+// FeatureFlag that transitively depends on it.
+func (c *FeatureController) enqueueDependents(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
 		return
 	}
+
+	visited := map[string]bool{key: true}
+	queue := []string{key}
+	for len(queue) > 0 {
+		dependents, err := c.featureflagsIndexer.ByIndex(prerequisiteIndex, queue[0])
+		queue = queue[1:]
+		if err != nil {
+			utilruntime.HandleError(err)
+			return
+		}
+		for _, dependent := range dependents {
+			dependentKey, err := cache.MetaNamespaceKeyFunc(dependent)
+			if err != nil || visited[dependentKey] {
+				continue
+			}
+			visited[dependentKey] = true
+			c.workqueue.Add(dependentKey)
+			queue = append(queue, dependentKey)
+		}
+	}
+}
+
+// handleSegment takes a FeatureSegment, or its tombstone, and enqueues every
+// FeatureFlag in its namespace that references it.
+func (c *FeatureController) handleSegment(obj interface{}) {
+	var object metav1.Object
+	var ok bool
+	if object, ok = obj.(metav1.Object); !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		object, ok = tombstone.Obj.(metav1.Object)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+		klog.V(4).Infof("Recovered deleted segment '%s' from tombstone", object.GetName())
+	}
+	klog.V(4).Infof("Processing segment: %s", object.GetName())
+
+	featureflags, err := c.featureflagsIndexer.ByIndex(segmentIndex, object.GetNamespace()+"/"+object.GetName())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, featureflag := range featureflags {
+		c.enqueueFeatureFlag(featureflag)
+	}
+}
+
+// indexBySegment is the cache.IndexFunc of segmentIndex.
+func indexBySegment(obj interface{}) ([]string, error) {
+	featureflag, ok := obj.(*samplev1alpha1.FeatureFlag)
+	if !ok {
+		return nil, nil
+	}
+	var keys []string
+	for _, name := range evaluation.SegmentNames(featureflag) {
+		keys = append(keys, featureflag.Namespace+"/"+name)
+	}
+	return keys, nil
+}
+
+// indexByPrerequisite is the cache.IndexFunc of prerequisiteIndex.
+func indexByPrerequisite(obj interface{}) ([]string, error) {
+	featureflag, ok := obj.(*samplev1alpha1.FeatureFlag)
+	if !ok {
+		return nil, nil
+	}
+	var keys []string
+	for _, name := range evaluation.PrerequisiteNames(featureflag) {
+		keys = append(keys, featureflag.Namespace+"/"+name)
+	}
+	return keys, nil
 }
 
-// DANVIR: This needs refactoring
-//
 // newConfigMap creates a new ConfigMap for a FeatureFlag resource. It also sets
 // the appropriate OwnerReferences on the resource so handleObject can discover
-// the FeatureFlag resource that 'owns' it.
+// the FeatureFlag resource that 'owns' it, and ManagedLabel so the informer
+// watches it. The payload is added by setPayload.
 func newConfigMap(featureflag *samplev1alpha1.FeatureFlag) *corev1.ConfigMap {
-
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      featureflag.Spec.ConfigMapName,
 			Namespace: featureflag.Namespace,
+			Labels:    map[string]string{ManagedLabel: "true"},
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(featureflag, samplev1alpha1.SchemeGroupVersion.WithKind("FeatureFlag")),
 			},
 		},
-		// Data: map[string]string{
-		// 	ConfigMapConfKeyName: defaultSentinelConfig(redis.Name, redis.Spec.Sentinels.Quorum),
-		// },
 	}
 }
+
+// setPayload renders the FeatureFlag, serving variation to contexts matching
+// no rule, into the Data of the ConfigMap, removing any key left behind by a
+// previous format of the same flag, and records the hash of the content in
+// ContentHashesAnnotation. It reports whether the ConfigMap was modified.
+func setPayload(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag, variation string, segments []*samplev1alpha1.FeatureSegment) (bool, error) {
+	dataKey, content, err := renderPayload(featureflag, variation, segments)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	for _, ext := range payloadExtensions {
+		staleKey := featureflag.Name + "." + ext
+		if _, ok := configmap.Data[staleKey]; ok && staleKey != dataKey {
+			delete(configmap.Data, staleKey)
+			changed = true
+		}
+	}
+
+	if current, ok := configmap.Data[dataKey]; !ok || current != content {
+		if configmap.Data == nil {
+			configmap.Data = map[string]string{}
+		}
+		configmap.Data[dataKey] = content
+		changed = true
+	}
+
+	if recordContentHash(configmap, featureflag, dataKey, content) {
+		changed = true
+	}
+	return changed, nil
+}
@@ -6,11 +6,14 @@ import (
 	"testing"
 	"time"
 
+	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
@@ -25,6 +28,8 @@ import (
 var (
 	alwaysReady        = func() bool { return true }
 	noResyncPeriodFunc = func() time.Duration { return 0 }
+	// testNow is the time returned by the controller clock in tests
+	testNow = time.Date(2020, time.April, 1, 12, 0, 0, 0, time.UTC)
 )
 
 type fixture struct {
@@ -32,15 +37,31 @@ type fixture struct {
 
 	client     *fake.Clientset
 	kubeclient *k8sfake.Clientset
+	recorder   *record.FakeRecorder
 	// Objects to put in the store.
-	featureflagLister []*featurecontroller.FeatureFlag
-	configmapLister   []*core.ConfigMap
+	featureflagLister        []*featurecontroller.FeatureFlag
+	featuresegmentLister     []*featurecontroller.FeatureSegment
+	clusterfeatureflagLister []*featurecontroller.ClusterFeatureFlag
+	configmapLister          []*core.ConfigMap
+	namespaceLister          []*core.Namespace
 	// Actions expected to happen on the client.
 	kubeactions []kubetesting.Action
 	actions     []kubetesting.Action
 	// Objects from here preloaded into NewSimpleFake.
 	kubeobjects []runtime.Object
 	objects     []runtime.Object
+	// secretsEnabled calls EnableSecrets on the controller, whose lister
+	// holds secretLister.
+	secretsEnabled bool
+	secretLister   []*core.Secret
+	// publishDir, when set, calls EnableFilePublishers on the controller.
+	publishDir string
+	// allowedURLs, when set, calls EnableHTTPPublishers on the controller.
+	allowedURLs []string
+	// injectionEnabled calls EnableInjection on the controller, whose
+	// listers hold the Deployments, StatefulSets and DaemonSets of appLister.
+	injectionEnabled bool
+	appLister        []runtime.Object
 }
 
 func newFixture(t *testing.T) *fixture {
@@ -51,16 +72,26 @@ func newFixture(t *testing.T) *fixture {
 	return f
 }
 
-func newFeatureFlag(name string, replicas *int32) *featurecontroller.FeatureFlag {
+func newFeatureFlag(name string) *featurecontroller.FeatureFlag {
 	return &featurecontroller.FeatureFlag{
 		TypeMeta: metav1.TypeMeta{APIVersion: featurecontroller.SchemeGroupVersion.String()},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: metav1.NamespaceDefault,
+			Name:       name,
+			Namespace:  metav1.NamespaceDefault,
+			Finalizers: []string{FeatureFlagFinalizer},
 		},
 		Spec: featurecontroller.FeatureFlagSpec{
 			ConfigMapName: fmt.Sprintf("%s-config", name),
-			Replicas:      replicas,
+			FlagSpec: featurecontroller.FlagSpec{
+				Type:    featurecontroller.FlagTypeBoolean,
+				Enabled: true,
+				Variations: []featurecontroller.Variation{
+					{Name: "on", Value: "true"},
+					{Name: "off", Value: "false"},
+				},
+				DefaultVariation: "on",
+				OffVariation:     "off",
+			},
 		},
 	}
 }
@@ -73,16 +104,63 @@ func (f *fixture) newFeatureController() (*FeatureController, informers.SharedIn
 	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
 
 	c := NewFeatureController(f.kubeclient, f.client,
-		k8sI.Core().V1().ConfigMaps(), i.Featurecontroller().V1alpha1().FeatureFlags())
+		k8sI.Core().V1().ConfigMaps(), k8sI.Core().V1().Namespaces(),
+		i.Featurecontroller().V1alpha1().FeatureFlags(), i.Featurecontroller().V1alpha1().FeatureSegments(),
+		i.Featurecontroller().V1alpha1().ClusterFeatureFlags())
 
 	c.featureflagsSynced = alwaysReady
+	c.featuresegmentsSynced = alwaysReady
+	c.clusterfeatureflagsSynced = alwaysReady
 	c.configmapsSynced = alwaysReady
-	c.recorder = &record.FakeRecorder{}
+	c.namespacesSynced = alwaysReady
+	f.recorder = record.NewFakeRecorder(100)
+	c.recorder = f.recorder
+	c.clock = clock.NewFakeClock(testNow)
+	if f.secretsEnabled {
+		c.EnableSecrets(k8sI.Core().V1().Secrets())
+	}
+	for _, s := range f.secretLister {
+		k8sI.Core().V1().Secrets().Informer().GetIndexer().Add(s)
+	}
+	if f.publishDir != "" {
+		c.EnableFilePublishers(f.publishDir)
+	}
+	if len(f.allowedURLs) > 0 {
+		if err := c.EnableHTTPPublishers(f.allowedURLs, time.Second); err != nil {
+			f.t.Fatalf("Unexpected error enabling HTTP publishers: %v", err)
+		}
+	}
+
+	if f.injectionEnabled {
+		c.EnableInjection(k8sI.Apps().V1().Deployments(), k8sI.Apps().V1().StatefulSets(), k8sI.Apps().V1().DaemonSets())
+	}
+	for _, obj := range f.appLister {
+		switch obj.(type) {
+		case *apps.Deployment:
+			k8sI.Apps().V1().Deployments().Informer().GetIndexer().Add(obj)
+		case *apps.StatefulSet:
+			k8sI.Apps().V1().StatefulSets().Informer().GetIndexer().Add(obj)
+		case *apps.DaemonSet:
+			k8sI.Apps().V1().DaemonSets().Informer().GetIndexer().Add(obj)
+		}
+	}
 
 	for _, f := range f.featureflagLister {
 		i.Featurecontroller().V1alpha1().FeatureFlags().Informer().GetIndexer().Add(f)
 	}
 
+	for _, s := range f.featuresegmentLister {
+		i.Featurecontroller().V1alpha1().FeatureSegments().Informer().GetIndexer().Add(s)
+	}
+
+	for _, cf := range f.clusterfeatureflagLister {
+		i.Featurecontroller().V1alpha1().ClusterFeatureFlags().Informer().GetIndexer().Add(cf)
+	}
+
+	for _, ns := range f.namespaceLister {
+		k8sI.Core().V1().Namespaces().Informer().GetIndexer().Add(ns)
+	}
+
 	for _, d := range f.configmapLister {
 		fmt.Println("---- Adding configmap to lister ----")
 		k8sI.Core().V1().ConfigMaps().Informer().GetIndexer().Add(d)
@@ -99,7 +177,7 @@ func (f *fixture) runExpectError(featureflagName string) {
 	f.runFeatureController(featureflagName, true, true)
 }
 
-func (f *fixture) runFeatureController(featureflagName string, startInformers bool, expectError bool) {
+func (f *fixture) runFeatureController(featureflagName string, startInformers bool, expectError bool) *FeatureController {
 	c, i, k8sI := f.newFeatureController()
 	if startInformers {
 		stopCh := make(chan struct{})
@@ -144,6 +222,8 @@ func (f *fixture) runFeatureController(featureflagName string, startInformers bo
 	if len(f.kubeactions) > len(k8sActions) {
 		f.t.Errorf("%d additional expected actions:%+v", len(f.kubeactions)-len(k8sActions), f.kubeactions[len(k8sActions):])
 	}
+
+	return c
 }
 
 // checkAction verifies that expected and actual actions are equal and both have
@@ -178,6 +258,18 @@ func checkAction(expected, actual kubetesting.Action, t *testing.T) {
 			t.Errorf("Action %s %s has wrong object\nDiff:\n %s",
 				a.GetVerb(), a.GetResource().Resource, diff.ObjectGoPrintSideBySide(expObject, object))
 		}
+	case kubetesting.GetActionImpl:
+		e, _ := expected.(kubetesting.GetActionImpl)
+		if e.GetNamespace() != a.GetNamespace() || e.GetName() != a.GetName() {
+			t.Errorf("Action %s %s has wrong object\nExpected: %s/%s\nGot: %s/%s",
+				a.GetVerb(), a.GetResource().Resource, e.GetNamespace(), e.GetName(), a.GetNamespace(), a.GetName())
+		}
+	case kubetesting.DeleteActionImpl:
+		e, _ := expected.(kubetesting.DeleteActionImpl)
+		if e.GetNamespace() != a.GetNamespace() || e.GetName() != a.GetName() {
+			t.Errorf("Action %s %s has wrong object\nExpected: %s/%s\nGot: %s/%s",
+				a.GetVerb(), a.GetResource().Resource, e.GetNamespace(), e.GetName(), a.GetNamespace(), a.GetName())
+		}
 	case kubetesting.PatchActionImpl:
 		e, _ := expected.(kubetesting.PatchActionImpl)
 		expPatch := e.GetPatch()
@@ -202,8 +294,22 @@ func filterInformerActions(actions []kubetesting.Action) []kubetesting.Action {
 		if len(action.GetNamespace()) == 0 &&
 			(action.Matches("list", "featureflags") ||
 				action.Matches("watch", "featureflags") ||
+				action.Matches("list", "featuresegments") ||
+				action.Matches("watch", "featuresegments") ||
+				action.Matches("list", "clusterfeatureflags") ||
+				action.Matches("watch", "clusterfeatureflags") ||
+				action.Matches("list", "namespaces") ||
+				action.Matches("watch", "namespaces") ||
 				action.Matches("list", "configmaps") ||
-				action.Matches("watch", "configmaps")) {
+				action.Matches("watch", "configmaps") ||
+				action.Matches("list", "secrets") ||
+				action.Matches("watch", "secrets") ||
+				action.Matches("list", "deployments") ||
+				action.Matches("watch", "deployments") ||
+				action.Matches("list", "statefulsets") ||
+				action.Matches("watch", "statefulsets") ||
+				action.Matches("list", "daemonsets") ||
+				action.Matches("watch", "daemonsets")) {
 			continue
 		}
 		ret = append(ret, action)
@@ -216,17 +322,102 @@ func (f *fixture) expectCreateConfigMapAction(d *core.ConfigMap) {
 	f.kubeactions = append(f.kubeactions, kubetesting.NewCreateAction(schema.GroupVersionResource{Resource: "configmaps", Version: "v1"}, d.Namespace, d))
 }
 
+func (f *fixture) expectGetConfigMapAction(d *core.ConfigMap) {
+	f.kubeactions = append(f.kubeactions, kubetesting.NewGetAction(schema.GroupVersionResource{Resource: "configmaps", Version: "v1"}, d.Namespace, d.Name))
+}
+
 func (f *fixture) expectUpdateConfigMapAction(d *core.ConfigMap) {
 	f.kubeactions = append(f.kubeactions, kubetesting.NewUpdateAction(schema.GroupVersionResource{Resource: "configmaps"}, d.Namespace, d))
 }
 
+func (f *fixture) expectDeleteConfigMapAction(d *core.ConfigMap) {
+	f.kubeactions = append(f.kubeactions, kubetesting.NewDeleteAction(schema.GroupVersionResource{Resource: "configmaps"}, d.Namespace, d.Name))
+}
+
+func (f *fixture) expectUpdateFeatureFlagAction(featureflag *featurecontroller.FeatureFlag) {
+	f.actions = append(f.actions, kubetesting.NewUpdateAction(schema.GroupVersionResource{Resource: "featureflags"}, featureflag.Namespace, featureflag))
+}
+
 func (f *fixture) expectUpdateFooStatusAction(featureflag *featurecontroller.FeatureFlag) {
 	action := kubetesting.NewUpdateAction(schema.GroupVersionResource{Resource: "featureflags"}, featureflag.Namespace, featureflag)
-	// TODO: Until #38113 is merged, we can't use Subresource
-	//action.Subresource = "status"
+	action.Subresource = "status"
 	f.actions = append(f.actions, action)
 }
 
+// withCondition returns a copy of the FeatureFlag with the condition set as
+// the controller would set it at testNow.
+func withCondition(featureflag *featurecontroller.FeatureFlag, conditionType featurecontroller.FeatureFlagConditionType, status core.ConditionStatus, reason, message string) *featurecontroller.FeatureFlag {
+	featureflag = featureflag.DeepCopy()
+	setCondition(&featureflag.Status, newCondition(conditionType, status, reason, message), metav1.NewTime(testNow))
+	return featureflag
+}
+
+// withSynced returns a copy of the FeatureFlag with the observedGeneration
+// and lastSyncTime written by the controller at testNow.
+func withSynced(featureflag *featurecontroller.FeatureFlag) *featurecontroller.FeatureFlag {
+	featureflag = featureflag.DeepCopy()
+	featureflag.Status.ObservedGeneration = featureflag.Generation
+	featureflag.Status.LastSyncTime = &metav1.Time{Time: testNow}
+	return featureflag
+}
+
+// withValid returns a copy of the FeatureFlag as updated by a successful sync
+// publishing the ConfigMap.
+func withValid(featureflag *featurecontroller.FeatureFlag, configmap *core.ConfigMap) *featurecontroller.FeatureFlag {
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageConfigMapSynced, configmap.Name))
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagReady, core.ConditionTrue, SuccessSynced, MessageResourceSynced)
+	featureflag.Status.ContentHash = contentHash(configmap.Data[payloadKey(featureflag)])
+	return withSynced(featureflag)
+}
+
+// withRejected returns a copy of the FeatureFlag as updated by a sync
+// rejecting it for the reason.
+func withRejected(featureflag *featurecontroller.FeatureFlag, reason, message string) *featurecontroller.FeatureFlag {
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionFalse, reason, message)
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagReady, core.ConditionFalse, reason, message)
+	return withSynced(featureflag)
+}
+
+func newFeatureSegment(name string, included ...string) *featurecontroller.FeatureSegment {
+	return &featurecontroller.FeatureSegment{
+		TypeMeta: metav1.TypeMeta{APIVersion: featurecontroller.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       metav1.NamespaceDefault,
+			ResourceVersion: "1",
+		},
+		Spec: featurecontroller.FeatureSegmentSpec{Included: included},
+	}
+}
+
+// withSegmentRule returns a copy of the FeatureFlag serving off to the segments.
+func withSegmentRule(featureflag *featurecontroller.FeatureFlag, segments ...string) *featurecontroller.FeatureFlag {
+	featureflag = featureflag.DeepCopy()
+	featureflag.Spec.Rules = append(featureflag.Spec.Rules, featurecontroller.Rule{
+		Clauses:   []featurecontroller.Clause{{Operator: featurecontroller.OperatorInSegment, Values: segments}},
+		Variation: "off",
+	})
+	return featureflag
+}
+
+// withPrerequisites returns a copy of the FeatureFlag requiring each flag to serve "on".
+func withPrerequisites(featureflag *featurecontroller.FeatureFlag, flags ...string) *featurecontroller.FeatureFlag {
+	featureflag = featureflag.DeepCopy()
+	for _, flag := range flags {
+		featureflag.Spec.Prerequisites = append(featureflag.Spec.Prerequisites, featurecontroller.Prerequisite{Flag: flag, Variation: "on"})
+	}
+	return featureflag
+}
+
+func newConfigMapWithPayload(featureflag *featurecontroller.FeatureFlag, t *testing.T, segments ...*featurecontroller.FeatureSegment) *core.ConfigMap {
+	configmap := newConfigMap(featureflag)
+	if _, err := setPayload(configmap, featureflag, defaultVariation(featureflag), segments); err != nil {
+		t.Fatalf("Unexpected error rendering featureflag %v: %v", featureflag.Name, err)
+	}
+	return configmap
+}
+
 func getKey(featureflag *featurecontroller.FeatureFlag, t *testing.T) string {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(featureflag)
 	if err != nil {
@@ -239,14 +430,14 @@ func getKey(featureflag *featurecontroller.FeatureFlag, t *testing.T) string {
 // TestCreateDeployment tests that a configmap is created automatically if a new CRD FeatureFlag is created
 func TestCreatesDeployment(t *testing.T) {
 	f := newFixture(t)
-	featureflag := newFeatureFlag("test", int32Ptr(1))
+	featureflag := newFeatureFlag("test")
 
 	f.featureflagLister = append(f.featureflagLister, featureflag)
 	f.objects = append(f.objects, featureflag)
 
-	expConfig := newConfigMap(featureflag)
+	expConfig := newConfigMapWithPayload(featureflag, t)
 	f.expectCreateConfigMapAction(expConfig)
-	f.expectUpdateFooStatusAction(featureflag)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
 
 	f.run(getKey(featureflag, t))
 }
@@ -254,42 +445,78 @@ func TestCreatesDeployment(t *testing.T) {
 // TestDoNothing tests that the controller takes no action if the configmap for the CRD FeatureFlag exists already
 func TestDoNothing(t *testing.T) {
 	f := newFixture(t)
-	featureflag := newFeatureFlag("test", int32Ptr(1))
-	d := newConfigMap(featureflag)
+	featureflag := newFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
 
 	f.featureflagLister = append(f.featureflagLister, featureflag)
 	f.objects = append(f.objects, featureflag)
 	f.configmapLister = append(f.configmapLister, d)
 	f.kubeobjects = append(f.kubeobjects, d)
 
-	f.expectUpdateFooStatusAction(featureflag)
+	f.expectUpdateFooStatusAction(withValid(featureflag, d))
 
 	f.run(getKey(featureflag, t))
 }
 
-// // TestUpdateConfig tests that a configmap can be updated
-// func TestUpdateConfig(t *testing.T) {
-// 	f := newFixture(t)
-// 	featureflag := newFeatureFlag("test", int32Ptr(1))
-// 	d := newConfigMap(featureflag)
+// TestStatusUnchanged tests that the status is not written when a resync changes nothing
+func TestStatusUnchanged(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	featureflag.Generation = 2
+	d := newConfigMapWithPayload(featureflag, t)
+	featureflag = withValid(featureflag, d)
+	featureflag.Status.LastSyncTime = &metav1.Time{Time: testNow.Add(-time.Hour)}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestUpdateConfig tests that a configmap is updated when the FeatureFlag spec changes
+func TestUpdateConfig(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
+
+	// Switch the flag off
+	featureflag.Spec.Enabled = false
+	expConfig := newConfigMapWithPayload(featureflag, t)
 
-// 	// Update replicas
-// 	featureflag.Spec.Replicas = int32Ptr(2)
-// 	expDeployment := newConfigMap(featureflag)
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+	f.run(getKey(featureflag, t))
+}
+
+// TestUpdateConfigFormat tests that changing the format replaces the previously published key
+func TestUpdateConfigFormat(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
 
-// 	f.featureflagLister = append(f.featureflagLister, featureflag)
-// 	f.objects = append(f.objects, featureflag)
-// 	f.configmapLister = append(f.configmapLister, d)
-// 	f.kubeobjects = append(f.kubeobjects, d)
+	featureflag.Spec.Format = featurecontroller.PayloadFormatDotenv
+	expConfig := newConfigMapWithPayload(featureflag, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
 
-// 	f.expectUpdateFooStatusAction(featureflag)
-// 	f.expectUpdateConfigMapAction(expDeployment)
-// 	f.run(getKey(featureflag, t))
-// }
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+	f.run(getKey(featureflag, t))
+}
 
 func TestNotControlledByUs(t *testing.T) {
 	f := newFixture(t)
-	featureflag := newFeatureFlag("test", int32Ptr(1))
+	featureflag := newFeatureFlag("test")
 	d := newConfigMap(featureflag)
 
 	d.ObjectMeta.OwnerReferences = []metav1.OwnerReference{}
@@ -299,7 +526,290 @@ func TestNotControlledByUs(t *testing.T) {
 	f.configmapLister = append(f.configmapLister, d)
 	f.kubeobjects = append(f.kubeobjects, d)
 
+	msg := fmt.Sprintf(MessageResourceExists, d.Name)
+	expFlag := withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	expFlag = withCondition(expFlag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionFalse, ErrResourceExists, msg)
+	expFlag = withCondition(expFlag, featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrResourceExists, msg)
+	f.expectUpdateFooStatusAction(withSynced(expFlag))
+
 	f.runExpectError(getKey(featureflag, t))
 }
 
-func int32Ptr(i int32) *int32 { return &i }
+// TestInvalidSpec tests that the controller takes no action if the variations do not match the flag type
+func TestInvalidSpec(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	featureflag.Spec.Variations[0].Value = "yes"
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+
+	f.expectUpdateFooStatusAction(withRejected(featureflag, ErrInvalidSpec,
+		`FeatureFlag spec is invalid: spec.variations[0].value: Invalid value: "yes": must be "true" or "false" for a boolean flag`))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestInvalidRules tests that invalid regexes and versions are reported as a condition and nothing is published
+func TestInvalidRules(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	featureflag.Spec.Rules = []featurecontroller.Rule{{
+		Clauses: []featurecontroller.Clause{
+			{Attribute: "email", Operator: featurecontroller.OperatorMatches, Values: []string{"(.*@example.com"}},
+			{Attribute: "appVersion", Operator: featurecontroller.OperatorSemVerLessThan, Values: []string{"two"}},
+		},
+		Variation: "off",
+	}}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+
+	f.expectUpdateFooStatusAction(withRejected(featureflag, ErrInvalidRules,
+		"FeatureFlag rules are invalid: [spec.rules[0].clauses[0].values[0]: Invalid value: \"(.*@example.com\": error parsing regexp: missing closing ): `(.*@example.com`, "+
+			"spec.rules[0].clauses[1].values[0]: Invalid value: \"two\": must be a semantic version]"))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestSegmentPublished tests that referenced segments are published with the flag
+func TestSegmentPublished(t *testing.T) {
+	f := newFixture(t)
+	segment := newFeatureSegment("beta-testers", "user-1")
+	featureflag := withSegmentRule(newFeatureFlag("test"), segment.Name)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.featuresegmentLister = append(f.featuresegmentLister, segment)
+	f.objects = append(f.objects, featureflag, segment)
+
+	expConfig := newConfigMapWithPayload(featureflag, t, segment)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestMissingSegment tests that a rule referencing a missing segment is reported as invalid
+func TestMissingSegment(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withSegmentRule(newFeatureFlag("test"), "beta-testers")
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+
+	f.expectUpdateFooStatusAction(withRejected(featureflag, ErrInvalidRules,
+		`FeatureFlag rules are invalid: spec.rules[0].clauses[0].values[0]: Not found: "beta-testers"`))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestSegmentChangeEnqueuesFlags tests that a segment change only enqueues the flags referencing it
+func TestSegmentChangeEnqueuesFlags(t *testing.T) {
+	f := newFixture(t)
+	segment := newFeatureSegment("beta-testers", "user-1")
+	referencing := withSegmentRule(newFeatureFlag("referencing"), segment.Name)
+	other := withSegmentRule(newFeatureFlag("other"), "internal")
+	unrelated := newFeatureFlag("unrelated")
+	f.featureflagLister = append(f.featureflagLister, referencing, other, unrelated)
+
+	c, _, _ := f.newFeatureController()
+
+	updated := segment.DeepCopy()
+	updated.ResourceVersion = "2"
+	c.handleSegment(updated)
+	c.handleSegment(cache.DeletedFinalStateUnknown{Key: getKey(referencing, t), Obj: updated})
+
+	if c.workqueue.Len() != 1 {
+		t.Fatalf("expected 1 featureflag queued, got %d", c.workqueue.Len())
+	}
+	key, _ := c.workqueue.Get()
+	if key != getKey(referencing, t) {
+		t.Errorf("expected %s to be queued, got %v", getKey(referencing, t), key)
+	}
+}
+
+// TestPrerequisiteNotMet tests that a flag whose prerequisite is off publishes its off variation
+func TestPrerequisiteNotMet(t *testing.T) {
+	f := newFixture(t)
+	api := newFeatureFlag("new-api")
+	api.Spec.Enabled = false
+	featureflag := withPrerequisites(newFeatureFlag("test"), api.Name)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag, api)
+	f.objects = append(f.objects, featureflag, api)
+
+	expConfig := newConfigMap(featureflag)
+	if _, err := setPayload(expConfig, featureflag, "off", nil); err != nil {
+		t.Fatal(err)
+	}
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestPrerequisiteCycle tests that a cycle is reported as a condition and a Warning event and nothing is published
+func TestPrerequisiteCycle(t *testing.T) {
+	f := newFixture(t)
+	a := withPrerequisites(newFeatureFlag("a"), "b")
+	b := withPrerequisites(newFeatureFlag("b"), "c")
+	c := withPrerequisites(newFeatureFlag("c"), "b")
+
+	f.featureflagLister = append(f.featureflagLister, a, b, c)
+	f.objects = append(f.objects, a, b, c)
+
+	msg := "FeatureFlag prerequisites form a cycle: b -> c -> b"
+	f.expectUpdateFooStatusAction(withRejected(a, ErrPrerequisiteCycle, msg))
+
+	f.run(getKey(a, t))
+
+	event := <-f.recorder.Events
+	if expected := core.EventTypeWarning + " " + ErrPrerequisiteCycle + " " + msg; event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestPrerequisiteChangeEnqueuesDependents tests that every flag transitively depending on a changed flag is enqueued
+func TestPrerequisiteChangeEnqueuesDependents(t *testing.T) {
+	f := newFixture(t)
+	api := newFeatureFlag("new-api")
+	backend := withPrerequisites(newFeatureFlag("new-backend"), api.Name)
+	checkout := withPrerequisites(newFeatureFlag("new-checkout"), backend.Name)
+	unrelated := newFeatureFlag("unrelated")
+	f.featureflagLister = append(f.featureflagLister, api, backend, checkout, unrelated)
+
+	c, _, _ := f.newFeatureController()
+	c.enqueueDependents(cache.DeletedFinalStateUnknown{Key: getKey(api, t), Obj: api})
+
+	queued := map[interface{}]bool{}
+	for c.workqueue.Len() > 0 {
+		key, _ := c.workqueue.Get()
+		queued[key] = true
+	}
+	expected := map[interface{}]bool{getKey(backend, t): true, getKey(checkout, t): true}
+	if !reflect.DeepEqual(expected, queued) {
+		t.Errorf("expected %v to be queued, got %v", expected, queued)
+	}
+}
+
+// TestScheduledChange tests that missed changes are applied, recorded in status and the flag is requeued for the next change
+func TestScheduledChange(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	featureflag.Spec.Enabled = false
+	featureflag.Spec.Schedule = []featurecontroller.ScheduledChange{
+		newScheduledChange("launch", testNow.Add(-time.Hour), enable(true)),
+		newScheduledChange("rollback", testNow.Add(100*time.Millisecond), enable(false)),
+	}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+
+	launched := featureflag.DeepCopy()
+	launched.Spec.Enabled = true
+	expConfig := newConfigMapWithPayload(launched, t)
+	f.expectCreateConfigMapAction(expConfig)
+
+	expFlag := withValid(featureflag, expConfig)
+	expFlag.Status.AppliedChanges = []featurecontroller.AppliedChange{
+		{Name: "launch", At: metav1.NewTime(testNow.Add(-time.Hour)), AppliedTime: metav1.NewTime(testNow)},
+	}
+	f.expectUpdateFooStatusAction(expFlag)
+
+	c := f.runFeatureController(getKey(featureflag, t), true, false)
+
+	if c.workqueue.Len() != 0 {
+		t.Fatalf("expected the featureflag to be requeued once the next change is due, got %d queued", c.workqueue.Len())
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return c.workqueue.Len() == 1, nil
+	}); err != nil {
+		t.Fatalf("featureflag was not requeued when the next change was due: %v", err)
+	}
+}
+
+// TestRolloutPlanAbortRevertsPayload tests that aborting a rollout plan immediately publishes the off variation
+func TestRolloutPlanAbortRevertsPayload(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withRolloutPlan(newFeatureFlag("test"))
+	featureflag.Status.RolloutPlan = advanceRolloutPlan(featureflag, testNow.Add(-45*time.Minute))
+	featureflag.Status.RolloutPlan = advanceRolloutPlan(featureflag, testNow.Add(-time.Minute))
+
+	progressing := featureflag.DeepCopy()
+	applyRolloutPlan(progressing, progressing.Status.RolloutPlan)
+	d := newConfigMapWithPayload(progressing, t)
+
+	featureflag.Annotations = map[string]string{featurecontroller.RolloutPlanAnnotation: featurecontroller.RolloutPlanAbort}
+	aborted := featureflag.DeepCopy()
+	aborted.Spec.Enabled = false
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newConfigMapWithPayload(aborted, t)
+	f.expectUpdateConfigMapAction(expConfig)
+	expFlag := withValid(featureflag, expConfig)
+	expFlag.Status.RolloutPlan = &featurecontroller.RolloutPlanStatus{
+		Phase:         featurecontroller.RolloutPlanAborted,
+		PlanHash:      rolloutPlanHash(featureflag.Spec.RolloutPlan),
+		CurrentStep:   1,
+		CurrentWeight: 5,
+		StepStartTime: metav1.NewTime(testNow.Add(-15 * time.Minute)),
+	}
+	f.expectUpdateFooStatusAction(expFlag)
+
+	c := f.runFeatureController(getKey(featureflag, t), true, false)
+	if c.workqueue.Len() != 0 {
+		t.Errorf("expected an aborted plan not to be requeued, got %d queued", c.workqueue.Len())
+	}
+}
+
+// TestCheckWorkers tests that only a worker processing the same item for longer than the timeout is reported
+func TestCheckWorkers(t *testing.T) {
+	f := newFixture(t)
+	c, _, _ := f.newFeatureController()
+	fakeClock := clock.NewFakeClock(testNow)
+	c.clock = fakeClock
+
+	if err := c.CheckWorkers(time.Minute); err != nil {
+		t.Errorf("expected idle workers to be healthy, got %v", err)
+	}
+
+	c.startWork("default/test")
+	fakeClock.Step(time.Minute)
+	if err := c.CheckWorkers(time.Minute); err != nil {
+		t.Errorf("expected a worker busy for the timeout to be healthy, got %v", err)
+	}
+
+	fakeClock.Step(time.Second)
+	if err := c.CheckWorkers(time.Minute); err == nil {
+		t.Error("expected a stalled worker to be reported")
+	}
+
+	c.finishWork("default/test")
+	if err := c.CheckWorkers(time.Minute); err != nil {
+		t.Errorf("expected workers to be healthy once the item is processed, got %v", err)
+	}
+}
+
+// TestRunWorkersDrainsWorkqueue tests that the workers process every queued item before shutting down
+func TestRunWorkersDrainsWorkqueue(t *testing.T) {
+	f := newFixture(t)
+	c, _, _ := f.newFeatureController()
+	for _, key := range []string{"default/a", "default/b", "default/c"} {
+		c.workqueue.Add(key)
+	}
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+	c.runWorkers(2, stopCh)
+
+	if !c.workqueue.ShuttingDown() {
+		t.Error("expected the workqueue to be shut down")
+	}
+	if n := c.workqueue.Len(); n != 0 {
+		t.Errorf("expected the workqueue to be drained, %d items left", n)
+	}
+}
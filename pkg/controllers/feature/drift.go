@@ -0,0 +1,328 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/validation"
+)
+
+// ContentHashesAnnotation records, as JSON, the SHA-256 of the content last
+// published under each key of the ConfigMap, so that edits made outside of
+// the operator are detected.
+const ContentHashesAnnotation = "featureflags.featured.io/content-hashes"
+
+const (
+	// ErrDrifted is used as part of the Event 'reason' when the payload of a
+	// FeatureFlag was modified in the ConfigMap outside of the operator
+	ErrDrifted = "ErrDrifted"
+
+	// MessageDriftReverted is the message used for Events when modified keys
+	// are published again
+	MessageDriftReverted = "ConfigMap %q was modified outside of the operator, reverting keys %s"
+	// MessageDriftReported is the message used for Events when modified keys
+	// are left as edited
+	MessageDriftReported = "ConfigMap %q was modified outside of the operator, keys %s are left as edited"
+	// MessageDriftAccepted is the message used for Events when modified keys
+	// are accepted as the new spec of the FeatureFlag
+	MessageDriftAccepted = "ConfigMap %q was modified outside of the operator, keys %s are accepted as the new spec"
+	// MessageDriftNotAccepted is the message used for Events when modified
+	// keys cannot be accepted as the new spec, and are left as edited
+	MessageDriftNotAccepted = "ConfigMap %q was modified outside of the operator, keys %s are left as edited as they cannot be accepted: %v"
+)
+
+// contentHashes returns the hashes recorded in ContentHashesAnnotation. An
+// invalid annotation is treated as if nothing was recorded.
+func contentHashes(configmap *corev1.ConfigMap) map[string]string {
+	hashes := map[string]string{}
+	if value, ok := configmap.Annotations[ContentHashesAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &hashes); err != nil {
+			return map[string]string{}
+		}
+	}
+	return hashes
+}
+
+// recordContentHash records the hash of content under dataKey, and forgets
+// the hashes of the other keys of the FeatureFlag. An empty dataKey forgets
+// every key of the FeatureFlag. It reports whether the annotation changed.
+func recordContentHash(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag, dataKey, content string) bool {
+	hashes := contentHashes(configmap)
+	for _, ext := range payloadExtensions {
+		delete(hashes, featureflag.Name+"."+ext)
+	}
+	if dataKey != "" {
+		hashes[dataKey] = contentHash(content)
+	}
+
+	var value string
+	if len(hashes) > 0 {
+		// encoding/json sorts map keys, so the annotation is stable.
+		b, _ := json.Marshal(hashes)
+		value = string(b)
+	}
+	current, ok := configmap.Annotations[ContentHashesAnnotation]
+	switch {
+	case value == "" && !ok:
+		return false
+	case value == "":
+		delete(configmap.Annotations, ContentHashesAnnotation)
+	case current == value:
+		return false
+	default:
+		if configmap.Annotations == nil {
+			configmap.Annotations = map[string]string{}
+		}
+		configmap.Annotations[ContentHashesAnnotation] = value
+	}
+	return true
+}
+
+// driftedKeys returns, sorted, the keys of the FeatureFlag whose content no
+// longer matches the hash recorded when it was published, including keys
+// that were removed. Keys without a recorded hash, e.g. published by an
+// older version of the operator, are never reported.
+func driftedKeys(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) []string {
+	hashes := contentHashes(configmap)
+	var keys []string
+	for _, ext := range payloadExtensions {
+		key := featureflag.Name + "." + ext
+		recorded, ok := hashes[key]
+		if !ok {
+			continue
+		}
+		if content, ok := configmap.Data[key]; !ok || contentHash(content) != recorded {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// driftPolicy returns the drift policy of the FeatureFlag, defaulting to
+// DriftPolicyRevert.
+func driftPolicy(featureflag *samplev1alpha1.FeatureFlag) string {
+	if policy, ok := featureflag.Annotations[samplev1alpha1.DriftPolicyAnnotation]; ok {
+		return policy
+	}
+	return samplev1alpha1.DriftPolicyRevert
+}
+
+// handleDrift applies the drift policy of the FeatureFlag to the keys of its
+// payload modified outside of the operator. It reports whether the sync
+// should go on to publish the payload, which reverts the edit, and otherwise
+// the publication of the ConfigMap.
+func (c *FeatureController) handleDrift(featureflag *samplev1alpha1.FeatureFlag, configmap *corev1.ConfigMap, served string, keys []string) (bool, *publication, error) {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = fmt.Sprintf("%q", key)
+	}
+	list := strings.Join(quoted, ", ")
+
+	policy := driftPolicy(featureflag)
+	if policy == samplev1alpha1.DriftPolicyRevert {
+		klog.V(4).Infof("FeatureFlag %s/%s payload drifted, reverting keys %s", featureflag.Namespace, featureflag.Name, list)
+		c.recorder.Event(featureflag, corev1.EventTypeWarning, ErrDrifted, fmt.Sprintf(MessageDriftReverted, configmap.Name, list))
+		configmapDriftCount.WithLabelValues(policy).Inc()
+		return true, nil, nil
+	}
+
+	msg := fmt.Sprintf(MessageDriftReported, configmap.Name, list)
+	if policy == samplev1alpha1.DriftPolicyAccept {
+		accepted, err := c.acceptDrift(featureflag, configmap, served)
+		if err == nil {
+			c.recorder.Event(featureflag, corev1.EventTypeWarning, ErrDrifted, fmt.Sprintf(MessageDriftAccepted, configmap.Name, list))
+			configmapDriftCount.WithLabelValues(policy).Inc()
+			return false, nil, nil
+		}
+		if accepted {
+			// The spec was updated, but recording the accepted content
+			// failed, so retry.
+			return false, nil, err
+		}
+		msg = fmt.Sprintf(MessageDriftNotAccepted, configmap.Name, list, err)
+	}
+
+	// The edit is left in place and reported until it is reverted by hand
+	// or the policy changes. It is only counted once.
+	if condition := getCondition(&featureflag.Status, samplev1alpha1.FeatureFlagConfigMapSynced); condition == nil || condition.Message != msg {
+		c.recorder.Event(featureflag, corev1.EventTypeWarning, ErrDrifted, msg)
+		configmapDriftCount.WithLabelValues(policy).Inc()
+	}
+	return false, &publication{reason: ErrDrifted, message: msg}, nil
+}
+
+// acceptDrift updates the stored spec of the FeatureFlag from the payload
+// edited in the ConfigMap, then records the edited content as published so
+// it is not detected again. The spec update triggers a sync publishing the payload in
+// its canonical form. It reports whether the spec was accepted.
+func (c *FeatureController) acceptDrift(featureflag *samplev1alpha1.FeatureFlag, configmap *corev1.ConfigMap, served string) (bool, error) {
+	key := payloadKey(featureflag)
+	content, ok := configmap.Data[key]
+	if !ok {
+		return false, fmt.Errorf("key %q was removed", key)
+	}
+	spec, err := specFromPayload(featureflag, served, content)
+	if err != nil {
+		return false, err
+	}
+
+	featureflagCopy := featureflag.DeepCopy()
+	featureflagCopy.Spec = *spec
+	if errs := validation.ValidateFeatureFlag(featureflagCopy); len(errs) > 0 {
+		return false, errs.ToAggregate()
+	}
+	if !equality.Semantic.DeepEqual(featureflag.Spec, *spec) {
+		klog.V(4).Infof("FeatureFlag %s/%s payload drifted, accepting key %s as the new spec", featureflag.Namespace, featureflag.Name, key)
+		if _, err := c.featureclientset.FeaturecontrollerV1alpha1().FeatureFlags(featureflag.Namespace).Update(context.TODO(), featureflagCopy, metav1.UpdateOptions{}); err != nil {
+			return false, err
+		}
+	}
+
+	// NEVER modify objects from the store, so work on a copy.
+	configmapCopy := configmap.DeepCopy()
+	recordContentHash(configmapCopy, featureflag, key, content)
+	if _, err := c.configmapControl.UpdateConfigMap(configmap.Namespace, configmapCopy); err != nil {
+		return true, err
+	}
+	configmapUpdatedCount.WithLabelValues().Inc()
+	return true, nil
+}
+
+// specFromPayload returns the stored spec of the FeatureFlag with the served
+// variation and its value taken from an edited JSON or YAML payload. An
+// edited value replaces the value of the served variation, and a different
+// served variation becomes the default variation, or the off variation of a
+// disabled flag. The payload is rendered from the effective spec, so the rest
+// of it is ignored rather than writing scheduled changes or a rollout plan
+// into the spec. Other formats only hold the served value, so they cannot be
+// accepted.
+func specFromPayload(featureflag *samplev1alpha1.FeatureFlag, served, content string) (*samplev1alpha1.FeatureFlagSpec, error) {
+	var payload FlagPayload
+	switch format := payloadFormat(featureflag); format {
+	case samplev1alpha1.PayloadFormatJSON:
+		if err := json.Unmarshal([]byte(content), &payload); err != nil {
+			return nil, err
+		}
+	case samplev1alpha1.PayloadFormatYAML:
+		if err := yaml.Unmarshal([]byte(content), &payload); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("the %s format cannot be accepted as a spec", format)
+	}
+	if payload.Type != featureflag.Spec.Type {
+		return nil, fmt.Errorf("the type cannot be changed from %s to %s", featureflag.Spec.Type, payload.Type)
+	}
+	if payload.Variation == "" {
+		return nil, fmt.Errorf("no variation is served")
+	}
+
+	spec := featureflag.Spec.DeepCopy()
+	raw := payload.Value
+	if raw == nil {
+		raw = payload.Variations[payload.Variation]
+	}
+	found := false
+	for i, variation := range spec.Variations {
+		if variation.Name != payload.Variation {
+			continue
+		}
+		found = true
+		if raw == nil {
+			break
+		}
+		if current, err := encodeValue(spec.Type, variation.Value); err != nil || !bytes.Equal(current, compactJSON(raw)) {
+			value, err := decodeValue(spec.Type, raw)
+			if err != nil {
+				return nil, err
+			}
+			spec.Variations = append([]samplev1alpha1.Variation(nil), spec.Variations...)
+			spec.Variations[i].Value = value
+		}
+	}
+	if !found {
+		if raw == nil {
+			return nil, fmt.Errorf("variation %q has no value", payload.Variation)
+		}
+		value, err := decodeValue(spec.Type, raw)
+		if err != nil {
+			return nil, err
+		}
+		spec.Variations = append(spec.Variations, samplev1alpha1.Variation{Name: payload.Variation, Value: value})
+	}
+
+	if payload.Variation != served {
+		variation := servedBySpec(spec)
+		if variation == nil || *variation != served {
+			return nil, fmt.Errorf("variation %q is served by a scheduled change or rollout, not by the spec", served)
+		}
+		*variation = payload.Variation
+	}
+	return spec, nil
+}
+
+// servedBySpec returns the field of the spec holding the variation served
+// to contexts matching no rule, or nil when a rollout picks it.
+func servedBySpec(spec *samplev1alpha1.FeatureFlagSpec) *string {
+	switch {
+	case !spec.Enabled:
+		return &spec.OffVariation
+	case spec.DefaultRollout != nil || spec.RolloutPlan != nil:
+		return nil
+	default:
+		return &spec.DefaultVariation
+	}
+}
+
+// decodeValue converts the JSON value of a variation into its string
+// encoding in the spec, the inverse of encodeValue.
+func decodeValue(t samplev1alpha1.FlagType, raw json.RawMessage) (string, error) {
+	if t == samplev1alpha1.FlagTypeString {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+	value := compactJSON(raw)
+	if _, err := encodeValue(t, string(value)); err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// compactJSON returns the JSON without insignificant whitespace, or as is if
+// it is invalid.
+func compactJSON(raw json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,360 @@
+package feature
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// editedPayload is the payload of a FeatureFlag from newFeatureFlag edited
+// by hand to serve the off variation.
+const editedPayload = `{"schemaVersion": "featured.io/v1", "flag": "test", "type": "boolean", "enabled": true, "variation": "off", "value": false, "variations": {"off": false, "on": true}}`
+
+// newDriftedConfigMap returns the ConfigMap of the FeatureFlag with its
+// payload replaced without the operator recording it.
+func newDriftedConfigMap(featureflag *featurecontroller.FeatureFlag, content string, t *testing.T) *core.ConfigMap {
+	configmap := newConfigMapWithPayload(featureflag, t)
+	configmap.Data[payloadKey(featureflag)] = content
+	return configmap
+}
+
+// withDriftPolicy returns a copy of the FeatureFlag with the drift policy.
+func withDriftPolicy(featureflag *featurecontroller.FeatureFlag, policy string) *featurecontroller.FeatureFlag {
+	featureflag = featureflag.DeepCopy()
+	featureflag.Annotations = map[string]string{featurecontroller.DriftPolicyAnnotation: policy}
+	return featureflag
+}
+
+// TestRevertDrift tests that a payload edited in the ConfigMap is published again by default
+func TestRevertDrift(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	d := newDriftedConfigMap(featureflag, editedPayload, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrDrifted, fmt.Sprintf(MessageDriftReverted, d.Name, `"test.json"`)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestRevertRemovedKey tests that a payload removed from the ConfigMap is published again
+func TestRevertRemovedKey(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
+	delete(d.Data, payloadKey(featureflag))
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrDrifted, fmt.Sprintf(MessageDriftReverted, d.Name, `"test.json"`)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestReportDrift tests that a payload edited in the ConfigMap is left in place and reported with the report policy
+func TestReportDrift(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withDriftPolicy(newFeatureFlag("test"), featurecontroller.DriftPolicyReport)
+	d := newDriftedConfigMap(featureflag, editedPayload, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	msg := fmt.Sprintf(MessageDriftReported, d.Name, `"test.json"`)
+	expFeatureFlag := withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	expFeatureFlag = withCondition(expFeatureFlag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionFalse, ErrDrifted, msg)
+	expFeatureFlag = withCondition(expFeatureFlag, featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrDrifted, msg)
+	f.expectUpdateFooStatusAction(withSynced(expFeatureFlag))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrDrifted, msg); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestReportDriftOnce tests that drift already reported in the status is not reported again
+func TestReportDriftOnce(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withDriftPolicy(newFeatureFlag("test"), featurecontroller.DriftPolicyReport)
+	d := newDriftedConfigMap(featureflag, editedPayload, t)
+	msg := fmt.Sprintf(MessageDriftReported, d.Name, `"test.json"`)
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionFalse, ErrDrifted, msg)
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrDrifted, msg)
+	featureflag = withSynced(featureflag)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.run(getKey(featureflag, t))
+
+	select {
+	case event := <-f.recorder.Events:
+		t.Errorf("unexpected event %q", event)
+	default:
+	}
+}
+
+// TestAcceptDrift tests that a payload edited in the ConfigMap becomes the new spec with the accept policy
+func TestAcceptDrift(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withDriftPolicy(newFeatureFlag("test"), featurecontroller.DriftPolicyAccept)
+	d := newDriftedConfigMap(featureflag, editedPayload, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expFeatureFlag := featureflag.DeepCopy()
+	expFeatureFlag.Spec.DefaultVariation = "off"
+	expConfig := d.DeepCopy()
+	recordContentHash(expConfig, featureflag, payloadKey(featureflag), editedPayload)
+	f.expectUpdateFeatureFlagAction(expFeatureFlag)
+	f.expectUpdateConfigMapAction(expConfig)
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrDrifted, fmt.Sprintf(MessageDriftAccepted, d.Name, `"test.json"`)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// newColourFeatureFlag returns a string FeatureFlag serving "blue" when on and "grey" when off.
+func newColourFeatureFlag(name string) *featurecontroller.FeatureFlag {
+	featureflag := newFeatureFlag(name)
+	featureflag.Spec.Type = featurecontroller.FlagTypeString
+	featureflag.Spec.Variations = []featurecontroller.Variation{{Name: "on", Value: "blue"}, {Name: "off", Value: "grey"}}
+	return featureflag
+}
+
+// TestAcceptDriftWithSchedule tests that an edit is accepted into the stored spec, not the spec with the scheduled changes applied
+func TestAcceptDriftWithSchedule(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withDriftPolicy(newColourFeatureFlag("test"), featurecontroller.DriftPolicyAccept)
+	featureflag.Spec.Schedule = []featurecontroller.ScheduledChange{
+		newScheduledChange("pause", testNow.Add(-time.Hour), enable(false)),
+	}
+	featureflag.Status.AppliedChanges = []featurecontroller.AppliedChange{
+		{Name: "pause", At: metav1.NewTime(testNow.Add(-time.Hour)), AppliedTime: metav1.NewTime(testNow.Add(-time.Hour))},
+	}
+	// The payload of the paused flag, edited to serve "silver" for "off".
+	content := `{"type": "string", "enabled": false, "variation": "off", "value": "silver", "variations": {"on": "blue", "off": "grey"}}`
+	d := newDriftedConfigMap(featureflag, content, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expFeatureFlag := featureflag.DeepCopy()
+	expFeatureFlag.Spec.Variations[1].Value = "silver"
+	expConfig := d.DeepCopy()
+	recordContentHash(expConfig, featureflag, payloadKey(featureflag), content)
+	f.expectUpdateFeatureFlagAction(expFeatureFlag)
+	f.expectUpdateConfigMapAction(expConfig)
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrDrifted, fmt.Sprintf(MessageDriftAccepted, d.Name, `"test.json"`)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestAcceptDriftWithRolloutPlan tests that an edit is accepted without writing the rollout of the plan into the spec
+func TestAcceptDriftWithRolloutPlan(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withDriftPolicy(withRolloutPlan(newColourFeatureFlag("test")), featurecontroller.DriftPolicyAccept)
+	advance(featureflag, testNow)
+	// The payload of the flag at the first step of the plan, edited to
+	// serve "navy" for "on".
+	content := `{"type": "string", "enabled": true, "variation": "on", "value": "navy", "variations": {"on": "blue", "off": "grey"}, "defaultRollout": {"variations": [{"variation": "on", "weight": 1}, {"variation": "off", "weight": 99}]}}`
+	d := newDriftedConfigMap(featureflag, content, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expFeatureFlag := featureflag.DeepCopy()
+	expFeatureFlag.Spec.Variations[0].Value = "navy"
+	expConfig := d.DeepCopy()
+	recordContentHash(expConfig, featureflag, payloadKey(featureflag), content)
+	f.expectUpdateFeatureFlagAction(expFeatureFlag)
+	f.expectUpdateConfigMapAction(expConfig)
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrDrifted, fmt.Sprintf(MessageDriftAccepted, d.Name, `"test.json"`)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestAcceptDriftUnsupportedFormat tests that an edited payload that cannot be parsed back into a spec is reported instead
+func TestAcceptDriftUnsupportedFormat(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withDriftPolicy(newFeatureFlag("test"), featurecontroller.DriftPolicyAccept)
+	featureflag.Spec.Format = featurecontroller.PayloadFormatDotenv
+	d := newDriftedConfigMap(featureflag, "TEST_ENABLED=false\n", t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	msg := fmt.Sprintf(MessageDriftNotAccepted, d.Name, `"test.env"`, "the dotenv format cannot be accepted as a spec")
+	expFeatureFlag := withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	expFeatureFlag = withCondition(expFeatureFlag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionFalse, ErrDrifted, msg)
+	expFeatureFlag = withCondition(expFeatureFlag, featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrDrifted, msg)
+	f.expectUpdateFooStatusAction(withSynced(expFeatureFlag))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrDrifted, msg); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestSpecFromPayload tests that an edited payload is turned back into the spec it was rendered from
+func TestSpecFromPayload(t *testing.T) {
+	featureflag := newColourFeatureFlag("test")
+	rollout := newColourFeatureFlag("test")
+	rollout.Spec.DefaultRollout = &featurecontroller.Rollout{Variations: []featurecontroller.WeightedVariation{
+		{Variation: "on", Weight: 50},
+		{Variation: "off", Weight: 50},
+	}}
+
+	tests := []struct {
+		name        string
+		featureflag *featurecontroller.FeatureFlag
+		content     string
+		expect      func(spec *featurecontroller.FeatureFlagSpec)
+		err         string
+	}{
+		{
+			name:    "edited value",
+			content: `{"type": "string", "enabled": true, "variation": "on", "value": "green", "variations": {"on": "blue", "off": "grey"}}`,
+			expect:  func(spec *featurecontroller.FeatureFlagSpec) { spec.Variations[0].Value = "green" },
+		},
+		{
+			name:    "changed variation",
+			content: `{"type": "string", "enabled": true, "variation": "off", "value": "grey", "variations": {"on": "blue", "off": "grey"}}`,
+			expect:  func(spec *featurecontroller.FeatureFlagSpec) { spec.DefaultVariation = "off" },
+		},
+		{
+			name:    "ignored fields",
+			content: `{"type": "string", "enabled": false, "variation": "on", "value": "blue", "variations": {"on": "blue", "off": "black"}, "rules": [{"variation": "off"}]}`,
+			expect:  func(spec *featurecontroller.FeatureFlagSpec) {},
+		},
+		{
+			name:        "variation served by a rollout",
+			featureflag: rollout,
+			content:     `{"type": "string", "enabled": true, "variation": "off", "value": "grey", "variations": {"on": "blue", "off": "grey"}}`,
+			err:         `variation "on" is served by a scheduled change or rollout, not by the spec`,
+		},
+		{
+			name:    "added variation",
+			content: `{"type": "string", "enabled": true, "variation": "red", "value": "red", "variations": {"on": "blue", "off": "grey"}}`,
+			expect: func(spec *featurecontroller.FeatureFlagSpec) {
+				spec.Variations = append(spec.Variations, featurecontroller.Variation{Name: "red", Value: "red"})
+				spec.DefaultVariation = "red"
+			},
+		},
+		{
+			name:    "changed type",
+			content: `{"type": "boolean", "enabled": true, "variation": "on", "value": true}`,
+			err:     "the type cannot be changed from string to boolean",
+		},
+		{
+			name:    "invalid JSON",
+			content: `{"type": `,
+			err:     "unexpected end of JSON input",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			featureflag := featureflag
+			if test.featureflag != nil {
+				featureflag = test.featureflag
+			}
+			spec, err := specFromPayload(featureflag, "on", test.content)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := featureflag.Spec.DeepCopy()
+			expected.Variations = append([]featurecontroller.Variation(nil), expected.Variations...)
+			test.expect(expected)
+			if fmt.Sprintf("%+v", *spec) != fmt.Sprintf("%+v", *expected) {
+				t.Errorf("expected spec %+v, got %+v", *expected, *spec)
+			}
+		})
+	}
+}
+
+// TestRecordLegacyContentHash tests that a payload published without a content hash is not reported as drift, and its hash is recorded
+func TestRecordLegacyContentHash(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
+	delete(d.Annotations, ContentHashesAnnotation)
+	d.Data[payloadKey(featureflag)] = editedPayload
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeNormal, SuccessSynced, MessageResourceSynced); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// MessageFilePublished is the message used for the status of a file
+// publisher whose file holds the current payload
+const MessageFilePublished = "File %q holds the current payload"
+
+// EnableFilePublishers lets FeatureFlags publish their payload to files
+// under dir, typically a volume shared with consumers outside of
+// Kubernetes. Each namespace publishes into a directory of its own name.
+// Without it, file publishers report ErrPublisherDisabled.
+func (c *FeatureController) EnableFilePublishers(dir string) {
+	c.publishers[PublisherFile] = &filePublisher{dir: dir}
+}
+
+// filePublisher writes the payload of FeatureFlags to files under dir.
+type filePublisher struct {
+	dir string
+}
+
+// path returns the path of the file of the publisher, and its path relative
+// to the directory of the namespace. The path is checked to stay within that
+// directory even though validation rejects paths leaving it.
+func (p *filePublisher) path(featureflag *samplev1alpha1.FeatureFlag, sink *samplev1alpha1.Publisher) (string, string, error) {
+	name := sink.File.Path
+	if name == "" {
+		name = payloadKey(featureflag)
+	}
+	namespaceDir := filepath.Join(p.dir, featureflag.Namespace)
+	path := filepath.Join(namespaceDir, filepath.FromSlash(name))
+	if !strings.HasPrefix(path, namespaceDir+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("path %q is outside of the directory of the namespace", name)
+	}
+	return path, name, nil
+}
+
+// Publish writes the payload to the file unless it already holds it. The
+// file is replaced atomically, so readers never see a partial payload. When
+// the path defaults to the key of the payload, files left behind by a
+// previous format of the flag are removed.
+func (p *filePublisher) Publish(snapshot *snapshot, sink *samplev1alpha1.Publisher) (*publication, error) {
+	_, content, err := snapshot.render()
+	if err != nil {
+		return nil, err
+	}
+	path, name, err := p.path(snapshot.effective, sink)
+	if err != nil {
+		return &publication{reason: ErrPublishFailed, message: err.Error()}, nil
+	}
+	message := fmt.Sprintf(MessageFilePublished, name)
+
+	if sink.File.Path == "" {
+		for _, ext := range payloadExtensions {
+			stale := filepath.Join(filepath.Dir(path), snapshot.effective.Name+"."+ext)
+			if stale == path {
+				continue
+			}
+			if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+
+	current, err := ioutil.ReadFile(path)
+	if err == nil && string(current) == content {
+		return published(message, content), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	klog.V(4).Infof("FeatureFlag %s/%s payload changed, writing file %s", snapshot.featureflag.Namespace, snapshot.featureflag.Name, path)
+	if err := writeFileAtomically(path, content); err != nil {
+		return nil, err
+	}
+	publisherPublishedCount.WithLabelValues(PublisherFile).Inc()
+	return published(message, content), nil
+}
+
+// Unpublish removes the file of a FeatureFlag being deleted.
+func (p *filePublisher) Unpublish(key string, featureflag *samplev1alpha1.FeatureFlag, sink *samplev1alpha1.Publisher) error {
+	path, _, err := p.path(featureflag, sink)
+	if err != nil {
+		return nil
+	}
+	klog.V(4).Infof("FeatureFlag %s is being deleted, removing file %s", key, path)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomically writes content to a temporary file next to path, then
+// renames it over path.
+func writeFileAtomically(path, content string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
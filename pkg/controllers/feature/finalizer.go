@@ -0,0 +1,242 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// FeatureFlagFinalizer is added to every published FeatureFlag so that its
+// payload is removed from the ConfigMap before the FeatureFlag is deleted,
+// including from ConfigMaps the garbage collector would not delete.
+const FeatureFlagFinalizer = "featureflags.featured.io/finalizer"
+
+const (
+	// SuccessDeleted is used as part of the Event 'reason' when the payload of
+	// a deleted FeatureFlag is removed
+	SuccessDeleted = "Deleted"
+	// MessageResourceDeleted is the message used for an Event fired when the
+	// payload of a deleted FeatureFlag is removed
+	MessageResourceDeleted = "FeatureFlag payload removed from ConfigMap %q"
+)
+
+// finalizeFeatureFlag removes the payload of a FeatureFlag being deleted and
+// then its finalizer, which lets the API server delete it. A Secret the
+// FeatureFlag is published to is handled by finalizeSecret, and its
+// additional publishers by unpublishSinks, and the apps it was injected into
+// by uninjectApps. A ConfigMap controlled by the FeatureFlag is deleted, or
+// released when the OnDeleteAnnotation asks for it; the keys of the flag are
+// removed from other ConfigMaps it owns. A ConfigMap shared with other
+// FeatureFlags is kept for them, with the keys and owner reference of the
+// flag removed. ConfigMaps it does not own are left alone.
+func (c *FeatureController) finalizeFeatureFlag(key string, featureflag *samplev1alpha1.FeatureFlag) error {
+	if !hasFinalizer(featureflag, FeatureFlagFinalizer) {
+		return nil
+	}
+
+	msg := fmt.Sprintf(MessageResourceDeleted, featureflag.Spec.ConfigMapName)
+	if publishTarget(featureflag) == samplev1alpha1.PublishTargetSecret {
+		msg = fmt.Sprintf(MessageSecretDeleted, featureflag.Spec.SecretName)
+	}
+	// The garbage collector still deletes the Secret of a FeatureFlag if
+	// the operator cannot access Secrets.
+	if publisher, ok := c.publishers[string(publishTarget(featureflag))]; ok {
+		if err := publisher.Unpublish(key, featureflag, nil); err != nil {
+			return err
+		}
+	}
+	if err := c.unpublishSinks(key, featureflag); err != nil {
+		return err
+	}
+	if err := c.uninjectApps(featureflag); err != nil {
+		return err
+	}
+
+	// The payload is gone, so record it before the finalizer is removed: the
+	// API server may delete the FeatureFlag as soon as the update lands.
+	c.recorder.Event(featureflag, corev1.EventTypeNormal, SuccessDeleted, msg)
+	featureflagDeletedCount.WithLabelValues().Inc()
+
+	featureflagCopy := featureflag.DeepCopy()
+	featureflagCopy.Finalizers = removeFinalizer(featureflagCopy.Finalizers, FeatureFlagFinalizer)
+	_, err := c.featureclientset.FeaturecontrollerV1alpha1().FeatureFlags(featureflag.Namespace).Update(context.TODO(), featureflagCopy, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	c.forgetCompiled(key)
+	return nil
+}
+
+// finalizeConfigMap removes the payload of a FeatureFlag being deleted from
+// the ConfigMap it is published to, and from the ConfigMaps it was published
+// to before its configmapName changed.
+func (c *FeatureController) finalizeConfigMap(key string, featureflag *samplev1alpha1.FeatureFlag) error {
+	configmap, err := c.getConfigMap(featureflag.Namespace, featureflag.Spec.ConfigMapName)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	default:
+		klog.V(4).Infof("FeatureFlag %s is being deleted, removing it from configmap %s", key, configmap.Name)
+		if err := c.unpublishConfigMap(featureflag, configmap); err != nil {
+			return err
+		}
+	}
+	return c.unpublishStaleConfigMaps(featureflag)
+}
+
+// unpublishStaleConfigMaps removes the payload of a FeatureFlag from the
+// ConfigMaps it owns other than the one of its spec, i.e. those it was
+// published to before its configmapName changed. They are found by their
+// owner reference, as the FeatureFlag keeps none of their names.
+func (c *FeatureController) unpublishStaleConfigMaps(featureflag *samplev1alpha1.FeatureFlag) error {
+	configmaps, err := c.configmapsLister.ConfigMaps(featureflag.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, configmap := range configmaps {
+		if configmap.Name == featureflag.Spec.ConfigMapName || !isOwnedBy(configmap, featureflag) {
+			continue
+		}
+		klog.V(4).Infof("FeatureFlag %s/%s no longer publishes to configmap %s, removing it", featureflag.Namespace, featureflag.Name, configmap.Name)
+		if err := c.unpublishConfigMap(featureflag, configmap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpublishConfigMap removes the payload of a FeatureFlag from a ConfigMap
+// it no longer publishes to. A ConfigMap controlled by the FeatureFlag is
+// deleted, or released when the OnDeleteAnnotation asks for it; the keys of
+// the flag are removed from other ConfigMaps it owns. A ConfigMap shared
+// with other FeatureFlags is kept for them, with the keys and owner
+// reference of the flag removed. ConfigMaps it does not own are left alone.
+func (c *FeatureController) unpublishConfigMap(featureflag *samplev1alpha1.FeatureFlag, configmap *corev1.ConfigMap) error {
+	switch {
+	case isOwnedBy(configmap, featureflag) && len(otherContributors(configmap, featureflag)) > 0:
+		// NEVER modify objects from the store, so work on a copy.
+		configmapCopy := configmap.DeepCopy()
+		removePayload(configmapCopy, featureflag)
+		removeContributor(configmapCopy, featureflag)
+		klog.V(4).Infof("Leaving shared configmap %s/%s", configmap.Namespace, configmap.Name)
+		if _, err := c.configmapControl.UpdateConfigMap(configmap.Namespace, configmapCopy); err != nil {
+			return err
+		}
+		configmapUpdatedCount.WithLabelValues().Inc()
+	case metav1.IsControlledBy(configmap, featureflag) && releaseRequested(featureflag):
+		released, err := releaseConfigMap(configmap, featureflag)
+		if err != nil {
+			return err
+		}
+		klog.V(4).Infof("Releasing configmap %s/%s", configmap.Namespace, configmap.Name)
+		if _, err := c.configmapControl.UpdateConfigMap(configmap.Namespace, released); err != nil {
+			return err
+		}
+		configmapUpdatedCount.WithLabelValues().Inc()
+		c.recorder.Event(featureflag, corev1.EventTypeNormal, SuccessReleased, fmt.Sprintf(MessageResourceReleased, configmap.Name))
+	case metav1.IsControlledBy(configmap, featureflag):
+		klog.V(4).Infof("Deleting configmap %s/%s", configmap.Namespace, configmap.Name)
+		err := c.configmapControl.DeleteConfigMap(configmap.Namespace, configmap.Name)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		configmapDeletedCount.WithLabelValues().Inc()
+	case isOwnedBy(configmap, featureflag):
+		// NEVER modify objects from the store, so work on a copy.
+		configmapCopy := configmap.DeepCopy()
+		changed := removePayload(configmapCopy, featureflag)
+		if releaseRequested(featureflag) {
+			var err error
+			if configmapCopy, err = releaseConfigMap(configmapCopy, featureflag); err != nil {
+				return err
+			}
+			changed = true
+		}
+		if changed {
+			klog.V(4).Infof("Removing the payload from configmap %s/%s", configmap.Namespace, configmap.Name)
+			if _, err := c.configmapControl.UpdateConfigMap(configmap.Namespace, configmapCopy); err != nil {
+				return err
+			}
+			configmapUpdatedCount.WithLabelValues().Inc()
+		}
+	}
+	return nil
+}
+
+// addFinalizer adds FeatureFlagFinalizer to the FeatureFlag and returns the
+// updated FeatureFlag.
+func (c *FeatureController) addFinalizer(featureflag *samplev1alpha1.FeatureFlag) (*samplev1alpha1.FeatureFlag, error) {
+	// NEVER modify objects from the store.
+	featureflagCopy := featureflag.DeepCopy()
+	featureflagCopy.Finalizers = append(featureflagCopy.Finalizers, FeatureFlagFinalizer)
+	return c.featureclientset.FeaturecontrollerV1alpha1().FeatureFlags(featureflag.Namespace).Update(context.TODO(), featureflagCopy, metav1.UpdateOptions{})
+}
+
+// removePayload removes every key of the FeatureFlag, in any format, from
+// the Data of the ConfigMap, along with their recorded hashes. It reports
+// whether the ConfigMap was modified.
+func removePayload(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) bool {
+	changed := false
+	for _, ext := range payloadExtensions {
+		if _, ok := configmap.Data[featureflag.Name+"."+ext]; ok {
+			delete(configmap.Data, featureflag.Name+"."+ext)
+			changed = true
+		}
+	}
+	if recordContentHash(configmap, featureflag, "", "") {
+		changed = true
+	}
+	return changed
+}
+
+// isOwnedBy reports whether owner is one of the owners of obj, whether or
+// not it is its controller.
+func isOwnedBy(obj metav1.Object, owner metav1.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	var remaining []string
+	for _, f := range finalizers {
+		if f != finalizer {
+			remaining = append(remaining, f)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,121 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// newDeletedFeatureFlag returns a FeatureFlag being deleted, held by the finalizer.
+func newDeletedFeatureFlag(name string) *featurecontroller.FeatureFlag {
+	featureflag := newFeatureFlag(name)
+	featureflag.UID = types.UID("uid-" + featureflag.Name)
+	featureflag.DeletionTimestamp = &metav1.Time{Time: testNow}
+	return featureflag
+}
+
+// withoutFinalizer returns a copy of the FeatureFlag with its finalizer removed.
+func withoutFinalizer(featureflag *featurecontroller.FeatureFlag) *featurecontroller.FeatureFlag {
+	featureflag = featureflag.DeepCopy()
+	featureflag.Finalizers = nil
+	return featureflag
+}
+
+func (f *fixture) expectDeletedEvent(configmapName string) {
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeNormal, SuccessDeleted, fmt.Sprintf(MessageResourceDeleted, configmapName)); event != expected {
+		f.t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestAddFinalizer tests that the finalizer is added before the ConfigMap is published
+func TestAddFinalizer(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withoutFinalizer(newFeatureFlag("test"))
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+
+	withFinalizer := newFeatureFlag("test")
+	expConfig := newConfigMapWithPayload(withFinalizer, t)
+	f.expectUpdateFeatureFlagAction(withFinalizer)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(withFinalizer, expConfig))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestFinalizeControlledConfigMap tests that deleting a FeatureFlag deletes the ConfigMap it controls before releasing it
+func TestFinalizeControlledConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newDeletedFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectDeleteConfigMapAction(d)
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(featureflag))
+
+	f.run(getKey(featureflag, t))
+	f.expectDeletedEvent(d.Name)
+}
+
+// TestFinalizeOwnedConfigMap tests that deleting a FeatureFlag only removes its keys from a ConfigMap it owns without controlling
+func TestFinalizeOwnedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newDeletedFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
+	d.OwnerReferences[0].Controller = nil
+	d.Data["other.json"] = `{"value":true}`
+
+	expConfig := d.DeepCopy()
+	delete(expConfig.Data, payloadKey(featureflag))
+	delete(expConfig.Annotations, ContentHashesAnnotation)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(featureflag))
+
+	f.run(getKey(featureflag, t))
+	f.expectDeletedEvent(d.Name)
+}
+
+// TestFinalizeForeignConfigMap tests that deleting a FeatureFlag leaves a ConfigMap it does not own untouched
+func TestFinalizeForeignConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newDeletedFeatureFlag("test")
+	d := newConfigMapWithPayload(featureflag, t)
+	d.OwnerReferences = nil
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(featureflag))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestFinalizeWithoutFinalizer tests that nothing is done for a FeatureFlag being deleted without the finalizer
+func TestFinalizeWithoutFinalizer(t *testing.T) {
+	f := newFixture(t)
+	featureflag := withoutFinalizer(newDeletedFeatureFlag("test"))
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+
+	f.run(getKey(featureflag, t))
+}
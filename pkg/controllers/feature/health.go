@@ -0,0 +1,73 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// WaitForCacheSync waits for the informer caches of the controller to sync
+// and reports whether they did before stopCh was closed. It can be called
+// before Run, e.g. by a standby replica keeping its caches warm.
+func (c *FeatureController) WaitForCacheSync(stopCh <-chan struct{}) bool {
+	synced := append([]cache.InformerSynced{c.configmapsSynced, c.featureflagsSynced, c.featuresegmentsSynced,
+		c.clusterfeatureflagsSynced, c.namespacesSynced}, c.appsSynced...)
+	if c.secretsSynced != nil {
+		synced = append(synced, c.secretsSynced)
+	}
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		return false
+	}
+	atomic.StoreInt32(&c.cachesSynced, 1)
+	return true
+}
+
+// HasSynced reports whether WaitForCacheSync has succeeded.
+func (c *FeatureController) HasSynced() bool {
+	return atomic.LoadInt32(&c.cachesSynced) == 1
+}
+
+// CheckWorkers returns an error when a worker has been processing the same
+// item for longer than timeout, e.g. as it is blocked on a call to the API
+// server that never returns. Idle workers are healthy.
+func (c *FeatureController) CheckWorkers(timeout time.Duration) error {
+	c.inFlightLock.Lock()
+	defer c.inFlightLock.Unlock()
+	now := c.clock.Now()
+	for item, started := range c.inFlight {
+		if elapsed := now.Sub(started); elapsed > timeout {
+			return fmt.Errorf("worker stalled processing %v for %s", item, elapsed.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// startWork records that a worker started processing the item.
+func (c *FeatureController) startWork(item interface{}) {
+	c.inFlightLock.Lock()
+	defer c.inFlightLock.Unlock()
+	c.inFlight[item] = c.clock.Now()
+}
+
+// finishWork records that a worker finished processing the item.
+func (c *FeatureController) finishWork(item interface{}) {
+	c.inFlightLock.Lock()
+	defer c.inFlightLock.Unlock()
+	delete(c.inFlight, item)
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+const (
+	// ErrURLNotAllowed is used as part of the Event 'reason' when an HTTP
+	// publisher sends to a URL the operator does not allow
+	ErrURLNotAllowed = "ErrURLNotAllowed"
+
+	// MessageURLNotAllowed is the message used for the status of an HTTP
+	// publisher sending to a URL the operator does not allow
+	MessageURLNotAllowed = "URL %q is not allowed by the operator"
+	// MessageHTTPPublished is the message used for the status of an HTTP
+	// publisher whose URL was sent the current payload
+	MessageHTTPPublished = "URL %q was sent the current payload"
+)
+
+const (
+	// MaxHTTPPublishTimeout is the longest an HTTP publisher may wait for a
+	// response. Requests are sent by the workers, so it is kept well below
+	// the period of the liveness probe.
+	MaxHTTPPublishTimeout = 5 * time.Second
+
+	// httpBackoffInitial and httpBackoffMax bound how long an HTTP publisher
+	// waits after a failed request before sending to the URL again.
+	httpBackoffInitial = 10 * time.Second
+	httpBackoffMax     = 5 * time.Minute
+)
+
+// payloadContentTypes maps each format to the Content-Type of its payload.
+var payloadContentTypes = map[samplev1alpha1.PayloadFormat]string{
+	samplev1alpha1.PayloadFormatJSON:       "application/json",
+	samplev1alpha1.PayloadFormatYAML:       "application/yaml",
+	samplev1alpha1.PayloadFormatDotenv:     "text/plain; charset=utf-8",
+	samplev1alpha1.PayloadFormatProperties: "text/plain; charset=utf-8",
+}
+
+// EnableHTTPPublishers lets FeatureFlags send their payload to the URLs
+// below one of allowedURLs, which must have the same scheme and host, with
+// requests timing out after timeout, at most MaxHTTPPublishTimeout. Without
+// it, HTTP publishers report ErrPublisherDisabled.
+func (c *FeatureController) EnableHTTPPublishers(allowedURLs []string, timeout time.Duration) error {
+	if timeout <= 0 || timeout > MaxHTTPPublishTimeout {
+		return fmt.Errorf("timeout %s must be positive and at most %s", timeout, MaxHTTPPublishTimeout)
+	}
+	var allowed []*url.URL
+	for _, raw := range allowedURLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("allowed URL %q must be absolute", raw)
+		}
+		allowed = append(allowed, u)
+	}
+	backoff := flowcontrol.NewBackOff(httpBackoffInitial, httpBackoffMax)
+	backoff.Clock = c.clock
+	c.publishers[PublisherHTTP] = &httpPublisher{
+		c:       c,
+		allowed: allowed,
+		backoff: backoff,
+		client: &http.Client{
+			Timeout: timeout,
+			// Redirects could lead to a URL that is not allowed.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	return nil
+}
+
+// httpPublisher sends the payload of FeatureFlags to the allowed URLs.
+type httpPublisher struct {
+	c       *FeatureController
+	allowed []*url.URL
+	client  *http.Client
+	// backoff holds back each publisher of a FeatureFlag after a failed
+	// request, so that a URL that is down does not hold up a worker on
+	// every sync of the flag.
+	backoff *flowcontrol.Backoff
+}
+
+// allows reports whether the URL is under one of the allowed URLs. Paths
+// with "." or ".." segments are rejected, as the server could resolve them
+// outside of the allowed path, and an allowed path only covers the paths
+// below it, so that "/api" does not allow "/apix".
+func (p *httpPublisher) allows(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil {
+		return false
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	for _, allowed := range p.allowed {
+		if u.Scheme == allowed.Scheme && u.Host == allowed.Host && underPath(u.Path, allowed.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// underPath reports whether path is base or below it.
+func underPath(path, base string) bool {
+	if path == base || base == "" {
+		return true
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return strings.HasPrefix(path, base)
+}
+
+// Publish sends the payload to the URL, unless it was already sent the same
+// payload. After a failure, nothing is sent
+// until the backoff of the publisher expires: the last failure is reported
+// meanwhile, and the FeatureFlag is requeued for when it expires.
+func (p *httpPublisher) Publish(snapshot *snapshot, sink *samplev1alpha1.Publisher) (*publication, error) {
+	target := sink.HTTP.URL
+	if !p.allows(target) {
+		return &publication{reason: ErrURLNotAllowed, message: fmt.Sprintf(MessageURLNotAllowed, target)}, nil
+	}
+	_, content, err := snapshot.render()
+	if err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf(MessageHTTPPublished, target)
+	last := getPublisherStatus(&snapshot.featureflag.Status, sink.Name)
+	if last != nil && last.Published == corev1.ConditionTrue && last.ContentHash == contentHash(content) {
+		return published(message, content), nil
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(snapshot.featureflag)
+	if err != nil {
+		return nil, err
+	}
+	id := key + "/" + sink.Name
+	now := p.backoff.Clock.Now()
+	p.backoff.GC()
+	if last != nil && last.Published == corev1.ConditionFalse && p.backoff.IsInBackOffSinceUpdate(id, now) {
+		klog.V(4).Infof("FeatureFlag %s publisher %s is backing off, not sending to %s", key, sink.Name, target)
+		p.c.workqueue.AddAfter(key, p.backoff.Get(id))
+		return &publication{reason: last.Reason, message: last.Message}, nil
+	}
+
+	method := string(sink.HTTP.Method)
+	if method == "" {
+		method = string(samplev1alpha1.HTTPMethodPut)
+	}
+	req, err := http.NewRequest(method, target, strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", payloadContentTypes[payloadFormat(snapshot.effective)])
+
+	klog.V(4).Infof("FeatureFlag %s payload changed, sending %s %s", key, method, target)
+	if err := p.send(req); err != nil {
+		p.backoff.Next(id, now)
+		return nil, err
+	}
+	p.backoff.Reset(id)
+	publisherPublishedCount.WithLabelValues(PublisherHTTP).Inc()
+	return published(message, content), nil
+}
+
+// send sends the request. Any 2xx response is a success.
+func (p *httpPublisher) send(req *http.Request) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection is reused.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", req.Method, req.URL, resp.Status)
+	}
+	return nil
+}
+
+// Unpublish does nothing: consumers of an HTTP publisher are not told when
+// the FeatureFlag is deleted.
+func (p *httpPublisher) Unpublish(key string, featureflag *samplev1alpha1.FeatureFlag, sink *samplev1alpha1.Publisher) error {
+	return nil
+}
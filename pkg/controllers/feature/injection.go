@@ -0,0 +1,486 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	appsinformers "k8s.io/client-go/informers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// InjectedAnnotation lists, comma separated, the FeatureFlags injected into
+// the pod template of an app, so that their variables are removed once the
+// flags no longer select it. It is set on the app rather than on its pod
+// template so that it does not roll the app out.
+const InjectedAnnotation = "featureflags.featured.io/injected"
+
+const (
+	// ErrInjectFailed is used as part of the Event 'reason' when a
+	// FeatureFlag fails to be injected into an app
+	ErrInjectFailed = "ErrInjectFailed"
+	// ErrEnvVarExists is used as part of the Event 'reason' when an app
+	// selected by a FeatureFlag already defines its variable
+	ErrEnvVarExists = "ErrEnvVarExists"
+	// ErrInjectionDisabled is used as part of the Event 'reason' when a
+	// FeatureFlag selects apps but injection is not enabled on the operator
+	ErrInjectionDisabled = "ErrInjectionDisabled"
+
+	// MessageInjected is the message used for the Injected condition of a
+	// FeatureFlag injected into every app it selects
+	MessageInjected = "FeatureFlag is injected into %d apps"
+	// MessageInjectFailed is the message used for Events when a FeatureFlag
+	// fails to be injected into an app
+	MessageInjectFailed = "Injecting into %s failed: %s"
+	// MessageEnvVarExists is the message used for Events when an app selected
+	// by a FeatureFlag already defines its variable
+	MessageEnvVarExists = "%s already defines %s"
+	// MessageInjectionDisabled is the message used for the Injected condition
+	// of a FeatureFlag selecting apps when injection is not enabled
+	MessageInjectionDisabled = "Injection into apps is not enabled on the operator"
+)
+
+// EnableInjection lets FeatureFlags with an AppSelector be injected into the
+// pod template of the Deployments, StatefulSets and DaemonSets they select.
+// The informers must watch every app of the namespaces the operator manages.
+// Without it, such FeatureFlags report ErrInjectionDisabled.
+func (c *FeatureController) EnableInjection(
+	deploymentInformer appsinformers.DeploymentInformer,
+	statefulsetInformer appsinformers.StatefulSetInformer,
+	daemonsetInformer appsinformers.DaemonSetInformer) {
+
+	c.deploymentsLister = deploymentInformer.Lister()
+	c.statefulsetsLister = statefulsetInformer.Lister()
+	c.daemonsetsLister = daemonsetInformer.Lister()
+	c.appsSynced = []cache.InformerSynced{
+		deploymentInformer.Informer().HasSynced,
+		statefulsetInformer.Informer().HasSynced,
+		daemonsetInformer.Informer().HasSynced,
+	}
+
+	// Set up an event handler for when apps are created, relabelled, edited
+	// or deleted, which changes the apps FeatureFlags select or the variables
+	// they define. Status updates are ignored.
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: c.handleApp,
+		UpdateFunc: func(old, new interface{}) {
+			oldApp := old.(metav1.Object)
+			newApp := new.(metav1.Object)
+			if oldApp.GetGeneration() == newApp.GetGeneration() && reflect.DeepEqual(oldApp.GetLabels(), newApp.GetLabels()) &&
+				oldApp.GetAnnotations()[InjectedAnnotation] == newApp.GetAnnotations()[InjectedAnnotation] {
+				return
+			}
+			c.handleApp(new)
+		},
+		DeleteFunc: c.handleApp,
+	}
+	deploymentInformer.Informer().AddEventHandler(handler)
+	statefulsetInformer.Informer().AddEventHandler(handler)
+	daemonsetInformer.Informer().AddEventHandler(handler)
+}
+
+// injectionEnabled reports whether EnableInjection was called.
+func (c *FeatureController) injectionEnabled() bool {
+	return c.deploymentsLister != nil
+}
+
+// app is a Deployment, StatefulSet or DaemonSet, with its pod template.
+type app struct {
+	kind     string
+	obj      runtime.Object
+	meta     metav1.Object
+	template *corev1.PodTemplateSpec
+}
+
+// newApp wraps a Deployment, StatefulSet or DaemonSet. Modifying the app
+// modifies obj.
+func newApp(obj runtime.Object) *app {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &app{kind: "Deployment", obj: o, meta: o, template: &o.Spec.Template}
+	case *appsv1.StatefulSet:
+		return &app{kind: "StatefulSet", obj: o, meta: o, template: &o.Spec.Template}
+	case *appsv1.DaemonSet:
+		return &app{kind: "DaemonSet", obj: o, meta: o, template: &o.Spec.Template}
+	}
+	return nil
+}
+
+func (a *app) String() string {
+	return a.kind + "/" + a.meta.GetName()
+}
+
+// listApps returns the apps of the namespace, sorted by kind and name.
+func (c *FeatureController) listApps(namespace string) ([]*app, error) {
+	var apps []*app
+	deployments, err := c.deploymentsLister.Deployments(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		apps = append(apps, newApp(deployment))
+	}
+	statefulsets, err := c.statefulsetsLister.StatefulSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, statefulset := range statefulsets {
+		apps = append(apps, newApp(statefulset))
+	}
+	daemonsets, err := c.daemonsetsLister.DaemonSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, daemonset := range daemonsets {
+		apps = append(apps, newApp(daemonset))
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].String() < apps[j].String()
+	})
+	return apps, nil
+}
+
+// injectedEnvVar returns the variable the snapshot is injected as: the value
+// served to contexts matching no rule, or a reference to the key its payload
+// is published under.
+func injectedEnvVar(snapshot *snapshot) (corev1.EnvVar, error) {
+	featureflag := snapshot.effective
+	env := corev1.EnvVar{Name: envVarName(featureflag.Name)}
+	if featureflag.Spec.Injection == samplev1alpha1.InjectionReference {
+		key := payloadKey(featureflag)
+		if publishTarget(featureflag) == samplev1alpha1.PublishTargetSecret {
+			env.ValueFrom = &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: featureflag.Spec.SecretName},
+				Key:                  key,
+			}}
+		} else {
+			env.ValueFrom = &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: featureflag.Spec.ConfigMapName},
+				Key:                  key,
+			}}
+		}
+		return env, nil
+	}
+	payload, err := newFlagPayload(featureflag, snapshot.variation, nil)
+	if err != nil {
+		return env, err
+	}
+	env.Value = scalarValue(payload)
+	return env, nil
+}
+
+// injectApps injects the snapshot into the apps selected by the AppSelector
+// of its FeatureFlag and removes it from the apps it no longer selects. It
+// returns nil when the FeatureFlag has no AppSelector.
+func (c *FeatureController) injectApps(snapshot *snapshot) (*publication, error) {
+	featureflag := snapshot.effective
+	if featureflag.Spec.AppSelector == nil {
+		if !c.injectionEnabled() {
+			return nil, nil
+		}
+		_, err := c.syncApps(snapshot.featureflag, labels.Nothing(), corev1.EnvVar{Name: envVarName(featureflag.Name)})
+		return nil, err
+	}
+	if !c.injectionEnabled() {
+		return &publication{reason: ErrInjectionDisabled, message: MessageInjectionDisabled}, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(featureflag.Spec.AppSelector)
+	if err != nil {
+		return nil, err
+	}
+	env, err := injectedEnvVar(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return c.syncApps(snapshot.featureflag, selector, env)
+}
+
+// uninjectApps removes a FeatureFlag being deleted from the apps it was
+// injected into.
+func (c *FeatureController) uninjectApps(featureflag *samplev1alpha1.FeatureFlag) error {
+	if !c.injectionEnabled() {
+		return nil
+	}
+	_, err := c.syncApps(featureflag, labels.Nothing(), corev1.EnvVar{Name: envVarName(featureflag.Name)})
+	return err
+}
+
+// syncApps sets env in every container of the apps of the namespace of the
+// FeatureFlag matching selector, and removes it from the apps it was injected
+// into that no longer match. Apps are only patched when they change. An app
+// already defining the variable without the FeatureFlag having injected it
+// is left alone. A failing app does not hold back the others; each failure
+// is reported with a Warning event.
+func (c *FeatureController) syncApps(featureflag *samplev1alpha1.FeatureFlag, selector labels.Selector, env corev1.EnvVar) (*publication, error) {
+	apps, err := c.listApps(featureflag.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	injected := 0
+	reason := SuccessSynced
+	var failures []string
+	var errs []error
+	for _, a := range apps {
+		selected := selector.Matches(labels.Set(a.meta.GetLabels()))
+		listed := isInjected(a.meta, featureflag.Name)
+		if !selected && !listed {
+			continue
+		}
+		if selected && !listed && definesEnv(a.template, env.Name) {
+			msg := fmt.Sprintf(MessageEnvVarExists, a, env.Name)
+			c.recorder.Event(featureflag, corev1.EventTypeWarning, ErrEnvVarExists, msg)
+			if reason == SuccessSynced {
+				reason = ErrEnvVarExists
+			}
+			failures = append(failures, msg)
+			continue
+		}
+
+		// NEVER modify objects from the store, so work on a copy.
+		modified := newApp(a.obj.DeepCopyObject())
+		var changed bool
+		if selected {
+			changed = setEnv(modified.template, env)
+		} else {
+			changed = removeEnv(modified.template, env.Name)
+		}
+		if setInjected(modified.meta, featureflag.Name, selected) {
+			changed = true
+		}
+		if changed {
+			klog.V(4).Infof("FeatureFlag %s/%s injection changed, patching %s", featureflag.Namespace, featureflag.Name, a)
+			if err := c.patchApp(a, modified); err != nil {
+				msg := fmt.Sprintf(MessageInjectFailed, a, err)
+				c.recorder.Event(featureflag, corev1.EventTypeWarning, ErrInjectFailed, msg)
+				reason = ErrInjectFailed
+				failures = append(failures, msg)
+				errs = append(errs, fmt.Errorf("%s: %v", a, err))
+				continue
+			}
+			appPatchedCount.WithLabelValues(a.kind).Inc()
+		}
+		if selected {
+			injected++
+		}
+	}
+
+	if len(failures) > 0 {
+		return &publication{reason: reason, message: strings.Join(failures, "; ")}, utilerrors.NewAggregate(errs)
+	}
+	return &publication{reason: SuccessSynced, message: fmt.Sprintf(MessageInjected, injected)}, nil
+}
+
+// patchApp patches the app with the strategic merge patch turning original
+// into modified, which only holds the changed variables and annotation.
+func (c *FeatureController) patchApp(original, modified *app) error {
+	originalJSON, err := json.Marshal(original.obj)
+	if err != nil {
+		return err
+	}
+	modifiedJSON, err := json.Marshal(modified.obj)
+	if err != nil {
+		return err
+	}
+	patch, err := strategicpatch.CreateTwoWayMergePatch(originalJSON, modifiedJSON, modified.obj)
+	if err != nil {
+		return err
+	}
+
+	namespace, name := original.meta.GetNamespace(), original.meta.GetName()
+	apps := c.kubeclientset.AppsV1()
+	switch original.obj.(type) {
+	case *appsv1.Deployment:
+		_, err = apps.Deployments(namespace).Patch(context.TODO(), name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case *appsv1.StatefulSet:
+		_, err = apps.StatefulSets(namespace).Patch(context.TODO(), name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case *appsv1.DaemonSet:
+		_, err = apps.DaemonSets(namespace).Patch(context.TODO(), name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
+
+// setEnv sets the variable in every container of the pod template and
+// reports whether the pod template was modified.
+func setEnv(template *corev1.PodTemplateSpec, env corev1.EnvVar) bool {
+	changed := false
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		found := false
+		for j := range container.Env {
+			if container.Env[j].Name != env.Name {
+				continue
+			}
+			found = true
+			if !reflect.DeepEqual(container.Env[j], env) {
+				container.Env[j] = env
+				changed = true
+			}
+		}
+		if !found {
+			container.Env = append(container.Env, env)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// removeEnv removes the variable from every container of the pod template
+// and reports whether the pod template was modified.
+func removeEnv(template *corev1.PodTemplateSpec, name string) bool {
+	changed := false
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		var env []corev1.EnvVar
+		for _, e := range container.Env {
+			if e.Name == name {
+				changed = true
+				continue
+			}
+			env = append(env, e)
+		}
+		container.Env = env
+	}
+	return changed
+}
+
+// definesEnv reports whether a container of the pod template defines the
+// variable.
+func definesEnv(template *corev1.PodTemplateSpec, name string) bool {
+	for _, container := range template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// injectedFlags returns the FeatureFlags listed in the InjectedAnnotation of
+// the app.
+func injectedFlags(obj metav1.Object) []string {
+	value := obj.GetAnnotations()[InjectedAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// isInjected reports whether the FeatureFlag is listed in the
+// InjectedAnnotation of the app.
+func isInjected(obj metav1.Object, flag string) bool {
+	for _, name := range injectedFlags(obj) {
+		if name == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// setInjected adds the FeatureFlag to the InjectedAnnotation of the app, or
+// removes it, and reports whether the annotation was modified. The
+// annotation is removed once empty.
+func setInjected(obj metav1.Object, flag string, injected bool) bool {
+	if isInjected(obj, flag) == injected {
+		return false
+	}
+	var flags []string
+	for _, name := range injectedFlags(obj) {
+		if name != flag {
+			flags = append(flags, name)
+		}
+	}
+	if injected {
+		flags = append(flags, flag)
+		sort.Strings(flags)
+	}
+
+	annotations := obj.GetAnnotations()
+	if len(flags) == 0 {
+		delete(annotations, InjectedAnnotation)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[InjectedAnnotation] = strings.Join(flags, ",")
+	}
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// handleApp takes a Deployment, StatefulSet or DaemonSet, or its tombstone,
+// and enqueues the FeatureFlags of its namespace that select it or were
+// injected into it.
+func (c *FeatureController) handleApp(obj interface{}) {
+	var object metav1.Object
+	var ok bool
+	if object, ok = obj.(metav1.Object); !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		object, ok = tombstone.Obj.(metav1.Object)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+		klog.V(4).Infof("Recovered deleted app '%s' from tombstone", object.GetName())
+	}
+	klog.V(4).Infof("Processing app: %s", object.GetName())
+
+	featureflags, err := c.featureflagsLister.FeatureFlags(object.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, featureflag := range featureflags {
+		if isInjected(object, featureflag.Name) || selectsApp(featureflag, object) {
+			c.enqueueFeatureFlag(featureflag)
+		}
+	}
+}
+
+// selectsApp reports whether the AppSelector of the FeatureFlag matches the
+// labels of the app.
+func selectsApp(featureflag *samplev1alpha1.FeatureFlag, obj metav1.Object) bool {
+	if featureflag.Spec.AppSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(featureflag.Spec.AppSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
@@ -0,0 +1,284 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kubetesting "k8s.io/client-go/testing"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// newAppTemplate returns a pod template with a single container defining env.
+func newAppTemplate(env ...core.EnvVar) core.PodTemplateSpec {
+	return core.PodTemplateSpec{
+		Spec: core.PodSpec{
+			Containers: []core.Container{{Name: "app", Image: "checkout:1.0", Env: env}},
+		},
+	}
+}
+
+// newDeployment returns a Deployment with the labels whose container defines env.
+func newDeployment(name string, labels map[string]string, env ...core.EnvVar) *apps.Deployment {
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault, Labels: labels},
+		Spec:       apps.DeploymentSpec{Template: newAppTemplate(env...)},
+	}
+}
+
+// withInjected sets the InjectedAnnotation of the app to list flags.
+func withInjected(obj metav1.Object, flags string) {
+	obj.SetAnnotations(map[string]string{InjectedAnnotation: flags})
+}
+
+// withInjection returns a copy of the FeatureFlag as updated by a sync
+// publishing the ConfigMap and reporting the Injected condition.
+func withInjection(featureflag *featurecontroller.FeatureFlag, configmap *core.ConfigMap, status core.ConditionStatus, reason, message string) *featurecontroller.FeatureFlag {
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageConfigMapSynced, configmap.Name))
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagInjected, status, reason, message)
+	if status == core.ConditionTrue {
+		featureflag = withCondition(featureflag, featurecontroller.FeatureFlagReady, core.ConditionTrue, SuccessSynced, MessageResourceSynced)
+	} else {
+		featureflag = withCondition(featureflag, featurecontroller.FeatureFlagReady, core.ConditionFalse, reason, message)
+	}
+	featureflag.Status.ContentHash = contentHash(configmap.Data[payloadKey(featureflag)])
+	return withSynced(featureflag)
+}
+
+func (f *fixture) expectPatchAppAction(resource string, obj metav1.Object, patch string) {
+	f.kubeactions = append(f.kubeactions, kubetesting.NewPatchAction(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: resource},
+		obj.GetNamespace(), obj.GetName(), types.StrategicMergePatchType, []byte(patch)))
+}
+
+// TestInjectValue tests that the value of the flag is injected into the selected apps only
+func TestInjectValue(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newFeatureFlag("new-checkout")
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	featureflag.Spec.Injection = featurecontroller.InjectionValue
+	selected := newDeployment("checkout", map[string]string{"app": "checkout"}, core.EnvVar{Name: "PORT", Value: "8080"})
+	other := newDeployment("cart", map[string]string{"app": "cart"})
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.appLister = append(f.appLister, selected, other)
+	f.kubeobjects = append(f.kubeobjects, selected, other)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectPatchAppAction("deployments", selected, `{"metadata":{"annotations":{"featureflags.featured.io/injected":"new-checkout"}},"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"app"}],"containers":[{"$setElementOrder/env":[{"name":"PORT"},{"name":"FEATURE_NEW_CHECKOUT"}],"env":[{"name":"FEATURE_NEW_CHECKOUT","value":"true"}],"name":"app"}]}}}}`)
+	f.expectUpdateFooStatusAction(withInjection(featureflag, expConfig, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageInjected, 1)))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestInjectReference tests that a flag injected by reference reads the key of its payload
+func TestInjectReference(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newFeatureFlag("new-checkout")
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	featureflag.Spec.Injection = featurecontroller.InjectionReference
+	daemonset := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout-agent", Namespace: metav1.NamespaceDefault, Labels: map[string]string{"app": "checkout"}},
+		Spec:       apps.DaemonSetSpec{Template: newAppTemplate()},
+	}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.appLister = append(f.appLister, daemonset)
+	f.kubeobjects = append(f.kubeobjects, daemonset)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectPatchAppAction("daemonsets", daemonset, `{"metadata":{"annotations":{"featureflags.featured.io/injected":"new-checkout"}},"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"app"}],"containers":[{"env":[{"name":"FEATURE_NEW_CHECKOUT","valueFrom":{"configMapKeyRef":{"key":"new-checkout.json","name":"new-checkout-config"}}}],"name":"app"}]}}}}`)
+	f.expectUpdateFooStatusAction(withInjection(featureflag, expConfig, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageInjected, 1)))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestInjectUnchanged tests that an app already holding the current value is not patched
+func TestInjectUnchanged(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newFeatureFlag("new-checkout")
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	statefulset := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: metav1.NamespaceDefault, Labels: map[string]string{"app": "checkout"}},
+		Spec:       apps.StatefulSetSpec{Template: newAppTemplate(core.EnvVar{Name: "FEATURE_NEW_CHECKOUT", Value: "true"})},
+	}
+	withInjected(statefulset, "new-checkout")
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.appLister = append(f.appLister, statefulset)
+	f.kubeobjects = append(f.kubeobjects, statefulset)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withInjection(featureflag, expConfig, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageInjected, 1)))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestInjectValueChanged tests that the variable of an app is refreshed when the value served changes
+func TestInjectValueChanged(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newFeatureFlag("new-checkout")
+	featureflag.Spec.Enabled = false
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	deployment := newDeployment("checkout", map[string]string{"app": "checkout"}, core.EnvVar{Name: "FEATURE_NEW_CHECKOUT", Value: "true"})
+	withInjected(deployment, "new-checkout")
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.appLister = append(f.appLister, deployment)
+	f.kubeobjects = append(f.kubeobjects, deployment)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectPatchAppAction("deployments", deployment, `{"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"app"}],"containers":[{"$setElementOrder/env":[{"name":"FEATURE_NEW_CHECKOUT"}],"env":[{"name":"FEATURE_NEW_CHECKOUT","value":"false"}],"name":"app"}]}}}}`)
+	f.expectUpdateFooStatusAction(withInjection(featureflag, expConfig, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageInjected, 1)))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestUninjectUnselected tests that the variable is removed from an app the flag no longer selects
+func TestUninjectUnselected(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newFeatureFlag("new-checkout")
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	deployment := newDeployment("cart", map[string]string{"app": "cart"},
+		core.EnvVar{Name: "FEATURE_NEW_CHECKOUT", Value: "true"}, core.EnvVar{Name: "FEATURE_NEW_CART", Value: "true"})
+	withInjected(deployment, "new-cart,new-checkout")
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.appLister = append(f.appLister, deployment)
+	f.kubeobjects = append(f.kubeobjects, deployment)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectPatchAppAction("deployments", deployment, `{"metadata":{"annotations":{"featureflags.featured.io/injected":"new-cart"}},"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"app"}],"containers":[{"$setElementOrder/env":[{"name":"FEATURE_NEW_CART"}],"env":[{"$patch":"delete","name":"FEATURE_NEW_CHECKOUT"}],"name":"app"}]}}}}`)
+	f.expectUpdateFooStatusAction(withInjection(featureflag, expConfig, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageInjected, 0)))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestUninjectWithoutSelector tests that removing the app selector removes the variable and the Injected condition
+func TestUninjectWithoutSelector(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newFeatureFlag("new-checkout")
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	featureflag = withInjection(featureflag, expConfig, core.ConditionTrue, SuccessSynced, fmt.Sprintf(MessageInjected, 1))
+	deployment := newDeployment("checkout", map[string]string{"app": "checkout"}, core.EnvVar{Name: "FEATURE_NEW_CHECKOUT", Value: "true"})
+	withInjected(deployment, "new-checkout")
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, expConfig)
+	f.kubeobjects = append(f.kubeobjects, expConfig)
+	f.appLister = append(f.appLister, deployment)
+	f.kubeobjects = append(f.kubeobjects, deployment)
+
+	expFlag := featureflag.DeepCopy()
+	removeCondition(&expFlag.Status, featurecontroller.FeatureFlagInjected)
+	f.expectPatchAppAction("deployments", deployment, `{"metadata":{"annotations":null},"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"app"}],"containers":[{"env":null,"name":"app"}]}}}}`)
+	f.expectUpdateFooStatusAction(expFlag)
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestInjectEnvVarExists tests that an app already defining the variable is left alone and reported
+func TestInjectEnvVarExists(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newFeatureFlag("new-checkout")
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	deployment := newDeployment("checkout", map[string]string{"app": "checkout"}, core.EnvVar{Name: "FEATURE_NEW_CHECKOUT", Value: "false"})
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.appLister = append(f.appLister, deployment)
+	f.kubeobjects = append(f.kubeobjects, deployment)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	msg := fmt.Sprintf(MessageEnvVarExists, "Deployment/checkout", "FEATURE_NEW_CHECKOUT")
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withInjection(featureflag, expConfig, core.ConditionFalse, ErrEnvVarExists, msg))
+
+	f.run(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrEnvVarExists, msg); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestInjectionNotEnabled tests that a flag selecting apps reports that injection is not enabled without affecting its ConfigMap
+func TestInjectionNotEnabled(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("new-checkout")
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withInjection(featureflag, expConfig, core.ConditionFalse, ErrInjectionDisabled, MessageInjectionDisabled))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestUninjectOnDelete tests that deleting a FeatureFlag removes it from the apps it was injected into
+func TestUninjectOnDelete(t *testing.T) {
+	f := newFixture(t)
+	f.injectionEnabled = true
+	featureflag := newDeletedFeatureFlag("new-checkout")
+	featureflag.Spec.AppSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "checkout"}}
+	deployment := newDeployment("checkout", map[string]string{"app": "checkout"},
+		core.EnvVar{Name: "PORT", Value: "8080"}, core.EnvVar{Name: "FEATURE_NEW_CHECKOUT", Value: "true"})
+	withInjected(deployment, "new-checkout")
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.appLister = append(f.appLister, deployment)
+	f.kubeobjects = append(f.kubeobjects, deployment)
+
+	f.expectGetConfigMapAction(newConfigMap(featureflag))
+	f.expectPatchAppAction("deployments", deployment, `{"metadata":{"annotations":null},"spec":{"template":{"spec":{"$setElementOrder/containers":[{"name":"app"}],"containers":[{"$setElementOrder/env":[{"name":"PORT"}],"env":[{"$patch":"delete","name":"FEATURE_NEW_CHECKOUT"}],"name":"app"}]}}}}`)
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(featureflag))
+
+	f.run(getKey(featureflag, t))
+	f.expectDeletedEvent(featureflag.Spec.ConfigMapName)
+}
+
+// TestSetInjected tests that the InjectedAnnotation lists the injected flags sorted and is removed once empty
+func TestSetInjected(t *testing.T) {
+	deployment := newDeployment("checkout", nil)
+	if !setInjected(deployment, "new-checkout", true) || !setInjected(deployment, "new-cart", true) {
+		t.Fatal("expected the annotation to change")
+	}
+	if setInjected(deployment, "new-cart", true) {
+		t.Error("expected an injected flag to leave the annotation unchanged")
+	}
+	if got := deployment.Annotations[InjectedAnnotation]; got != "new-cart,new-checkout" {
+		t.Errorf("expected the flags to be sorted, got %q", got)
+	}
+	setInjected(deployment, "new-cart", false)
+	setInjected(deployment, "new-checkout", false)
+	if _, ok := deployment.Annotations[InjectedAnnotation]; ok {
+		t.Error("expected the empty annotation to be removed")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ManagedLabel is set on every ConfigMap the operator publishes to. The
+	// ConfigMap informer only watches the ConfigMaps carrying it, so the
+	// operator does not cache every ConfigMap of the cluster.
+	ManagedLabel = "featureflags.featured.io/managed"
+	// ManagedLabelSelector selects the ConfigMaps carrying ManagedLabel.
+	ManagedLabelSelector = ManagedLabel + "=true"
+)
+
+// ManagedListOptions restricts the list and watch of an informer to the
+// objects managed by the operator. It is meant for
+// kubeinformers.WithTweakListOptions.
+func ManagedListOptions(options *metav1.ListOptions) {
+	options.LabelSelector = ManagedLabelSelector
+}
+
+// setManaged sets ManagedLabel on the ConfigMap and reports whether it was
+// missing, e.g. on a ConfigMap published by an older version of the operator.
+func setManaged(configmap *corev1.ConfigMap) bool {
+	if configmap.Labels[ManagedLabel] == "true" {
+		return false
+	}
+	if configmap.Labels == nil {
+		configmap.Labels = map[string]string{}
+	}
+	configmap.Labels[ManagedLabel] = "true"
+	return true
+}
+
+// getConfigMap returns the ConfigMap from the informer cache. A ConfigMap
+// missing from the cache may exist without ManagedLabel, so it is looked up
+// through the API server before reporting it as not found.
+func (c *FeatureController) getConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
+	configmap, err := c.configmapsLister.ConfigMaps(namespace).Get(name)
+	if !errors.IsNotFound(err) {
+		return configmap, err
+	}
+	return c.configmapControl.GetConfigMap(namespace, name)
+}
+
+// createConfigMap creates the ConfigMap, which is missing from the informer
+// cache. When a ConfigMap of the same name exists without ManagedLabel it is
+// returned instead, so that it is labelled if controlled by the flag, adopted
+// or reported as a conflict like any other. It reports whether the
+// ConfigMap was created.
+func (c *FeatureController) createConfigMap(namespace string, configmap *corev1.ConfigMap) (*corev1.ConfigMap, bool, error) {
+	created, err := c.configmapControl.CreateConfigMap(namespace, configmap)
+	if errors.IsAlreadyExists(err) {
+		existing, err := c.configmapControl.GetConfigMap(namespace, configmap.Name)
+		return existing, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	configmapCreatedCount.WithLabelValues().Inc()
+	return created, true, nil
+}
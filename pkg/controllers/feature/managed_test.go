@@ -0,0 +1,144 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	goruntime "runtime"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// newUnlabeledConfigMap returns the ConfigMap published for the FeatureFlag
+// by an older version of the operator, which did not label it.
+func newUnlabeledConfigMap(featureflag *featurecontroller.FeatureFlag, t *testing.T) *core.ConfigMap {
+	configmap := newConfigMapWithPayload(featureflag, t)
+	configmap.Labels = nil
+	return configmap
+}
+
+// TestMigrateUnlabeledConfigMap tests that a ConfigMap published by an older version, missing from the cache, is labelled
+func TestMigrateUnlabeledConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	d := newUnlabeledConfigMap(featureflag, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	// The informer only caches labelled ConfigMaps, so d is only known to
+	// the API server.
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newConfigMapWithPayload(featureflag, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectGetConfigMapAction(d)
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestAdoptUncachedConfigMap tests that an unlabelled ConfigMap missing from the cache can still be adopted
+func TestAdoptUncachedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newFeatureFlag("test")
+	featureflag.Annotations = map[string]string{featurecontroller.AdoptAnnotation: "true"}
+	d := newLegacyConfigMap(featureflag)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newAdoptedConfigMap(featureflag, t)
+	f.expectCreateConfigMapAction(newConfigMapWithPayload(featureflag, t))
+	f.expectGetConfigMapAction(d)
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(featureflag, expConfig))
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestFinalizeUncachedConfigMap tests that the unlabelled ConfigMap of a deleted FeatureFlag is still deleted
+func TestFinalizeUncachedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newDeletedFeatureFlag("test")
+	d := newUnlabeledConfigMap(featureflag, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectGetConfigMapAction(d)
+	f.expectDeleteConfigMapAction(d)
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(featureflag))
+
+	f.run(getKey(featureflag, t))
+	f.expectDeletedEvent(d.Name)
+}
+
+// BenchmarkConfigMapInformer compares the memory held by a ConfigMap
+// informer watching every ConfigMap with one restricted to ManagedLabel, in
+// a cluster of 50k unrelated ConfigMaps and 100 managed ones.
+func BenchmarkConfigMapInformer(b *testing.B) {
+	const unrelated, managed = 50000, 100
+	var objects []runtime.Object
+	for i := 0; i < unrelated; i++ {
+		objects = append(objects, &core.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("unrelated-%d", i), Namespace: fmt.Sprintf("ns-%d", i%100)},
+			Data:       map[string]string{"application.properties": "server.port=8080\nlogging.level=INFO\n"},
+		})
+	}
+	for i := 0; i < managed; i++ {
+		objects = append(objects, &core.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("flags-%d", i), Namespace: fmt.Sprintf("ns-%d", i), Labels: map[string]string{ManagedLabel: "true"}},
+			Data:       map[string]string{"test.json": `{"value":true}`},
+		})
+	}
+	client := k8sfake.NewSimpleClientset(objects...)
+
+	for _, bm := range []struct {
+		name    string
+		options []kubeinformers.SharedInformerOption
+	}{
+		{name: "all"},
+		{name: "managed", options: []kubeinformers.SharedInformerOption{kubeinformers.WithTweakListOptions(ManagedListOptions)}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			var cached int
+			var heap uint64
+			for n := 0; n < b.N; n++ {
+				before := heapInUse()
+				ctx, cancel := context.WithCancel(context.Background())
+				factory := kubeinformers.NewSharedInformerFactoryWithOptions(client, 0, bm.options...)
+				informer := factory.Core().V1().ConfigMaps().Informer()
+				factory.Start(ctx.Done())
+				if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+					b.Fatal("informer did not sync")
+				}
+				cached = len(informer.GetStore().ListKeys())
+				if after := heapInUse(); after > before {
+					heap = after - before
+				}
+				cancel()
+			}
+			b.ReportMetric(float64(cached), "cached")
+			b.ReportMetric(float64(heap)/(1<<20), "heap-MiB")
+		})
+	}
+}
+
+// heapInUse returns the bytes of live heap objects after a garbage
+// collection.
+func heapInUse() uint64 {
+	goruntime.GC()
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -43,14 +43,32 @@ func newCounter(namespace string, subsystem string, name string, help string, la
 }
 
 var (
-	configmapTotalCount   = newCounter("featured_operator", "featureflag", "configmaps", "Total number of configmap managed", []string{})
-	configmapCreatedCount = newCounter("featured_operator", "featureflag", "configmap_created", "Total number of configmap created", []string{})
-	configmapDeletedCount = newCounter("featured_operator", "featureflag", "configmap_deleted", "Total number of configmap deleted", []string{})
+	configmapTotalCount     = newCounter("featured_operator", "featureflag", "configmaps", "Total number of configmap managed", []string{})
+	configmapCreatedCount   = newCounter("featured_operator", "featureflag", "configmap_created", "Total number of configmap created", []string{})
+	configmapUpdatedCount   = newCounter("featured_operator", "featureflag", "configmap_updated", "Total number of configmap updated", []string{})
+	configmapDeletedCount   = newCounter("featured_operator", "featureflag", "configmap_deleted", "Total number of configmap deleted", []string{})
+	featureflagDeletedCount = newCounter("featured_operator", "featureflag", "deleted", "Total number of featureflags deleted and cleaned up", []string{})
+	configmapDriftCount     = newCounter("featured_operator", "featureflag", "configmap_drift", "Total number of configmap edits made outside of the operator", []string{"policy"})
+	secretCreatedCount      = newCounter("featured_operator", "featureflag", "secret_created", "Total number of secret created", []string{})
+	secretUpdatedCount      = newCounter("featured_operator", "featureflag", "secret_updated", "Total number of secret updated", []string{})
+	secretDeletedCount      = newCounter("featured_operator", "featureflag", "secret_deleted", "Total number of secret deleted", []string{})
+	publisherPublishedCount = newCounter("featured_operator", "featureflag", "publisher_published", "Total number of payloads written by publishers", []string{"type"})
+	publisherFailedCount    = newCounter("featured_operator", "featureflag", "publisher_failed", "Total number of payloads publishers failed to publish", []string{"type"})
+	appPatchedCount         = newCounter("featured_operator", "featureflag", "app_patched", "Total number of apps patched to inject or remove flags", []string{"kind"})
 )
 
 // RegisterMetrics registers the featurecontroller CRUD metrics.
 func RegisterMetrics() {
 	prometheus.MustRegister(configmapTotalCount)
 	prometheus.MustRegister(configmapCreatedCount)
+	prometheus.MustRegister(configmapUpdatedCount)
 	prometheus.MustRegister(configmapDeletedCount)
+	prometheus.MustRegister(featureflagDeletedCount)
+	prometheus.MustRegister(configmapDriftCount)
+	prometheus.MustRegister(secretCreatedCount)
+	prometheus.MustRegister(secretUpdatedCount)
+	prometheus.MustRegister(secretDeletedCount)
+	prometheus.MustRegister(publisherPublishedCount)
+	prometheus.MustRegister(publisherFailedCount)
+	prometheus.MustRegister(appPatchedCount)
 }
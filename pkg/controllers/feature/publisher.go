@@ -0,0 +1,334 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+const (
+	// PublisherFile is the type of the publishers writing the payload to a
+	// file
+	PublisherFile = "File"
+	// PublisherHTTP is the type of the publishers sending the payload to a
+	// URL
+	PublisherHTTP = "HTTP"
+)
+
+const (
+	// ErrPublishFailed is used as part of the Event 'reason' when a
+	// publisher of a FeatureFlag fails to publish its payload
+	ErrPublishFailed = "ErrPublishFailed"
+	// ErrPublisherDisabled is used as part of the Event 'reason' when a
+	// FeatureFlag has a publisher of a type not enabled on the operator
+	ErrPublisherDisabled = "ErrPublisherDisabled"
+
+	// MessagePublishFailed is the message used for Events when a publisher
+	// of a FeatureFlag fails to publish its payload
+	MessagePublishFailed = "Publisher %q failed: %s"
+	// MessagePublishersFailed is the message used for the Ready condition of
+	// a FeatureFlag whose publishers failed
+	MessagePublishersFailed = "Publishers %s failed"
+	// MessagePublisherDisabled is the message used for the status of a
+	// publisher of a type not enabled on the operator
+	MessagePublisherDisabled = "%s publishers are not enabled on the operator"
+)
+
+// Publisher publishes the payload of FeatureFlags to one kind of target:
+// the ConfigMap or Secret of their spec, or the file or URL of one of their
+// additional publishers.
+type Publisher interface {
+	// Publish publishes the payload of the snapshot to the target of its
+	// FeatureFlag, or to sink when not nil. A failure that needs a fix on
+	// the FeatureFlag or the target is described by a publication with a
+	// reason other than SuccessSynced. The error, if any, is returned for
+	// the FeatureFlag to be retried.
+	Publish(snapshot *snapshot, sink *samplev1alpha1.Publisher) (*publication, error)
+	// Unpublish removes the payload of a FeatureFlag being deleted from the
+	// target of its spec, or from sink when not nil.
+	Unpublish(key string, featureflag *samplev1alpha1.FeatureFlag, sink *samplev1alpha1.Publisher) error
+}
+
+// snapshot is the resolved state of a FeatureFlag handed to Publishers.
+type snapshot struct {
+	// featureflag is the FeatureFlag as stored.
+	featureflag *samplev1alpha1.FeatureFlag
+	// effective is the FeatureFlag with its scheduled changes and rollout
+	// plan applied, which is what is published.
+	effective *samplev1alpha1.FeatureFlag
+	// variation is served to contexts matching no rule.
+	variation string
+	segments  []*samplev1alpha1.FeatureSegment
+}
+
+// render returns the key the payload is published under and the payload.
+func (s *snapshot) render() (string, string, error) {
+	return renderPayload(s.effective, s.variation, s.segments)
+}
+
+// publication is the outcome of publishing a FeatureFlag to one target. A
+// nil publication leaves the status of the target as it is.
+type publication struct {
+	// reason is SuccessSynced when the target holds the current payload.
+	reason  string
+	message string
+	// contentHash is the hash of the payload the target holds, if reported.
+	contentHash string
+	// conflict reports that another FeatureFlag publishes the same key.
+	conflict bool
+}
+
+// published returns the publication of a target holding content.
+func published(message, content string) *publication {
+	return &publication{reason: SuccessSynced, message: message, contentHash: contentHash(content)}
+}
+
+// configMapPublisher publishes FeatureFlags to the ConfigMap of their spec.
+type configMapPublisher struct {
+	c *FeatureController
+}
+
+func (p *configMapPublisher) Publish(snapshot *snapshot, _ *samplev1alpha1.Publisher) (*publication, error) {
+	return p.c.publishConfigMap(snapshot)
+}
+
+func (p *configMapPublisher) Unpublish(key string, featureflag *samplev1alpha1.FeatureFlag, _ *samplev1alpha1.Publisher) error {
+	return p.c.finalizeConfigMap(key, featureflag)
+}
+
+// secretPublisher publishes FeatureFlags to the Secret of their spec.
+type secretPublisher struct {
+	c *FeatureController
+}
+
+func (p *secretPublisher) Publish(snapshot *snapshot, _ *samplev1alpha1.Publisher) (*publication, error) {
+	return p.c.publishSecret(snapshot)
+}
+
+func (p *secretPublisher) Unpublish(key string, featureflag *samplev1alpha1.FeatureFlag, _ *samplev1alpha1.Publisher) error {
+	return p.c.finalizeSecret(key, featureflag)
+}
+
+// publishTarget returns the PublishTarget of the FeatureFlag, which is a
+// ConfigMap unless set.
+func publishTarget(featureflag *samplev1alpha1.FeatureFlag) samplev1alpha1.PublishTarget {
+	if featureflag.Spec.Target == "" {
+		return samplev1alpha1.PublishTargetConfigMap
+	}
+	return featureflag.Spec.Target
+}
+
+// syncedConditionType returns the condition reporting whether the target
+// holds the current payload.
+func syncedConditionType(target samplev1alpha1.PublishTarget) samplev1alpha1.FeatureFlagConditionType {
+	if target == samplev1alpha1.PublishTargetSecret {
+		return samplev1alpha1.FeatureFlagSecretSynced
+	}
+	return samplev1alpha1.FeatureFlagConfigMapSynced
+}
+
+// publisherType returns the type of the publisher of a sink.
+func publisherType(sink *samplev1alpha1.Publisher) string {
+	if sink.HTTP != nil {
+		return PublisherHTTP
+	}
+	return PublisherFile
+}
+
+// getPublisherStatus returns the status of the named publisher, or nil.
+func getPublisherStatus(status *samplev1alpha1.FeatureFlagStatus, name string) *samplev1alpha1.PublisherStatus {
+	for i := range status.Publishers {
+		if status.Publishers[i].Name == name {
+			return &status.Publishers[i]
+		}
+	}
+	return nil
+}
+
+// publishFeatureFlag publishes the snapshot to the target of the FeatureFlag
+// and to each of its publishers, injects it into the apps it selects, then
+// writes the outcome to its status. A failing target does not hold back the
+// others; their errors are returned together so the FeatureFlag is retried.
+// Once the target holds the current payload, the applied scheduled changes
+// and the rollout plan are recorded and the FeatureFlag is requeued for its
+// next change or rollout step.
+func (c *FeatureController) publishFeatureFlag(key string, snapshot *snapshot, applied []samplev1alpha1.ScheduledChange, rolloutPlan *samplev1alpha1.RolloutPlanStatus, now, next time.Time) error {
+	featureflag := snapshot.featureflag
+	target := publishTarget(featureflag)
+	var errs []error
+
+	result, err := c.publishers[string(target)].Publish(snapshot, nil)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	// There is nothing to report when the target was left as it was, e.g.
+	// after a transient error, and there are no publishers.
+	if result == nil && len(featureflag.Spec.Publishers) == 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	synced := result != nil && result.reason == SuccessSynced
+
+	status := featureflag.Status.DeepCopy()
+	if synced {
+		setAppliedChanges(status, applied, metav1.NewTime(now))
+		status.RolloutPlan = rolloutPlan
+		status.ContentHash = result.contentHash
+		status.Overrides = c.overriddenClusterFeatureFlag(featureflag)
+	}
+	var failed []string
+	status.Publishers, failed, err = c.publishSinks(snapshot, now)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	// Apps are only injected once the payload they may reference is
+	// published.
+	var injection *publication
+	if synced {
+		if injection, err = c.injectApps(snapshot); err != nil {
+			errs = append(errs, err)
+		}
+		if featureflag.Spec.AppSelector == nil {
+			removeCondition(status, samplev1alpha1.FeatureFlagInjected)
+		}
+	}
+	injectionFailed := injection != nil && injection.reason != SuccessSynced
+
+	conditions := []samplev1alpha1.FeatureFlagCondition{
+		newCondition(samplev1alpha1.FeatureFlagValid, corev1.ConditionTrue, SuccessSynced, MessageValid),
+	}
+	switch {
+	case result == nil:
+		// The target is left as it was, e.g. after a transient error, so is
+		// its condition.
+	case synced:
+		conditions = append(conditions, newCondition(syncedConditionType(target), corev1.ConditionTrue, SuccessSynced, result.message))
+	default:
+		conditions = append(conditions,
+			newCondition(syncedConditionType(target), corev1.ConditionFalse, result.reason, result.message),
+			newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionFalse, result.reason, result.message))
+	}
+	switch {
+	case injectionFailed:
+		conditions = append(conditions, newCondition(samplev1alpha1.FeatureFlagInjected, corev1.ConditionFalse, injection.reason, injection.message))
+	case injection != nil:
+		conditions = append(conditions, newCondition(samplev1alpha1.FeatureFlagInjected, corev1.ConditionTrue, SuccessSynced, injection.message))
+	}
+	if len(failed) > 0 && (result == nil || synced) {
+		msg := fmt.Sprintf(MessagePublishersFailed, strings.Join(failed, ", "))
+		conditions = append(conditions, newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionFalse, ErrPublishFailed, msg))
+	} else if injectionFailed {
+		conditions = append(conditions, newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionFalse, injection.reason, injection.message))
+	} else if synced {
+		conditions = append(conditions, newCondition(samplev1alpha1.FeatureFlagReady, corev1.ConditionTrue, SuccessSynced, MessageResourceSynced))
+	}
+	// The Conflict condition is only reported once a conflict occurred.
+	if result != nil && result.conflict {
+		conditions = append(conditions, newCondition(samplev1alpha1.FeatureFlagConflict, corev1.ConditionTrue, result.reason, result.message))
+	} else if synced && getCondition(status, samplev1alpha1.FeatureFlagConflict) != nil {
+		conditions = append(conditions, newCondition(samplev1alpha1.FeatureFlagConflict, corev1.ConditionFalse, SuccessSynced, MessageNoConflict))
+	}
+	if err := c.updateFeatureFlagStatus(featureflag, status, conditions...); err != nil {
+		return err
+	}
+
+	if synced && len(failed) == 0 && !injectionFailed {
+		c.recorder.Event(featureflag, corev1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
+	}
+
+	// Wake up exactly when the next scheduled change or rollout step is due
+	// rather than waiting for a resync.
+	if synced && !next.IsZero() {
+		klog.V(4).Infof("FeatureFlag %s has a scheduled change or rollout step due at %s", key, next)
+		c.workqueue.AddAfter(key, next.Sub(now))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// publishSinks publishes the snapshot with each of the publishers of its
+// FeatureFlag and returns their status, along with the names of those that
+// failed and their errors. Each failure is reported with a Warning event. The
+// hash and time of the last payload published are kept on failure, so the
+// status tells which payload the target still holds.
+func (c *FeatureController) publishSinks(snapshot *snapshot, now time.Time) ([]samplev1alpha1.PublisherStatus, []string, error) {
+	featureflag := snapshot.featureflag
+	var statuses []samplev1alpha1.PublisherStatus
+	var failed []string
+	var errs []error
+	for i := range featureflag.Spec.Publishers {
+		sink := &featureflag.Spec.Publishers[i]
+		status := samplev1alpha1.PublisherStatus{Name: sink.Name}
+		if last := getPublisherStatus(&featureflag.Status, sink.Name); last != nil {
+			status = *last.DeepCopy()
+		}
+
+		var result *publication
+		var err error
+		if publisher, ok := c.publishers[publisherType(sink)]; ok {
+			result, err = publisher.Publish(snapshot, sink)
+		} else {
+			result = &publication{reason: ErrPublisherDisabled, message: fmt.Sprintf(MessagePublisherDisabled, publisherType(sink))}
+		}
+		if result == nil {
+			result = &publication{reason: ErrPublishFailed, message: err.Error()}
+		}
+
+		if result.reason == SuccessSynced {
+			if status.Published != corev1.ConditionTrue || status.ContentHash != result.contentHash || status.LastPublishTime == nil {
+				status.LastPublishTime = &metav1.Time{Time: now}
+			}
+			status.Published = corev1.ConditionTrue
+			status.ContentHash = result.contentHash
+		} else {
+			klog.V(4).Infof("FeatureFlag %s/%s publisher %s failed: %s", featureflag.Namespace, featureflag.Name, sink.Name, result.message)
+			c.recorder.Event(featureflag, corev1.EventTypeWarning, result.reason, fmt.Sprintf(MessagePublishFailed, sink.Name, result.message))
+			publisherFailedCount.WithLabelValues(publisherType(sink)).Inc()
+			status.Published = corev1.ConditionFalse
+			failed = append(failed, fmt.Sprintf("%q", sink.Name))
+		}
+		status.Reason = result.reason
+		status.Message = result.message
+		statuses = append(statuses, status)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("publisher %q: %v", sink.Name, err))
+		}
+	}
+	return statuses, failed, utilerrors.NewAggregate(errs)
+}
+
+// unpublishSinks removes the payload of a FeatureFlag being deleted with each
+// of its publishers. Publishers of a type no longer enabled are skipped.
+func (c *FeatureController) unpublishSinks(key string, featureflag *samplev1alpha1.FeatureFlag) error {
+	var errs []error
+	for i := range featureflag.Spec.Publishers {
+		sink := &featureflag.Spec.Publishers[i]
+		publisher, ok := c.publishers[publisherType(sink)]
+		if !ok {
+			continue
+		}
+		if err := publisher.Unpublish(key, featureflag, sink); err != nil {
+			errs = append(errs, fmt.Errorf("publisher %q: %v", sink.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
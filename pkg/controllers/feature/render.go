@@ -0,0 +1,274 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"sigs.k8s.io/yaml"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// PayloadSchemaVersion identifies the layout of the payload published for a
+// FeatureFlag (see docs/payload.md). It changes whenever a field is removed or
+// changes meaning; adding optional fields does not change it.
+const PayloadSchemaVersion = "featured.io/v1"
+
+// payloadExtensions maps each format to the extension of its ConfigMap key.
+var payloadExtensions = map[featurev1alpha1.PayloadFormat]string{
+	featurev1alpha1.PayloadFormatJSON:       "json",
+	featurev1alpha1.PayloadFormatYAML:       "yaml",
+	featurev1alpha1.PayloadFormatDotenv:     "env",
+	featurev1alpha1.PayloadFormatProperties: "properties",
+}
+
+// FlagPayload is the resolved state of a FeatureFlag as published to
+// applications. Variation and Value are served to contexts that match no
+// rule; Variations, Prerequisites, Rules, DefaultRollout and Segments let
+// SDKs evaluate the flag for a context.
+type FlagPayload struct {
+	SchemaVersion  string                         `json:"schemaVersion"`
+	Flag           string                         `json:"flag"`
+	Type           featurev1alpha1.FlagType       `json:"type"`
+	Enabled        bool                           `json:"enabled"`
+	Variation      string                         `json:"variation"`
+	Value          json.RawMessage                `json:"value"`
+	Variations     map[string]json.RawMessage     `json:"variations"`
+	Prerequisites  []featurev1alpha1.Prerequisite `json:"prerequisites,omitempty"`
+	Rules          []featurev1alpha1.Rule         `json:"rules,omitempty"`
+	DefaultRollout *featurev1alpha1.Rollout       `json:"defaultRollout,omitempty"`
+	// Segments holds the FeatureSegments referenced by Rules, by name.
+	Segments map[string]featurev1alpha1.FeatureSegmentSpec `json:"segments,omitempty"`
+}
+
+// newFlagPayload builds the payload of the FeatureFlag serving variation to
+// contexts that match no rule. The spec must already have passed validation.
+func newFlagPayload(featureflag *featurev1alpha1.FeatureFlag, variation string, segments []*featurev1alpha1.FeatureSegment) (*FlagPayload, error) {
+	spec := featureflag.Spec
+	name := variation
+
+	variations := map[string]json.RawMessage{}
+	for _, variation := range spec.Variations {
+		value, err := encodeValue(spec.Type, variation.Value)
+		if err != nil {
+			return nil, err
+		}
+		variations[variation.Name] = value
+	}
+
+	value, ok := variations[name]
+	if !ok {
+		return nil, fmt.Errorf("variation %q not found", name)
+	}
+
+	var segmentSpecs map[string]featurev1alpha1.FeatureSegmentSpec
+	for _, segment := range segments {
+		if segmentSpecs == nil {
+			segmentSpecs = map[string]featurev1alpha1.FeatureSegmentSpec{}
+		}
+		segmentSpecs[segment.Name] = segment.Spec
+	}
+
+	return &FlagPayload{
+		SchemaVersion:  PayloadSchemaVersion,
+		Flag:           featureflag.Name,
+		Type:           spec.Type,
+		Enabled:        spec.Enabled,
+		Variation:      name,
+		Value:          value,
+		Variations:     variations,
+		Prerequisites:  spec.Prerequisites,
+		Rules:          spec.Rules,
+		DefaultRollout: spec.DefaultRollout,
+		Segments:       segmentSpecs,
+	}, nil
+}
+
+// encodeValue converts the string encoding of a variation into JSON.
+func encodeValue(t featurev1alpha1.FlagType, value string) (json.RawMessage, error) {
+	switch t {
+	case featurev1alpha1.FlagTypeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(b)
+	case featurev1alpha1.FlagTypeNumber:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(f)
+	case featurev1alpha1.FlagTypeJSON:
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(value)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return json.Marshal(value)
+	}
+}
+
+// payloadFormat returns the format of the FeatureFlag, defaulting to JSON.
+func payloadFormat(featureflag *featurev1alpha1.FeatureFlag) featurev1alpha1.PayloadFormat {
+	if featureflag.Spec.Format == "" {
+		return featurev1alpha1.PayloadFormatJSON
+	}
+	return featureflag.Spec.Format
+}
+
+// payloadKey is the ConfigMap key the FeatureFlag is published under, e.g.
+// "new-checkout.json".
+func payloadKey(featureflag *featurev1alpha1.FeatureFlag) string {
+	return featureflag.Name + "." + payloadExtensions[payloadFormat(featureflag)]
+}
+
+// renderPayload serializes the resolved state of the FeatureFlag in its
+// selected format and returns it along with the ConfigMap key to store it under.
+func renderPayload(featureflag *featurev1alpha1.FeatureFlag, variation string, segments []*featurev1alpha1.FeatureSegment) (string, string, error) {
+	payload, err := newFlagPayload(featureflag, variation, segments)
+	if err != nil {
+		return "", "", err
+	}
+
+	var content []byte
+	switch format := payloadFormat(featureflag); format {
+	case featurev1alpha1.PayloadFormatJSON:
+		content, err = json.MarshalIndent(payload, "", "  ")
+		content = append(content, '\n')
+	case featurev1alpha1.PayloadFormatYAML:
+		content, err = yaml.Marshal(payload)
+	case featurev1alpha1.PayloadFormatDotenv:
+		content = renderDotenv(payload)
+	case featurev1alpha1.PayloadFormatProperties:
+		content = renderProperties(payload)
+	default:
+		err = fmt.Errorf("unsupported payload format %q", format)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return payloadKey(featureflag), string(content), nil
+}
+
+// contentHash returns the hex encoded SHA-256 of published content.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// renderDotenv writes the payload as KEY=value lines. String and JSON values
+// are double quoted; booleans and numbers are written bare.
+func renderDotenv(payload *FlagPayload) []byte {
+	prefix := envVarName(payload.Flag)
+	value := scalarValue(payload)
+	if payload.Type == featurev1alpha1.FlagTypeString || payload.Type == featurev1alpha1.FlagTypeJSON {
+		value = strconv.Quote(value)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "FEATURED_SCHEMA_VERSION=%s\n", PayloadSchemaVersion)
+	fmt.Fprintf(&buf, "%s=%s\n", prefix, value)
+	fmt.Fprintf(&buf, "%s_ENABLED=%t\n", prefix, payload.Enabled)
+	fmt.Fprintf(&buf, "%s_VARIATION=%s\n", prefix, strconv.Quote(payload.Variation))
+	return buf.Bytes()
+}
+
+// renderProperties writes the payload as a Java properties file.
+func renderProperties(payload *FlagPayload) []byte {
+	prefix := "feature." + payload.Flag
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "featured.schemaVersion=%s\n", escapeProperty(PayloadSchemaVersion))
+	fmt.Fprintf(&buf, "%s=%s\n", prefix, escapeProperty(scalarValue(payload)))
+	fmt.Fprintf(&buf, "%s.enabled=%t\n", prefix, payload.Enabled)
+	fmt.Fprintf(&buf, "%s.variation=%s\n", prefix, escapeProperty(payload.Variation))
+	return buf.Bytes()
+}
+
+// scalarValue returns the payload value without JSON string quoting.
+func scalarValue(payload *FlagPayload) string {
+	if payload.Type == featurev1alpha1.FlagTypeString {
+		var s string
+		if err := json.Unmarshal(payload.Value, &s); err == nil {
+			return s
+		}
+	}
+	return string(payload.Value)
+}
+
+// envVarName converts a flag name into an environment variable name, e.g.
+// "new-checkout" becomes "FEATURE_NEW_CHECKOUT".
+func envVarName(flag string) string {
+	return "FEATURE_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, flag)
+}
+
+// escapeProperty escapes a value as described by java.util.Properties.load.
+func escapeProperty(value string) string {
+	var buf strings.Builder
+	for i, r := range value {
+		switch r {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case ' ':
+			// Only leading whitespace is stripped by the parser.
+			if i == 0 {
+				buf.WriteString(`\ `)
+			} else {
+				buf.WriteRune(r)
+			}
+		case '=', ':', '#', '!':
+			buf.WriteRune('\\')
+			buf.WriteRune(r)
+		default:
+			if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(&buf, `\u%04x\u%04x`, r1, r2)
+			} else if r > 0x7e {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	return buf.String()
+}
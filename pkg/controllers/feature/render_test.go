@@ -0,0 +1,182 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// defaultVariation returns the variation a FeatureFlag without prerequisites
+// serves to contexts matching no rule.
+func defaultVariation(featureflag *featurecontroller.FeatureFlag) string {
+	if featureflag.Spec.Enabled {
+		return featureflag.Spec.DefaultVariation
+	}
+	return featureflag.Spec.OffVariation
+}
+
+// TestRenderPayload tests the rendering of every payload format
+func TestRenderPayload(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(featureflag *featurecontroller.FeatureFlag)
+		expKey     string
+		expContent string
+	}{
+		{
+			name:   "An enabled boolean flag should default to json and serve the default variation.",
+			mutate: func(featureflag *featurecontroller.FeatureFlag) {},
+			expKey: "new-checkout.json",
+			expContent: `{
+  "schemaVersion": "featured.io/v1",
+  "flag": "new-checkout",
+  "type": "boolean",
+  "enabled": true,
+  "variation": "on",
+  "value": true,
+  "variations": {
+    "off": false,
+    "on": true
+  }
+}
+`,
+		},
+		{
+			name: "A disabled flag should serve the off variation.",
+			mutate: func(featureflag *featurecontroller.FeatureFlag) {
+				featureflag.Spec.Enabled = false
+				featureflag.Spec.Format = featurecontroller.PayloadFormatYAML
+			},
+			expKey: "new-checkout.yaml",
+			expContent: `enabled: false
+flag: new-checkout
+schemaVersion: featured.io/v1
+type: boolean
+value: false
+variation: "off"
+variations:
+  "off": false
+  "on": true
+`,
+		},
+		{
+			name: "A json flag should be rendered as nested yaml.",
+			mutate: func(featureflag *featurecontroller.FeatureFlag) {
+				featureflag.Spec.Type = featurecontroller.FlagTypeJSON
+				featureflag.Spec.Variations[0].Value = `{ "color": "blue" }`
+				featureflag.Spec.Rules = []featurecontroller.Rule{{
+					Clauses: []featurecontroller.Clause{
+						{Attribute: "country", Operator: featurecontroller.OperatorIn, Values: []string{"GB"}},
+					},
+					Variation: "off",
+				}}
+				featureflag.Spec.Format = featurecontroller.PayloadFormatYAML
+			},
+			expKey: "new-checkout.yaml",
+			expContent: `enabled: true
+flag: new-checkout
+rules:
+- clauses:
+  - attribute: country
+    operator: in
+    values:
+    - GB
+  variation: "off"
+schemaVersion: featured.io/v1
+type: json
+value:
+  color: blue
+variation: "on"
+variations:
+  "off": false
+  "on":
+    color: blue
+`,
+		},
+		{
+			name: "A number flag should be rendered as dotenv.",
+			mutate: func(featureflag *featurecontroller.FeatureFlag) {
+				featureflag.Spec.Type = featurecontroller.FlagTypeNumber
+				featureflag.Spec.Variations[0].Value = "2.50"
+				featureflag.Spec.Variations[1].Value = "0"
+				featureflag.Spec.Format = featurecontroller.PayloadFormatDotenv
+			},
+			expKey: "new-checkout.env",
+			expContent: `FEATURED_SCHEMA_VERSION=featured.io/v1
+FEATURE_NEW_CHECKOUT=2.5
+FEATURE_NEW_CHECKOUT_ENABLED=true
+FEATURE_NEW_CHECKOUT_VARIATION="on"
+`,
+		},
+		{
+			name: "A string flag should be quoted in dotenv.",
+			mutate: func(featureflag *featurecontroller.FeatureFlag) {
+				featureflag.Spec.Type = featurecontroller.FlagTypeString
+				featureflag.Spec.Variations[0].Value = `say "hi"`
+				featureflag.Spec.Format = featurecontroller.PayloadFormatDotenv
+			},
+			expKey: "new-checkout.env",
+			expContent: `FEATURED_SCHEMA_VERSION=featured.io/v1
+FEATURE_NEW_CHECKOUT="say \"hi\""
+FEATURE_NEW_CHECKOUT_ENABLED=true
+FEATURE_NEW_CHECKOUT_VARIATION="on"
+`,
+		},
+		{
+			name: "A string flag should be escaped in properties.",
+			mutate: func(featureflag *featurecontroller.FeatureFlag) {
+				featureflag.Spec.Type = featurecontroller.FlagTypeString
+				featureflag.Spec.Variations[0].Value = " a=b:c\né"
+				featureflag.Spec.Format = featurecontroller.PayloadFormatProperties
+			},
+			expKey: "new-checkout.properties",
+			expContent: `featured.schemaVersion=featured.io/v1
+feature.new-checkout=\ a\=b\:c\n\u00e9
+feature.new-checkout.enabled=true
+feature.new-checkout.variation=on
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			featureflag := newFeatureFlag("new-checkout")
+			test.mutate(featureflag)
+
+			key, content, err := renderPayload(featureflag, defaultVariation(featureflag), nil)
+
+			require.NoError(t, err)
+			require.Equal(t, test.expKey, key)
+			require.Equal(t, test.expContent, content)
+		})
+	}
+}
+
+// TestSetPayload tests that unchanged content is not reported as a change
+func TestSetPayload(t *testing.T) {
+	featureflag := newFeatureFlag("test")
+	configmap := newConfigMap(featureflag)
+
+	changed, err := setPayload(configmap, featureflag, defaultVariation(featureflag), nil)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	changed, err = setPayload(configmap, featureflag, defaultVariation(featureflag), nil)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	featureflag.Spec.Format = featurecontroller.PayloadFormatProperties
+	changed, err = setPayload(configmap, featureflag, defaultVariation(featureflag), nil)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Len(t, configmap.Data, 1)
+	require.Contains(t, configmap.Data, "test.properties")
+}
+
+// TestEnvVarName tests the conversion of flag names to environment variables
+func TestEnvVarName(t *testing.T) {
+	require.Equal(t, "FEATURE_NEW_CHECKOUT", envVarName("new-checkout"))
+	require.Equal(t, "FEATURE_V2_API_ENABLED", envVarName("v2.api.enabled"))
+}
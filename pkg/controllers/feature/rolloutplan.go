@@ -0,0 +1,158 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// rolloutPlanControl returns whether the rollout plan of the FeatureFlag is
+// paused and aborted. The RolloutPlanAnnotation overrides the spec.
+func rolloutPlanControl(featureflag *featurev1alpha1.FeatureFlag) (paused, aborted bool) {
+	plan := featureflag.Spec.RolloutPlan
+	switch featureflag.Annotations[featurev1alpha1.RolloutPlanAnnotation] {
+	case featurev1alpha1.RolloutPlanAbort:
+		return false, true
+	case featurev1alpha1.RolloutPlanPause:
+		return true, plan.Aborted
+	case featurev1alpha1.RolloutPlanResume:
+		return false, plan.Aborted
+	default:
+		return plan.Paused, plan.Aborted
+	}
+}
+
+// rolloutPlanHash returns the hash of what the rollout plan serves at each
+// step, leaving out Paused and Aborted, which only control its progress.
+func rolloutPlanHash(plan *featurev1alpha1.RolloutPlan) string {
+	plan = plan.DeepCopy()
+	plan.Paused, plan.Aborted = false, false
+	content, _ := json.Marshal(plan)
+	return contentHash(string(content))
+}
+
+// advanceRolloutPlan returns the progress of the rollout plan of the
+// FeatureFlag at now, starting from its recorded status. Steps that ended
+// while the controller was down are moved through in order. A plan whose
+// variation, baseline, bucketing or steps changed restarts from its first
+// step. It returns nil if the FeatureFlag has no plan.
+func advanceRolloutPlan(featureflag *featurev1alpha1.FeatureFlag, now time.Time) *featurev1alpha1.RolloutPlanStatus {
+	plan := featureflag.Spec.RolloutPlan
+	if plan == nil {
+		return nil
+	}
+
+	metaNow := metav1.NewTime(now)
+	hash := rolloutPlanHash(plan)
+	start := &featurev1alpha1.RolloutPlanStatus{
+		Phase:         featurev1alpha1.RolloutPlanProgressing,
+		PlanHash:      hash,
+		StepStartTime: metaNow,
+	}
+
+	status := featureflag.Status.RolloutPlan.DeepCopy()
+	paused, aborted := rolloutPlanControl(featureflag)
+	switch {
+	case aborted:
+		if status == nil {
+			status = start
+		}
+		status.Phase = featurev1alpha1.RolloutPlanAborted
+		status.PlanHash = hash
+		status.PausedTime = nil
+		status.NextTransitionTime = nil
+		return status
+	case status == nil, status.Phase == featurev1alpha1.RolloutPlanAborted:
+		// Clearing an abort restarts the plan
+		status = start
+	case status.PlanHash != hash:
+		// The steps of the progress recorded belong to another plan
+		status = start
+	}
+
+	last := int32(len(plan.Steps) - 1)
+	if status.CurrentStep > last {
+		status.CurrentStep = last
+	}
+	defer func() {
+		status.CurrentWeight = plan.Steps[status.CurrentStep].Weight
+	}()
+
+	if status.Phase == featurev1alpha1.RolloutPlanCompleted {
+		return status
+	}
+
+	if paused {
+		if status.PausedTime == nil {
+			status.PausedTime = &metaNow
+		}
+		status.Phase = featurev1alpha1.RolloutPlanPaused
+		status.NextTransitionTime = nil
+		return status
+	}
+
+	// Time spent paused does not count towards the current step
+	if status.PausedTime != nil {
+		status.StepStartTime = metav1.NewTime(status.StepStartTime.Add(now.Sub(status.PausedTime.Time)))
+		status.PausedTime = nil
+	}
+
+	status.Phase = featurev1alpha1.RolloutPlanProgressing
+	for status.CurrentStep < last {
+		end := metav1.NewTime(status.StepStartTime.Add(plan.Steps[status.CurrentStep].Duration.Duration))
+		if end.After(now) {
+			status.NextTransitionTime = &end
+			return status
+		}
+		status.CurrentStep++
+		status.StepStartTime = end
+	}
+
+	status.Phase = featurev1alpha1.RolloutPlanCompleted
+	status.NextTransitionTime = nil
+	return status
+}
+
+// applyRolloutPlan sets the spec of the FeatureFlag to what its rollout plan
+// serves at the given progress: the current step as the default rollout, or
+// the off variation once aborted.
+func applyRolloutPlan(featureflag *featurev1alpha1.FeatureFlag, status *featurev1alpha1.RolloutPlanStatus) {
+	plan := featureflag.Spec.RolloutPlan
+	if plan == nil || status == nil {
+		return
+	}
+
+	if status.Phase == featurev1alpha1.RolloutPlanAborted {
+		featureflag.Spec.Enabled = false
+		return
+	}
+
+	baseline := plan.Baseline
+	if baseline == "" {
+		baseline = featureflag.Spec.OffVariation
+	}
+	featureflag.Spec.DefaultRollout = &featurev1alpha1.Rollout{
+		Variations: []featurev1alpha1.WeightedVariation{
+			{Variation: plan.Variation, Weight: status.CurrentWeight},
+			{Variation: baseline, Weight: 100 - status.CurrentWeight},
+		},
+		BucketBy: plan.BucketBy,
+	}
+}
@@ -0,0 +1,200 @@
+package feature
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// withRolloutPlan returns a copy of the FeatureFlag rolling out "on" to 1%, 5%, 25% and 100% at 30m intervals.
+func withRolloutPlan(featureflag *featurecontroller.FeatureFlag) *featurecontroller.FeatureFlag {
+	featureflag = featureflag.DeepCopy()
+	step := metav1.Duration{Duration: 30 * time.Minute}
+	featureflag.Spec.RolloutPlan = &featurecontroller.RolloutPlan{
+		Variation: "on",
+		Steps: []featurecontroller.RolloutStep{
+			{Weight: 1, Duration: step},
+			{Weight: 5, Duration: step},
+			{Weight: 25, Duration: step},
+			{Weight: 100},
+		},
+	}
+	return featureflag
+}
+
+// advance advances the plan of the FeatureFlag to now and records the progress in its status.
+func advance(featureflag *featurecontroller.FeatureFlag, now time.Time) *featurecontroller.RolloutPlanStatus {
+	featureflag.Status.RolloutPlan = advanceRolloutPlan(featureflag, now)
+	return featureflag.Status.RolloutPlan
+}
+
+func timePtr(t time.Time) *metav1.Time {
+	mt := metav1.NewTime(t)
+	return &mt
+}
+
+// TestRolloutPlanProgress tests that the plan moves through its steps as they end, including steps missed while down
+func TestRolloutPlanProgress(t *testing.T) {
+	featureflag := withRolloutPlan(newFeatureFlag("test"))
+
+	require.Nil(t, advanceRolloutPlan(newFeatureFlag("test"), testNow))
+
+	require.Equal(t, &featurecontroller.RolloutPlanStatus{
+		Phase:              featurecontroller.RolloutPlanProgressing,
+		PlanHash:           rolloutPlanHash(featureflag.Spec.RolloutPlan),
+		CurrentStep:        0,
+		CurrentWeight:      1,
+		StepStartTime:      metav1.NewTime(testNow),
+		NextTransitionTime: timePtr(testNow.Add(30 * time.Minute)),
+	}, advance(featureflag, testNow))
+
+	status := advance(featureflag, testNow.Add(30*time.Minute))
+	require.Equal(t, int32(1), status.CurrentStep)
+	require.Equal(t, int32(5), status.CurrentWeight)
+	require.Equal(t, timePtr(testNow.Add(time.Hour)), status.NextTransitionTime)
+
+	// The controller was down for the next step, which keeps its schedule
+	status = advance(featureflag, testNow.Add(80*time.Minute))
+	require.Equal(t, int32(2), status.CurrentStep)
+	require.Equal(t, metav1.NewTime(testNow.Add(time.Hour)), status.StepStartTime)
+	require.Equal(t, timePtr(testNow.Add(90*time.Minute)), status.NextTransitionTime)
+
+	require.Equal(t, &featurecontroller.RolloutPlanStatus{
+		Phase:         featurecontroller.RolloutPlanCompleted,
+		PlanHash:      rolloutPlanHash(featureflag.Spec.RolloutPlan),
+		CurrentStep:   3,
+		CurrentWeight: 100,
+		StepStartTime: metav1.NewTime(testNow.Add(90 * time.Minute)),
+	}, advance(featureflag, testNow.Add(10*time.Hour)))
+}
+
+// TestRolloutPlanPause tests that time spent paused does not count towards the current step
+func TestRolloutPlanPause(t *testing.T) {
+	featureflag := withRolloutPlan(newFeatureFlag("test"))
+	advance(featureflag, testNow)
+
+	featureflag.Spec.RolloutPlan.Paused = true
+	status := advance(featureflag, testNow.Add(10*time.Minute))
+	require.Equal(t, featurecontroller.RolloutPlanPaused, status.Phase)
+	require.Equal(t, timePtr(testNow.Add(10*time.Minute)), status.PausedTime)
+	require.Nil(t, status.NextTransitionTime)
+
+	// Still paused an hour later
+	status = advance(featureflag, testNow.Add(70*time.Minute))
+	require.Equal(t, int32(0), status.CurrentStep)
+
+	// The annotation overrides the spec
+	featureflag.Annotations = map[string]string{featurecontroller.RolloutPlanAnnotation: featurecontroller.RolloutPlanResume}
+	status = advance(featureflag, testNow.Add(70*time.Minute))
+	require.Equal(t, featurecontroller.RolloutPlanProgressing, status.Phase)
+	require.Equal(t, int32(0), status.CurrentStep)
+	require.Nil(t, status.PausedTime)
+	require.Equal(t, timePtr(testNow.Add(90*time.Minute)), status.NextTransitionTime)
+
+	featureflag.Annotations[featurecontroller.RolloutPlanAnnotation] = featurecontroller.RolloutPlanPause
+	require.Equal(t, featurecontroller.RolloutPlanPaused, advance(featureflag, testNow.Add(80*time.Minute)).Phase)
+}
+
+// TestRolloutPlanAbort tests that an abort serves the off variation and clearing it restarts the plan
+func TestRolloutPlanAbort(t *testing.T) {
+	featureflag := withRolloutPlan(newFeatureFlag("test"))
+	advance(featureflag, testNow)
+	advance(featureflag, testNow.Add(45*time.Minute))
+
+	featureflag.Annotations = map[string]string{featurecontroller.RolloutPlanAnnotation: featurecontroller.RolloutPlanAbort}
+	status := advance(featureflag, testNow.Add(50*time.Minute))
+	require.Equal(t, featurecontroller.RolloutPlanAborted, status.Phase)
+	require.Nil(t, status.NextTransitionTime)
+
+	effective := featureflag.DeepCopy()
+	applyRolloutPlan(effective, status)
+	require.False(t, effective.Spec.Enabled)
+
+	delete(featureflag.Annotations, featurecontroller.RolloutPlanAnnotation)
+	require.Equal(t, &featurecontroller.RolloutPlanStatus{
+		Phase:              featurecontroller.RolloutPlanProgressing,
+		PlanHash:           rolloutPlanHash(featureflag.Spec.RolloutPlan),
+		CurrentStep:        0,
+		CurrentWeight:      1,
+		StepStartTime:      metav1.NewTime(testNow.Add(time.Hour)),
+		NextTransitionTime: timePtr(testNow.Add(90 * time.Minute)),
+	}, advance(featureflag, testNow.Add(time.Hour)))
+}
+
+// TestRolloutPlanChanged tests that editing the steps, variation or baseline of a running plan restarts it, but pausing it does not
+func TestRolloutPlanChanged(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(plan *featurecontroller.RolloutPlan)
+	}{
+		{
+			name: "fewer steps",
+			edit: func(plan *featurecontroller.RolloutPlan) { plan.Steps = plan.Steps[2:] },
+		},
+		{
+			name: "changed weight",
+			edit: func(plan *featurecontroller.RolloutPlan) { plan.Steps[0].Weight = 10 },
+		},
+		{
+			name: "changed variation",
+			edit: func(plan *featurecontroller.RolloutPlan) { plan.Variation = "off" },
+		},
+		{
+			name: "changed baseline",
+			edit: func(plan *featurecontroller.RolloutPlan) { plan.Baseline = "on" },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			featureflag := withRolloutPlan(newFeatureFlag("test"))
+			advance(featureflag, testNow)
+			require.Equal(t, int32(2), advance(featureflag, testNow.Add(70*time.Minute)).CurrentStep)
+
+			test.edit(featureflag.Spec.RolloutPlan)
+			require.Equal(t, &featurecontroller.RolloutPlanStatus{
+				Phase:              featurecontroller.RolloutPlanProgressing,
+				PlanHash:           rolloutPlanHash(featureflag.Spec.RolloutPlan),
+				CurrentStep:        0,
+				CurrentWeight:      featureflag.Spec.RolloutPlan.Steps[0].Weight,
+				StepStartTime:      metav1.NewTime(testNow.Add(80 * time.Minute)),
+				NextTransitionTime: timePtr(testNow.Add(110 * time.Minute)),
+			}, advance(featureflag, testNow.Add(80*time.Minute)))
+		})
+	}
+
+	featureflag := withRolloutPlan(newFeatureFlag("test"))
+	advance(featureflag, testNow)
+	advance(featureflag, testNow.Add(35*time.Minute))
+	featureflag.Spec.RolloutPlan.Paused = true
+	status := advance(featureflag, testNow.Add(40*time.Minute))
+	require.Equal(t, featurecontroller.RolloutPlanPaused, status.Phase)
+	require.Equal(t, int32(1), status.CurrentStep)
+
+	// Progress without the hash of its plan restarts the plan
+	featureflag.Spec.RolloutPlan.Paused = false
+	featureflag.Status.RolloutPlan.PlanHash = ""
+	status = advance(featureflag, testNow.Add(40*time.Minute))
+	require.Equal(t, int32(0), status.CurrentStep)
+	require.Equal(t, rolloutPlanHash(featureflag.Spec.RolloutPlan), status.PlanHash)
+}
+
+// TestApplyRolloutPlan tests that the current step is served as the default rollout against the baseline
+func TestApplyRolloutPlan(t *testing.T) {
+	featureflag := withRolloutPlan(newFeatureFlag("test"))
+	featureflag.Spec.RolloutPlan.BucketBy = "tenantId"
+
+	applyRolloutPlan(featureflag, &featurecontroller.RolloutPlanStatus{Phase: featurecontroller.RolloutPlanProgressing, CurrentStep: 2, CurrentWeight: 25})
+
+	require.True(t, featureflag.Spec.Enabled)
+	require.Equal(t, &featurecontroller.Rollout{
+		Variations: []featurecontroller.WeightedVariation{
+			{Variation: "on", Weight: 25},
+			{Variation: "off", Weight: 75},
+		},
+		BucketBy: "tenantId",
+	}, featureflag.Spec.DefaultRollout)
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// applySchedule returns a copy of the FeatureFlag with every scheduled change
+// due at now applied over the spec in order of At, along with the changes
+// applied and the time the next change is due, which is zero if there is
+// none. Changes missed while the controller was down are all applied at once.
+func applySchedule(featureflag *featurev1alpha1.FeatureFlag, now time.Time) (*featurev1alpha1.FeatureFlag, []featurev1alpha1.ScheduledChange, time.Time) {
+	changes := make([]featurev1alpha1.ScheduledChange, len(featureflag.Spec.Schedule))
+	copy(changes, featureflag.Spec.Schedule)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].At.Before(&changes[j].At)
+	})
+
+	effective := featureflag.DeepCopy()
+	var applied []featurev1alpha1.ScheduledChange
+	for _, change := range changes {
+		if change.At.Time.After(now) {
+			return effective, applied, change.At.Time
+		}
+
+		if change.Enabled != nil {
+			effective.Spec.Enabled = *change.Enabled
+		}
+		if change.DefaultVariation != "" {
+			effective.Spec.DefaultVariation = change.DefaultVariation
+		}
+		if change.OffVariation != "" {
+			effective.Spec.OffVariation = change.OffVariation
+		}
+		if change.DefaultRollout != nil {
+			effective.Spec.DefaultRollout = change.DefaultRollout.DeepCopy()
+		}
+		applied = append(applied, change)
+	}
+	return effective, applied, time.Time{}
+}
+
+// setAppliedChanges records the applied changes in the status. Changes that
+// were already recorded keep the time they were first applied.
+func setAppliedChanges(status *featurev1alpha1.FeatureFlagStatus, applied []featurev1alpha1.ScheduledChange, now metav1.Time) {
+	previous := map[string]featurev1alpha1.AppliedChange{}
+	for _, change := range status.AppliedChanges {
+		previous[change.Name] = change
+	}
+
+	var changes []featurev1alpha1.AppliedChange
+	for _, change := range applied {
+		appliedChange := featurev1alpha1.AppliedChange{Name: change.Name, At: change.At, AppliedTime: now}
+		if p, ok := previous[change.Name]; ok && p.At.Equal(&change.At) {
+			appliedChange.AppliedTime = p.AppliedTime
+		}
+		changes = append(changes, appliedChange)
+	}
+	status.AppliedChanges = changes
+}
@@ -0,0 +1,81 @@
+package feature
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+func newScheduledChange(name string, at time.Time, mutate func(change *featurecontroller.ScheduledChange)) featurecontroller.ScheduledChange {
+	change := featurecontroller.ScheduledChange{Name: name, At: metav1.NewTime(at)}
+	mutate(&change)
+	return change
+}
+
+func enable(enabled bool) func(change *featurecontroller.ScheduledChange) {
+	return func(change *featurecontroller.ScheduledChange) {
+		change.Enabled = &enabled
+	}
+}
+
+// TestApplySchedule tests that due changes are applied in time order and the next change is returned
+func TestApplySchedule(t *testing.T) {
+	featureflag := newFeatureFlag("test")
+	featureflag.Spec.Enabled = false
+	featureflag.Spec.Schedule = []featurecontroller.ScheduledChange{
+		newScheduledChange("rollback", testNow.Add(-time.Minute), enable(false)),
+		newScheduledChange("launch", testNow.Add(-time.Hour), enable(true)),
+		newScheduledChange("half", testNow.Add(-time.Hour), func(change *featurecontroller.ScheduledChange) {
+			change.DefaultRollout = &featurecontroller.Rollout{Variations: []featurecontroller.WeightedVariation{
+				{Variation: "on", Weight: 50}, {Variation: "off", Weight: 50},
+			}}
+		}),
+		newScheduledChange("relaunch", testNow.Add(time.Hour), enable(true)),
+		newScheduledChange("switch", testNow.Add(2*time.Hour), func(change *featurecontroller.ScheduledChange) {
+			change.DefaultVariation = "off"
+		}),
+	}
+
+	effective, applied, next := applySchedule(featureflag, testNow)
+
+	names := []string{}
+	for _, change := range applied {
+		names = append(names, change.Name)
+	}
+	require.Equal(t, []string{"launch", "half", "rollback"}, names)
+	require.False(t, effective.Spec.Enabled)
+	require.Equal(t, int32(50), effective.Spec.DefaultRollout.Variations[0].Weight)
+	require.Equal(t, testNow.Add(time.Hour), next)
+	require.False(t, featureflag.Spec.Enabled, "the FeatureFlag must not be modified")
+	require.Nil(t, featureflag.Spec.DefaultRollout, "the FeatureFlag must not be modified")
+
+	effective, applied, next = applySchedule(featureflag, testNow.Add(3*time.Hour))
+	require.Len(t, applied, 5)
+	require.True(t, effective.Spec.Enabled)
+	require.Equal(t, "off", effective.Spec.DefaultVariation)
+	require.True(t, next.IsZero())
+}
+
+// TestSetAppliedChanges tests that changes keep the time they were first applied
+func TestSetAppliedChanges(t *testing.T) {
+	launch := newScheduledChange("launch", testNow.Add(-time.Hour), enable(true))
+	rollback := newScheduledChange("rollback", testNow, enable(false))
+	status := &featurecontroller.FeatureFlagStatus{}
+	firstApplied := metav1.NewTime(testNow.Add(-time.Hour))
+
+	setAppliedChanges(status, []featurecontroller.ScheduledChange{launch}, firstApplied)
+	setAppliedChanges(status, []featurecontroller.ScheduledChange{launch, rollback}, metav1.NewTime(testNow))
+
+	require.Equal(t, []featurecontroller.AppliedChange{
+		{Name: "launch", At: launch.At, AppliedTime: firstApplied},
+		{Name: "rollback", At: rollback.At, AppliedTime: metav1.NewTime(testNow)},
+	}, status.AppliedChanges)
+
+	// Rescheduling a change to the future drops it
+	setAppliedChanges(status, nil, metav1.NewTime(testNow))
+	require.Empty(t, status.AppliedChanges)
+}
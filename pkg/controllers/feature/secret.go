@@ -0,0 +1,307 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/validation"
+)
+
+// secretIndex is the name of the FeatureFlag informer index listing the
+// Secrets, as namespace/name, each FeatureFlag is published to or reads
+// values from.
+const secretIndex = "bySecret"
+
+const (
+	// ErrSecretsDisabled is used as part of the Event 'reason' when a
+	// FeatureFlag uses Secrets but the operator was started without them
+	ErrSecretsDisabled = "ErrSecretsDisabled"
+	// ErrSecretValue is used as part of the Event 'reason' when the value of
+	// a variation cannot be read from its Secret
+	ErrSecretValue = "ErrSecretValue"
+
+	// MessageSecretsDisabled is the message used for Events when a
+	// FeatureFlag uses Secrets but the operator was started without them
+	MessageSecretsDisabled = "FeatureFlag is published to or reads values from Secrets, which are not enabled on the operator"
+	// MessageSecretValue is the message used for Events when the value of a
+	// variation cannot be read from its Secret
+	MessageSecretValue = "Variation %q cannot be read from key %q of Secret %q: %v"
+	// MessageSecretSynced is the message used for the SecretSynced condition
+	// of a FeatureFlag whose payload is published
+	MessageSecretSynced = "Secret %q holds the current payload"
+	// MessageSecretReleased is the message used for an Event fired when a
+	// deleted FeatureFlag hands its Secret over
+	MessageSecretReleased = "Secret %q released"
+	// MessageSecretDeleted is the message used for an Event fired when the
+	// Secret of a deleted FeatureFlag is removed
+	MessageSecretDeleted = "FeatureFlag payload removed from Secret %q"
+)
+
+// EnableSecrets lets FeatureFlags be published to Secrets and read the
+// values of their variations from Secrets. The informer must watch every
+// Secret of the namespaces the operator manages, as the Secrets values are
+// read from are not labelled. The operator must be granted access to
+// Secrets. Without it, such FeatureFlags are rejected.
+func (c *FeatureController) EnableSecrets(secretInformer coreinformers.SecretInformer) {
+	c.secretControl = NewSecretControl(c.kubeclientset)
+	c.secretsLister = secretInformer.Lister()
+	c.secretsSynced = secretInformer.Informer().HasSynced
+	c.publishers[string(samplev1alpha1.PublishTargetSecret)] = &secretPublisher{c: c}
+
+	utilruntime.Must(c.featureflagsIndexer.AddIndexers(cache.Indexers{
+		secretIndex: indexBySecret,
+	}))
+
+	// Set up an event handler for when Secrets change, which changes the
+	// values FeatureFlags read or the payload they publish. Periodic resyncs
+	// are ignored.
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.handleSecret,
+		UpdateFunc: func(old, new interface{}) {
+			if new.(*corev1.Secret).ResourceVersion == old.(*corev1.Secret).ResourceVersion {
+				return
+			}
+			c.handleSecret(new)
+		},
+		DeleteFunc: c.handleSecret,
+	})
+}
+
+// handleSecret takes a Secret, or its tombstone, and enqueues every
+// FeatureFlag in its namespace that is published to it or reads values
+// from it.
+func (c *FeatureController) handleSecret(obj interface{}) {
+	var object metav1.Object
+	var ok bool
+	if object, ok = obj.(metav1.Object); !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		object, ok = tombstone.Obj.(metav1.Object)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+		klog.V(4).Infof("Recovered deleted secret '%s' from tombstone", object.GetName())
+	}
+
+	featureflags, err := c.featureflagsIndexer.ByIndex(secretIndex, object.GetNamespace()+"/"+object.GetName())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, featureflag := range featureflags {
+		c.enqueueFeatureFlag(featureflag)
+	}
+}
+
+// indexBySecret is the cache.IndexFunc of secretIndex.
+func indexBySecret(obj interface{}) ([]string, error) {
+	featureflag, ok := obj.(*samplev1alpha1.FeatureFlag)
+	if !ok {
+		return nil, nil
+	}
+	var keys []string
+	if featureflag.Spec.Target == samplev1alpha1.PublishTargetSecret {
+		keys = append(keys, featureflag.Namespace+"/"+featureflag.Spec.SecretName)
+	}
+	for _, variation := range featureflag.Spec.Variations {
+		if variation.ValueFrom != nil && variation.ValueFrom.SecretKeyRef != nil {
+			keys = append(keys, featureflag.Namespace+"/"+variation.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+	return keys, nil
+}
+
+// usesSecrets reports whether the FeatureFlag is published to a Secret or
+// reads the value of a variation from a Secret.
+func usesSecrets(spec *samplev1alpha1.FeatureFlagSpec) bool {
+	if spec.Target == samplev1alpha1.PublishTargetSecret {
+		return true
+	}
+	for _, variation := range spec.Variations {
+		if variation.ValueFrom != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// newSecret creates a new Secret for a FeatureFlag resource. It also sets
+// the appropriate OwnerReferences on the resource so handleObject can
+// discover the FeatureFlag resource that 'owns' it.
+func newSecret(featureflag *samplev1alpha1.FeatureFlag) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      featureflag.Spec.SecretName,
+			Namespace: featureflag.Namespace,
+			Labels:    map[string]string{ManagedLabel: "true"},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(featureflag, samplev1alpha1.SchemeGroupVersion.WithKind("FeatureFlag")),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+}
+
+// resolveValues returns a copy of the FeatureFlag with the value of every
+// variation read from a Secret filled in, or the FeatureFlag itself if none
+// is. Values are checked against the flag type, as validation cannot read
+// them.
+func (c *FeatureController) resolveValues(featureflag *samplev1alpha1.FeatureFlag) (*samplev1alpha1.FeatureFlag, error) {
+	resolved := featureflag
+	for i, variation := range featureflag.Spec.Variations {
+		if variation.ValueFrom == nil || variation.ValueFrom.SecretKeyRef == nil {
+			continue
+		}
+		ref := variation.ValueFrom.SecretKeyRef
+		secret, err := c.secretsLister.Secrets(featureflag.Namespace).Get(ref.Name)
+		if err != nil {
+			return nil, fmt.Errorf(MessageSecretValue, variation.Name, ref.Key, ref.Name, err)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf(MessageSecretValue, variation.Name, ref.Key, ref.Name, "key not found")
+		}
+		// The value itself is never part of the message, as it ends up in
+		// Events and the status.
+		if err := validation.ValidateValue(featureflag.Spec.Type, string(value)); err != nil {
+			return nil, fmt.Errorf(MessageSecretValue, variation.Name, ref.Key, ref.Name, fmt.Sprintf("not a valid %s value", featureflag.Spec.Type))
+		}
+
+		if resolved == featureflag {
+			resolved = featureflag.DeepCopy()
+		}
+		resolved.Spec.Variations[i] = samplev1alpha1.Variation{Name: variation.Name, Value: string(value)}
+	}
+	return resolved, nil
+}
+
+// publishSecret renders the snapshot into the Secret of its FeatureFlag,
+// creating the Secret if needed. The hash of the payload is not reported, as
+// readers of the FeatureFlag could use it to guess weak values.
+func (c *FeatureController) publishSecret(snapshot *snapshot) (*publication, error) {
+	featureflag := snapshot.featureflag
+	resolved, err := c.resolveValues(snapshot.effective)
+	if err != nil {
+		return c.secretError(featureflag, ErrSecretValue, err.Error())
+	}
+	dataKey, content, err := renderPayload(resolved, snapshot.variation, snapshot.segments)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := c.secretsLister.Secrets(featureflag.Namespace).Get(featureflag.Spec.SecretName)
+	if errors.IsNotFound(err) {
+		secret = newSecret(featureflag)
+		secret.Data = map[string][]byte{dataKey: []byte(content)}
+		klog.V(4).Infof("FeatureFlag %s/%s is published to a new secret %s", featureflag.Namespace, featureflag.Name, secret.Name)
+		if secret, err = c.secretControl.CreateSecret(featureflag.Namespace, secret); err != nil {
+			return nil, err
+		}
+		secretCreatedCount.WithLabelValues().Inc()
+		return &publication{reason: SuccessSynced, message: fmt.Sprintf(MessageSecretSynced, secret.Name)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A Secret the FeatureFlag does not control, e.g. one holding the values
+	// its variations read, is never written.
+	if !metav1.IsControlledBy(secret, featureflag) {
+		msg := fmt.Sprintf(MessageResourceExists, secret.Name)
+		return c.secretError(featureflag, ErrResourceExists, msg)
+	}
+
+	// NEVER modify objects from the store, so work on a copy.
+	secretCopy := secret.DeepCopy()
+	changed := false
+	for _, ext := range payloadExtensions {
+		staleKey := featureflag.Name + "." + ext
+		if _, ok := secretCopy.Data[staleKey]; ok && staleKey != dataKey {
+			delete(secretCopy.Data, staleKey)
+			changed = true
+		}
+	}
+	if current, ok := secretCopy.Data[dataKey]; !ok || string(current) != content {
+		if secretCopy.Data == nil {
+			secretCopy.Data = map[string][]byte{}
+		}
+		secretCopy.Data[dataKey] = []byte(content)
+		changed = true
+	}
+	if !changed {
+		return &publication{reason: SuccessSynced, message: fmt.Sprintf(MessageSecretSynced, secret.Name)}, nil
+	}
+
+	klog.V(4).Infof("FeatureFlag %s/%s payload changed, updating secret %s", featureflag.Namespace, featureflag.Name, secret.Name)
+	if secret, err = c.secretControl.UpdateSecret(featureflag.Namespace, secretCopy); err != nil {
+		return nil, err
+	}
+	secretUpdatedCount.WithLabelValues().Inc()
+	return &publication{reason: SuccessSynced, message: fmt.Sprintf(MessageSecretSynced, secret.Name)}, nil
+}
+
+// secretError reports a failure to publish the FeatureFlag to its Secret
+// with a Warning event, and returns it as an error too so the FeatureFlag is
+// retried.
+func (c *FeatureController) secretError(featureflag *samplev1alpha1.FeatureFlag, reason, msg string) (*publication, error) {
+	c.recorder.Event(featureflag, corev1.EventTypeWarning, reason, msg)
+	return &publication{reason: reason, message: msg}, fmt.Errorf(msg)
+}
+
+// finalizeSecret deletes the Secret controlled by a FeatureFlag being
+// deleted, or only hands it over when the OnDeleteAnnotation asks for its
+// release. Secrets it does not control are left alone.
+func (c *FeatureController) finalizeSecret(key string, featureflag *samplev1alpha1.FeatureFlag) error {
+	secret, err := c.secretControl.GetSecret(featureflag.Namespace, featureflag.Spec.SecretName)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	case !metav1.IsControlledBy(secret, featureflag):
+	case releaseRequested(featureflag):
+		// NEVER modify objects from the store, so work on a copy.
+		secretCopy := secret.DeepCopy()
+		secretCopy.OwnerReferences = nil
+		delete(secretCopy.Labels, ManagedLabel)
+		klog.V(4).Infof("FeatureFlag %s is being deleted, releasing secret %s", key, secret.Name)
+		if _, err := c.secretControl.UpdateSecret(secret.Namespace, secretCopy); err != nil {
+			return err
+		}
+		secretUpdatedCount.WithLabelValues().Inc()
+		c.recorder.Event(featureflag, corev1.EventTypeNormal, SuccessReleased, fmt.Sprintf(MessageSecretReleased, secret.Name))
+	default:
+		klog.V(4).Infof("FeatureFlag %s is being deleted, deleting secret %s", key, secret.Name)
+		err := c.secretControl.DeleteSecret(secret.Namespace, secret.Name)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		secretDeletedCount.WithLabelValues().Inc()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package feature
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SecretControlInterface defines the interface that the FeatureController
+// uses to publish FeatureFlags to Secrets and read the Secrets their
+// variations refer to. It mirrors ConfigMapControlInterface and is
+// implemented as an interface to enable testing.
+type SecretControlInterface interface {
+	GetSecret(namespace string, name string) (*corev1.Secret, error)
+	CreateSecret(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	UpdateSecret(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	CreateOrUpdateSecret(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	DeleteSecret(namespace string, name string) error
+	ListSecrets(namespace string) (*corev1.SecretList, error)
+}
+
+// SecretControl is the secret service implementation using API calls to kubernetes.
+type SecretControl struct {
+	kubeClient kubernetes.Interface
+	logger     *log.Entry
+}
+
+// NewSecretControl creates a concrete implementation of the SecretControlInterface.
+func NewSecretControl(kubeClient kubernetes.Interface) SecretControlInterface {
+
+	logger := log.WithFields(log.Fields{
+		"service": "k8s.secret",
+	})
+
+	return &SecretControl{
+		kubeClient: kubeClient,
+		logger:     logger,
+	}
+}
+
+// GetSecret get a secret resource given the name and namespace
+func (p *SecretControl) GetSecret(namespace string, name string) (*corev1.Secret, error) {
+	secret, err := p.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secret, err
+}
+
+// CreateSecret creates a secret resource
+func (p *SecretControl) CreateSecret(namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+	created, err := p.kubeClient.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	if err != nil {
+		return created, err
+	}
+	p.logger.WithFields(log.Fields{"namespace": namespace, "secret": secret.Name}).Info("secret created")
+	return created, nil
+}
+
+// UpdateSecret updates a secret resource
+func (p *SecretControl) UpdateSecret(namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+	updated, err := p.kubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if err != nil {
+		return updated, err
+	}
+	p.logger.WithFields(log.Fields{"namespace": namespace, "secret": secret.Name}).Info("secret updated")
+	return updated, nil
+}
+
+// CreateOrUpdateSecret creates a secret resource, or replaces it if it
+// already exists
+func (p *SecretControl) CreateOrUpdateSecret(namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+	storedSecret, err := p.GetSecret(namespace, secret.Name)
+	if err != nil {
+		// If no resource we need to create.
+		if errors.IsNotFound(err) {
+			return p.CreateSecret(namespace, secret)
+		}
+		return storedSecret, err
+	}
+
+	// Already exists, need to Update on the latest resource version, as for
+	// ConfigMaps.
+	secret.ResourceVersion = storedSecret.ResourceVersion
+	return p.UpdateSecret(namespace, secret)
+}
+
+// DeleteSecret deletes a secret resource
+func (p *SecretControl) DeleteSecret(namespace string, name string) error {
+	return p.kubeClient.CoreV1().Secrets(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// ListSecrets lists all the secrets for a given namespace
+func (p *SecretControl) ListSecrets(namespace string) (*corev1.SecretList, error) {
+	return p.kubeClient.CoreV1().Secrets(namespace).List(context.TODO(), metav1.ListOptions{})
+}
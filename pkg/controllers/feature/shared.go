@@ -0,0 +1,124 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+const (
+	// SuccessShared is used as part of the Event 'reason' when a FeatureFlag
+	// starts publishing into a ConfigMap shared with other FeatureFlags
+	SuccessShared = "Shared"
+	// ErrKeyConflict is used as part of the Event 'reason' when a FeatureFlag
+	// fails to sync as another FeatureFlag publishes the same key
+	ErrKeyConflict = "ErrKeyConflict"
+
+	// MessageResourceShared is the message used for an Event fired when a
+	// FeatureFlag starts publishing into a shared ConfigMap
+	MessageResourceShared = "FeatureFlag publishes into ConfigMap %q shared with other FeatureFlags"
+	// MessageKeyConflict is the message used for Events when a FeatureFlag
+	// fails to sync as another FeatureFlag publishes the same key
+	MessageKeyConflict = "Key %q of ConfigMap %q is published by FeatureFlag %q (uid %s)"
+	// MessageNoConflict is the message used for the Conflict condition once a
+	// conflict is resolved
+	MessageNoConflict = "No other FeatureFlag publishes the same key"
+)
+
+// featureFlagKind is the kind of the owner references set by FeatureFlags.
+var featureFlagKind = samplev1alpha1.SchemeGroupVersion.WithKind("FeatureFlag")
+
+// isFeatureFlagRef reports whether the owner reference points to a
+// FeatureFlag, in any version.
+func isFeatureFlagRef(ref metav1.OwnerReference) bool {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	return err == nil && gv.Group == featureFlagKind.Group && ref.Kind == featureFlagKind.Kind
+}
+
+// canShare reports whether the FeatureFlag may publish into the ConfigMap
+// next to other FeatureFlags: the ConfigMap is controlled by a FeatureFlag,
+// or has no controller and is owned by at least one FeatureFlag.
+func canShare(configmap *corev1.ConfigMap) bool {
+	if controller := metav1.GetControllerOf(configmap); controller != nil {
+		return isFeatureFlagRef(*controller)
+	}
+	for _, ref := range configmap.OwnerReferences {
+		if isFeatureFlagRef(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// shareConfigMap returns a copy of the ConfigMap with a non-controller owner
+// reference to the FeatureFlag, which records it as a contributor so the
+// garbage collector keeps the ConfigMap while it exists.
+func shareConfigMap(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) *corev1.ConfigMap {
+	configmapCopy := configmap.DeepCopy()
+	ref := metav1.NewControllerRef(featureflag, featureFlagKind)
+	ref.Controller = nil
+	configmapCopy.OwnerReferences = append(configmapCopy.OwnerReferences, *ref)
+	setManaged(configmapCopy)
+	return configmapCopy
+}
+
+// otherContributors returns the owner references of the FeatureFlags other
+// than featureflag publishing into the ConfigMap.
+func otherContributors(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) []metav1.OwnerReference {
+	var refs []metav1.OwnerReference
+	for _, ref := range configmap.OwnerReferences {
+		if isFeatureFlagRef(ref) && ref.UID != featureflag.UID {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// keyConflict returns a message describing the conflict when a key of the
+// FeatureFlag, in any format, is published into the ConfigMap by another
+// FeatureFlag, or "" if there is none. Keys are named after their flag, so
+// this is a FeatureFlag of the same name, e.g. one still being deleted when
+// the flag was created again. The first to publish keeps the key.
+func keyConflict(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) string {
+	for _, ref := range otherContributors(configmap, featureflag) {
+		if ref.Name != featureflag.Name {
+			continue
+		}
+		for _, ext := range payloadExtensions {
+			if key := ref.Name + "." + ext; configmap.Data[key] != "" {
+				return fmt.Sprintf(MessageKeyConflict, key, configmap.Name, ref.Name, ref.UID)
+			}
+		}
+	}
+	return ""
+}
+
+// removeContributor removes the owner reference to the FeatureFlag from the
+// ConfigMap.
+func removeContributor(configmap *corev1.ConfigMap, featureflag *samplev1alpha1.FeatureFlag) {
+	var ownerReferences []metav1.OwnerReference
+	for _, ref := range configmap.OwnerReferences {
+		if ref.UID != featureflag.UID {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	configmap.OwnerReferences = ownerReferences
+}
@@ -0,0 +1,215 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// newSharedFeatureFlag returns a FeatureFlag publishing into the shared-config ConfigMap.
+func newSharedFeatureFlag(name string, uid types.UID) *featurecontroller.FeatureFlag {
+	featureflag := newFeatureFlag(name)
+	featureflag.UID = uid
+	featureflag.Spec.ConfigMapName = "shared-config"
+	return featureflag
+}
+
+// withContributor returns a copy of the ConfigMap with the payload of the
+// FeatureFlag and a non-controller owner reference to it.
+func withContributor(configmap *core.ConfigMap, featureflag *featurecontroller.FeatureFlag, t *testing.T) *core.ConfigMap {
+	configmap = shareConfigMap(configmap, featureflag)
+	if _, err := setPayload(configmap, featureflag, defaultVariation(featureflag), nil); err != nil {
+		t.Fatalf("Unexpected error rendering featureflag %v: %v", featureflag.Name, err)
+	}
+	return configmap
+}
+
+// TestShareConfigMap tests that a FeatureFlag publishes its key next to those of the FeatureFlag controlling the ConfigMap
+func TestShareConfigMap(t *testing.T) {
+	f := newFixture(t)
+	first := newSharedFeatureFlag("first", "uid-first")
+	second := newSharedFeatureFlag("second", "uid-second")
+	d := newConfigMapWithPayload(first, t)
+
+	f.featureflagLister = append(f.featureflagLister, first, second)
+	f.objects = append(f.objects, first, second)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := withContributor(d, second, t)
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(second, expConfig))
+
+	f.run(getKey(second, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeNormal, SuccessShared, fmt.Sprintf(MessageResourceShared, d.Name)); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestShareConfigMapContributor tests that a contributor publishes into a shared ConfigMap without a controller
+func TestShareConfigMapContributor(t *testing.T) {
+	f := newFixture(t)
+	first := newSharedFeatureFlag("first", "uid-first")
+	second := newSharedFeatureFlag("second", "uid-second")
+	d := newConfigMapWithPayload(first, t)
+	d.OwnerReferences = nil
+	d = withContributor(d, first, t)
+	second.Spec.DefaultVariation = "off"
+	d = withContributor(d, second, t)
+	second.Spec.DefaultVariation = "on"
+
+	f.featureflagLister = append(f.featureflagLister, second)
+	f.objects = append(f.objects, second)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := d.DeepCopy()
+	if _, err := setPayload(expConfig, second, defaultVariation(second), nil); err != nil {
+		t.Fatal(err)
+	}
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFooStatusAction(withValid(second, expConfig))
+
+	f.run(getKey(second, t))
+}
+
+// TestKeyConflict tests that a FeatureFlag does not overwrite the key of another FeatureFlag and reports the conflict
+func TestKeyConflict(t *testing.T) {
+	f := newFixture(t)
+	// The flag was created again while its previous incarnation is still
+	// being deleted.
+	previous := newSharedFeatureFlag("test", "uid-previous")
+	featureflag := newSharedFeatureFlag("test", "uid-test")
+	d := newConfigMapWithPayload(previous, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	msg := fmt.Sprintf(MessageKeyConflict, "test.json", d.Name, "test", "uid-previous")
+	expFlag := withCondition(featureflag, featurecontroller.FeatureFlagValid, core.ConditionTrue, SuccessSynced, MessageValid)
+	expFlag = withCondition(expFlag, featurecontroller.FeatureFlagConfigMapSynced, core.ConditionFalse, ErrKeyConflict, msg)
+	expFlag = withCondition(expFlag, featurecontroller.FeatureFlagReady, core.ConditionFalse, ErrKeyConflict, msg)
+	expFlag = withCondition(expFlag, featurecontroller.FeatureFlagConflict, core.ConditionTrue, ErrKeyConflict, msg)
+	f.expectUpdateFooStatusAction(withSynced(expFlag))
+
+	f.runExpectError(getKey(featureflag, t))
+
+	event := <-f.recorder.Events
+	if expected := fmt.Sprintf("%s %s %s", core.EventTypeWarning, ErrKeyConflict, msg); event != expected {
+		t.Errorf("expected event %q, got %q", expected, event)
+	}
+}
+
+// TestKeyConflictResolved tests that the Conflict condition turns False once the FeatureFlag publishes its key
+func TestKeyConflictResolved(t *testing.T) {
+	f := newFixture(t)
+	featureflag := newSharedFeatureFlag("test", "uid-test")
+	featureflag = withCondition(featureflag, featurecontroller.FeatureFlagConflict, core.ConditionTrue, ErrKeyConflict, "conflict")
+	d := newConfigMapWithPayload(featureflag, t)
+
+	f.featureflagLister = append(f.featureflagLister, featureflag)
+	f.objects = append(f.objects, featureflag)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expFlag := withCondition(withValid(featureflag, d), featurecontroller.FeatureFlagConflict, core.ConditionFalse, SuccessSynced, MessageNoConflict)
+	f.expectUpdateFooStatusAction(expFlag)
+
+	f.run(getKey(featureflag, t))
+}
+
+// TestFinalizeSharedConfigMap tests that deleting the controller of a shared ConfigMap keeps it for the other FeatureFlags
+func TestFinalizeSharedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	first := newSharedFeatureFlag("first", "uid-first")
+	first.DeletionTimestamp = &metav1.Time{Time: testNow}
+	second := newSharedFeatureFlag("second", "uid-second")
+	d := withContributor(newConfigMapWithPayload(first, t), second, t)
+
+	expConfig := newConfigMap(second)
+	expConfig.OwnerReferences = []metav1.OwnerReference{d.OwnerReferences[1]}
+	expConfig.Data = map[string]string{payloadKey(second): d.Data[payloadKey(second)]}
+	recordContentHash(expConfig, second, payloadKey(second), expConfig.Data[payloadKey(second)])
+
+	f.featureflagLister = append(f.featureflagLister, first, second)
+	f.objects = append(f.objects, first, second)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	f.expectUpdateConfigMapAction(expConfig)
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(first))
+
+	f.run(getKey(first, t))
+	f.expectDeletedEvent(d.Name)
+}
+
+// TestHandleSharedConfigMap tests that a change to a shared ConfigMap enqueues every FeatureFlag publishing into it
+func TestHandleSharedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	first := newSharedFeatureFlag("first", "uid-first")
+	second := newSharedFeatureFlag("second", "uid-second")
+	d := withContributor(newConfigMapWithPayload(first, t), second, t)
+	f.featureflagLister = append(f.featureflagLister, first, second)
+	f.objects = append(f.objects, first, second)
+
+	c, _, _ := f.newFeatureController()
+	c.handleObject(d)
+
+	if n := c.workqueue.Len(); n != 2 {
+		t.Errorf("expected both FeatureFlags to be enqueued, got %d items", n)
+	}
+}
+
+// TestMoveFromSharedConfigMap tests that a FeatureFlag whose configmapName changed is removed from the shared ConfigMap it was published to
+func TestMoveFromSharedConfigMap(t *testing.T) {
+	f := newFixture(t)
+	first := newSharedFeatureFlag("first", "uid-first")
+	second := newSharedFeatureFlag("second", "uid-second")
+	d := withContributor(newConfigMapWithPayload(first, t), second, t)
+	second.Spec.ConfigMapName = "second-config"
+
+	f.featureflagLister = append(f.featureflagLister, first, second)
+	f.objects = append(f.objects, first, second)
+	f.configmapLister = append(f.configmapLister, d)
+	f.kubeobjects = append(f.kubeobjects, d)
+
+	expConfig := newConfigMapWithPayload(second, t)
+	expShared := newConfigMapWithPayload(first, t)
+	f.expectCreateConfigMapAction(expConfig)
+	f.expectUpdateConfigMapAction(expShared)
+	f.expectUpdateFooStatusAction(withValid(second, expConfig))
+
+	f.run(getKey(second, t))
+}
+
+// TestFinalizeMovedFeatureFlag tests that deleting a FeatureFlag whose configmapName changed also removes it from the ConfigMap it was published to before
+func TestFinalizeMovedFeatureFlag(t *testing.T) {
+	f := newFixture(t)
+	first := newSharedFeatureFlag("first", "uid-first")
+	second := newSharedFeatureFlag("second", "uid-second")
+	second.DeletionTimestamp = &metav1.Time{Time: testNow}
+	d := withContributor(newConfigMapWithPayload(first, t), second, t)
+	second.Spec.ConfigMapName = "second-config"
+	moved := newConfigMapWithPayload(second, t)
+
+	f.featureflagLister = append(f.featureflagLister, first, second)
+	f.objects = append(f.objects, first, second)
+	f.configmapLister = append(f.configmapLister, d, moved)
+	f.kubeobjects = append(f.kubeobjects, d, moved)
+
+	f.expectDeleteConfigMapAction(moved)
+	f.expectUpdateConfigMapAction(newConfigMapWithPayload(first, t))
+	f.expectUpdateFeatureFlagAction(withoutFinalizer(second))
+
+	f.run(getKey(second, t))
+	f.expectDeletedEvent(moved.Name)
+}
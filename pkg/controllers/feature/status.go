@@ -0,0 +1,72 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feature
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	samplev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// newCondition returns a FeatureFlagCondition without a transition time;
+// setCondition fills it in.
+func newCondition(conditionType samplev1alpha1.FeatureFlagConditionType, status corev1.ConditionStatus, reason, message string) samplev1alpha1.FeatureFlagCondition {
+	return samplev1alpha1.FeatureFlagCondition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// getCondition returns the condition with the given type, or nil.
+func getCondition(status *samplev1alpha1.FeatureFlagStatus, conditionType samplev1alpha1.FeatureFlagConditionType) *samplev1alpha1.FeatureFlagCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// removeCondition removes the condition with the given type, if any.
+func removeCondition(status *samplev1alpha1.FeatureFlagStatus, conditionType samplev1alpha1.FeatureFlagConditionType) {
+	var conditions []samplev1alpha1.FeatureFlagCondition
+	for _, condition := range status.Conditions {
+		if condition.Type != conditionType {
+			conditions = append(conditions, condition)
+		}
+	}
+	status.Conditions = conditions
+}
+
+// setCondition adds or replaces the condition of the same type. The
+// LastTransitionTime is only moved to now when the condition status changes.
+func setCondition(status *samplev1alpha1.FeatureFlagStatus, condition samplev1alpha1.FeatureFlagCondition, now metav1.Time) {
+	existing := getCondition(status, condition.Type)
+	if existing == nil {
+		condition.LastTransitionTime = now
+		status.Conditions = append(status.Conditions, condition)
+		return
+	}
+
+	if existing.Status != condition.Status {
+		condition.LastTransitionTime = now
+	} else {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	*existing = condition
+}
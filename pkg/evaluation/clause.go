@@ -0,0 +1,227 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluation
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// SupportedOperators lists every operator a Clause may use.
+var SupportedOperators = []string{
+	string(featurev1alpha1.OperatorIn),
+	string(featurev1alpha1.OperatorNotIn),
+	string(featurev1alpha1.OperatorStartsWith),
+	string(featurev1alpha1.OperatorEndsWith),
+	string(featurev1alpha1.OperatorMatches),
+	string(featurev1alpha1.OperatorLessThan),
+	string(featurev1alpha1.OperatorLessThanOrEqual),
+	string(featurev1alpha1.OperatorGreaterThan),
+	string(featurev1alpha1.OperatorGreaterThanOrEqual),
+	string(featurev1alpha1.OperatorSemVerEqual),
+	string(featurev1alpha1.OperatorSemVerLessThan),
+	string(featurev1alpha1.OperatorSemVerGreaterThan),
+	string(featurev1alpha1.OperatorInSegment),
+	string(featurev1alpha1.OperatorNotInSegment),
+}
+
+// clause is a compiled Clause. match is called with the value of the
+// attribute and reports whether it satisfies the operator for any value.
+// Segment clauses set matchContext instead, as they look at the whole context.
+type clause struct {
+	attribute    string
+	negate       bool
+	match        func(value string) bool
+	matchContext func(ctx Context) bool
+}
+
+func (c clause) matches(ctx Context) bool {
+	if c.matchContext != nil {
+		return c.matchContext(ctx) != c.negate
+	}
+
+	value, ok := ctx[c.attribute]
+	if !ok {
+		return false
+	}
+	return c.match(value) != c.negate
+}
+
+// compileClause parses the values of the clause once so evaluation does not
+// have to. segments holds the compiled FeatureSegments segment operators may
+// refer to.
+func compileClause(c featurev1alpha1.Clause, fldPath *field.Path, segments map[string]*segment) (clause, field.ErrorList) {
+	allErrs := field.ErrorList{}
+	compiled := clause{attribute: c.Attribute, negate: c.Negate}
+	valuesPath := fldPath.Child("values")
+
+	if len(c.Values) == 0 {
+		allErrs = append(allErrs, field.Required(valuesPath, ""))
+	}
+
+	switch c.Operator {
+	case featurev1alpha1.OperatorIn, featurev1alpha1.OperatorNotIn:
+		set := map[string]bool{}
+		for _, v := range c.Values {
+			set[v] = true
+		}
+		in := c.Operator == featurev1alpha1.OperatorIn
+		compiled.match = func(value string) bool {
+			return set[value] == in
+		}
+
+	case featurev1alpha1.OperatorStartsWith, featurev1alpha1.OperatorEndsWith:
+		has := strings.HasPrefix
+		if c.Operator == featurev1alpha1.OperatorEndsWith {
+			has = strings.HasSuffix
+		}
+		values := append([]string(nil), c.Values...)
+		compiled.match = func(value string) bool {
+			for _, v := range values {
+				if has(value, v) {
+					return true
+				}
+			}
+			return false
+		}
+
+	case featurev1alpha1.OperatorMatches:
+		var patterns []*regexp.Regexp
+		for i, v := range c.Values {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(valuesPath.Index(i), v, err.Error()))
+				continue
+			}
+			patterns = append(patterns, re)
+		}
+		compiled.match = func(value string) bool {
+			for _, re := range patterns {
+				if re.MatchString(value) {
+					return true
+				}
+			}
+			return false
+		}
+
+	case featurev1alpha1.OperatorLessThan, featurev1alpha1.OperatorLessThanOrEqual,
+		featurev1alpha1.OperatorGreaterThan, featurev1alpha1.OperatorGreaterThanOrEqual:
+		var numbers []float64
+		for i, v := range c.Values {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(valuesPath.Index(i), v, "must be a number"))
+				continue
+			}
+			numbers = append(numbers, n)
+		}
+		cmp := numericComparisons[c.Operator]
+		compiled.match = func(value string) bool {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false
+			}
+			for _, v := range numbers {
+				if cmp(n, v) {
+					return true
+				}
+			}
+			return false
+		}
+
+	case featurev1alpha1.OperatorSemVerEqual, featurev1alpha1.OperatorSemVerLessThan, featurev1alpha1.OperatorSemVerGreaterThan:
+		var versions []*semver.Version
+		for i, v := range c.Values {
+			version, err := parseSemVer(v)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(valuesPath.Index(i), v, "must be a semantic version"))
+				continue
+			}
+			versions = append(versions, version)
+		}
+		want := semVerComparisons[c.Operator]
+		compiled.match = func(value string) bool {
+			version, err := parseSemVer(value)
+			if err != nil {
+				return false
+			}
+			for _, v := range versions {
+				if version.Compare(*v) == want {
+					return true
+				}
+			}
+			return false
+		}
+
+	case featurev1alpha1.OperatorInSegment, featurev1alpha1.OperatorNotInSegment:
+		var referenced []*segment
+		for i, name := range c.Values {
+			s, ok := segments[name]
+			if !ok {
+				allErrs = append(allErrs, field.NotFound(valuesPath.Index(i), name))
+				continue
+			}
+			referenced = append(referenced, s)
+		}
+		in := c.Operator == featurev1alpha1.OperatorInSegment
+		compiled.matchContext = func(ctx Context) bool {
+			for _, s := range referenced {
+				if s.contains(ctx) {
+					return in
+				}
+			}
+			return !in
+		}
+
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("operator"), c.Operator, SupportedOperators))
+	}
+
+	return compiled, allErrs
+}
+
+var numericComparisons = map[featurev1alpha1.Operator]func(a, b float64) bool{
+	featurev1alpha1.OperatorLessThan:           func(a, b float64) bool { return a < b },
+	featurev1alpha1.OperatorLessThanOrEqual:    func(a, b float64) bool { return a <= b },
+	featurev1alpha1.OperatorGreaterThan:        func(a, b float64) bool { return a > b },
+	featurev1alpha1.OperatorGreaterThanOrEqual: func(a, b float64) bool { return a >= b },
+}
+
+// semVerComparisons maps each operator to the result of Version.Compare it
+// expects.
+var semVerComparisons = map[featurev1alpha1.Operator]int{
+	featurev1alpha1.OperatorSemVerEqual:       0,
+	featurev1alpha1.OperatorSemVerLessThan:    -1,
+	featurev1alpha1.OperatorSemVerGreaterThan: 1,
+}
+
+// parseSemVer parses a semantic version, accepting a leading "v" and a missing
+// minor or patch version, so "v2" and "2.1" are read as 2.0.0 and 2.1.0.
+func parseSemVer(value string) (*semver.Version, error) {
+	core, suffix := strings.TrimPrefix(value, "v"), ""
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core, suffix = core[:i], core[i:]
+	}
+	for n := strings.Count(core, "."); n < 2; n++ {
+		core += ".0"
+	}
+	return semver.NewVersion(core + suffix)
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package evaluation compiles FeatureFlag specs and evaluates them against an
+// evaluation context. It does not talk to the API server so the same logic can
+// be used by the controller, tests and SDKs.
+package evaluation
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// Context holds the attributes of the subject a flag is evaluated for, e.g.
+// {"key": "user-123", "country": "GB", "appVersion": "2.3.0"}.
+type Context map[string]string
+
+// Reason explains why a variation was served
+type Reason string
+
+const (
+	// ReasonOff is used when the flag is disabled
+	ReasonOff Reason = "Off"
+	// ReasonRuleMatch is used when a rule matched the context
+	ReasonRuleMatch Reason = "RuleMatch"
+	// ReasonDefault is used when the flag is enabled and no rule matched
+	ReasonDefault Reason = "Default"
+	// ReasonPrerequisiteFailed is used when a prerequisite flag did not
+	// resolve to its required variation
+	ReasonPrerequisiteFailed Reason = "PrerequisiteFailed"
+)
+
+// Result is the outcome of evaluating a Flag
+type Result struct {
+	Variation string
+	Value     string
+	Reason    Reason
+	// RuleIndex is the index of the matching rule, or -1
+	RuleIndex int
+	// Prerequisite is the key of the prerequisite that was not met when
+	// Reason is ReasonPrerequisiteFailed
+	Prerequisite string
+}
+
+// Flag is a compiled FeatureFlag. It is safe for concurrent use.
+type Flag struct {
+	key            string
+	spec           featurev1alpha1.FeatureFlagSpec
+	variations     map[string]string
+	rules          []rule
+	defaultRollout *rollout
+}
+
+// rule is a compiled Rule. Exactly one of variation and rollout is set.
+type rule struct {
+	clauses   []clause
+	variation string
+	rollout   *rollout
+}
+
+// Compile compiles the rules of a FeatureFlag along with the FeatureSegments
+// they reference. The returned error aggregates every invalid operator,
+// regular expression, number, semantic version or missing segment found, each
+// prefixed with its field path.
+func Compile(featureflag *featurev1alpha1.FeatureFlag, featuresegments ...*featurev1alpha1.FeatureSegment) (*Flag, error) {
+	spec := featureflag.Spec.DeepCopy()
+	flag := &Flag{
+		key:        featureflag.Name,
+		spec:       *spec,
+		variations: map[string]string{},
+	}
+
+	for _, variation := range spec.Variations {
+		flag.variations[variation.Name] = variation.Value
+	}
+
+	allErrs := field.ErrorList{}
+
+	segments := map[string]*segment{}
+	for _, featuresegment := range featuresegments {
+		compiled, errs := compileSegment(featuresegment)
+		if len(errs) > 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("segments").Key(featuresegment.Name), featuresegment.Name, errs.ToAggregate().Error()))
+			continue
+		}
+		segments[featuresegment.Name] = compiled
+	}
+
+	rulesPath := field.NewPath("spec", "rules")
+	for i, r := range spec.Rules {
+		compiled := rule{variation: r.Variation}
+		if r.Rollout != nil {
+			compiled.rollout = compileRollout(flag.key, r.Rollout)
+		}
+		for j, c := range r.Clauses {
+			cl, errs := compileClause(c, rulesPath.Index(i).Child("clauses").Index(j), segments)
+			allErrs = append(allErrs, errs...)
+			compiled.clauses = append(compiled.clauses, cl)
+		}
+		flag.rules = append(flag.rules, compiled)
+	}
+
+	if spec.DefaultRollout != nil {
+		flag.defaultRollout = compileRollout(flag.key, spec.DefaultRollout)
+	}
+
+	if len(allErrs) > 0 {
+		return nil, allErrs.ToAggregate()
+	}
+	return flag, nil
+}
+
+// ValidateRules checks the operator and values of every rule clause as
+// Compile does, without resolving the FeatureSegments they reference, which
+// may be created after the flag.
+func ValidateRules(featureflag *featurev1alpha1.FeatureFlag) field.ErrorList {
+	allErrs := field.ErrorList{}
+	rulesPath := field.NewPath("spec", "rules")
+	for i, r := range featureflag.Spec.Rules {
+		for j, c := range r.Clauses {
+			clausePath := rulesPath.Index(i).Child("clauses").Index(j)
+			if isSegmentOperator(c.Operator) {
+				if len(c.Values) == 0 {
+					allErrs = append(allErrs, field.Required(clausePath.Child("values"), ""))
+				}
+				continue
+			}
+			_, errs := compileClause(c, clausePath, nil)
+			allErrs = append(allErrs, errs...)
+		}
+	}
+	return allErrs
+}
+
+// Key returns the name of the FeatureFlag the Flag was compiled from.
+func (f *Flag) Key() string {
+	return f.key
+}
+
+// Evaluate returns the variation served to the evaluation context. Rollouts
+// serve DefaultVariation to contexts without their bucketing attribute.
+// Prerequisites are resolved against the other flags of a Set, so a flag with
+// prerequisites evaluated on its own always fails them.
+func (f *Flag) Evaluate(ctx Context) Result {
+	return f.evaluate(ctx, Set{f.key: f}, map[string]bool{}, false)
+}
+
+// evaluate resolves prerequisites against flags, skipping those whose key is
+// in visiting, then evaluates the rules unless defaultOnly is set.
+func (f *Flag) evaluate(ctx Context, flags Set, visiting map[string]bool, defaultOnly bool) Result {
+	if !f.spec.Enabled {
+		return f.result(f.spec.OffVariation, ReasonOff, -1)
+	}
+
+	if prerequisite, ok := f.prerequisitesMet(ctx, flags, visiting, defaultOnly); !ok {
+		result := f.result(f.spec.OffVariation, ReasonPrerequisiteFailed, -1)
+		result.Prerequisite = prerequisite
+		return result
+	}
+
+	if defaultOnly {
+		return f.result(f.spec.DefaultVariation, ReasonDefault, -1)
+	}
+
+	for i, r := range f.rules {
+		if r.matches(ctx) {
+			return f.result(f.serve(r.variation, r.rollout, ctx), ReasonRuleMatch, i)
+		}
+	}
+
+	return f.result(f.serve(f.spec.DefaultVariation, f.defaultRollout, ctx), ReasonDefault, -1)
+}
+
+// serve returns the variation, or the variation chosen by the rollout if set.
+func (f *Flag) serve(variation string, r *rollout, ctx Context) string {
+	if r == nil {
+		return variation
+	}
+	if v, ok := r.variation(ctx); ok {
+		return v
+	}
+	return f.spec.DefaultVariation
+}
+
+func (f *Flag) result(variation string, reason Reason, ruleIndex int) Result {
+	return Result{
+		Variation: variation,
+		Value:     f.variations[variation],
+		Reason:    reason,
+		RuleIndex: ruleIndex,
+	}
+}
+
+// matches reports whether every clause of the rule matches the context.
+func (r rule) matches(ctx Context) bool {
+	for _, c := range r.clauses {
+		if !c.matches(ctx) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+func newFeatureFlag(rules ...featurev1alpha1.Rule) *featurev1alpha1.FeatureFlag {
+	return &featurev1alpha1.FeatureFlag{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-checkout", Namespace: metav1.NamespaceDefault},
+		Spec: featurev1alpha1.FeatureFlagSpec{
+			FlagSpec: featurev1alpha1.FlagSpec{
+				Type:    featurev1alpha1.FlagTypeString,
+				Enabled: true,
+				Variations: []featurev1alpha1.Variation{
+					{Name: "blue", Value: "#0000ff"},
+					{Name: "green", Value: "#00ff00"},
+					{Name: "red", Value: "#ff0000"},
+				},
+				DefaultVariation: "blue",
+				OffVariation:     "red",
+				Rules:            rules,
+			},
+		},
+	}
+}
+
+func newRule(variation string, clauses ...featurev1alpha1.Clause) featurev1alpha1.Rule {
+	return featurev1alpha1.Rule{Clauses: clauses, Variation: variation}
+}
+
+func newClause(attribute string, op featurev1alpha1.Operator, values ...string) featurev1alpha1.Clause {
+	return featurev1alpha1.Clause{Attribute: attribute, Operator: op, Values: values}
+}
+
+// TestOperators tests every operator against matching and non matching attributes
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		name     string
+		clause   featurev1alpha1.Clause
+		value    string
+		expMatch bool
+	}{
+		{"in matches any value", newClause("a", featurev1alpha1.OperatorIn, "GB", "FR"), "FR", true},
+		{"in does not match other values", newClause("a", featurev1alpha1.OperatorIn, "GB", "FR"), "DE", false},
+		{"notIn matches other values", newClause("a", featurev1alpha1.OperatorNotIn, "GB", "FR"), "DE", true},
+		{"notIn does not match listed values", newClause("a", featurev1alpha1.OperatorNotIn, "GB", "FR"), "GB", false},
+		{"startsWith matches a prefix", newClause("a", featurev1alpha1.OperatorStartsWith, "beta-"), "beta-tester", true},
+		{"startsWith does not match a suffix", newClause("a", featurev1alpha1.OperatorStartsWith, "beta-"), "tester-beta-", false},
+		{"endsWith matches a suffix", newClause("a", featurev1alpha1.OperatorEndsWith, "@example.com"), "jo@example.com", true},
+		{"endsWith does not match a prefix", newClause("a", featurev1alpha1.OperatorEndsWith, "@example.com"), "@example.com.evil", false},
+		{"matches matches a regex", newClause("a", featurev1alpha1.OperatorMatches, `^user-\d+$`), "user-42", true},
+		{"matches does not match other strings", newClause("a", featurev1alpha1.OperatorMatches, `^user-\d+$`), "user-x", false},
+		{"lessThan matches a lower number", newClause("a", featurev1alpha1.OperatorLessThan, "10"), "9.5", true},
+		{"lessThan does not match an equal number", newClause("a", featurev1alpha1.OperatorLessThan, "10"), "10", false},
+		{"lessThanOrEqual matches an equal number", newClause("a", featurev1alpha1.OperatorLessThanOrEqual, "10"), "10", true},
+		{"greaterThan matches a higher number", newClause("a", featurev1alpha1.OperatorGreaterThan, "10"), "11", true},
+		{"greaterThanOrEqual does not match a lower number", newClause("a", featurev1alpha1.OperatorGreaterThanOrEqual, "10"), "2", false},
+		{"numeric operators do not match non numbers", newClause("a", featurev1alpha1.OperatorGreaterThan, "10"), "many", false},
+		{"semVerEqual matches equal versions", newClause("a", featurev1alpha1.OperatorSemVerEqual, "2.1"), "v2.1.0", true},
+		{"semVerLessThan matches lower versions", newClause("a", featurev1alpha1.OperatorSemVerLessThan, "2.10.0"), "2.9.3", true},
+		{"semVerLessThan orders pre-releases first", newClause("a", featurev1alpha1.OperatorSemVerLessThan, "2.0.0"), "2.0.0-rc.1", true},
+		{"semVerGreaterThan does not match lower versions", newClause("a", featurev1alpha1.OperatorSemVerGreaterThan, "2.0.0"), "1.99.0", false},
+		{"semver operators do not match non versions", newClause("a", featurev1alpha1.OperatorSemVerGreaterThan, "2.0.0"), "latest", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, errs := compileClause(test.clause, nil, nil)
+			require.Empty(t, errs)
+			require.Equal(t, test.expMatch, c.matches(Context{"a": test.value}))
+
+			test.clause.Negate = true
+			c, _ = compileClause(test.clause, nil, nil)
+			require.Equal(t, !test.expMatch, c.matches(Context{"a": test.value}), "negated")
+		})
+	}
+}
+
+// TestMissingAttribute tests that a clause never matches a context without its attribute, even when negated
+func TestMissingAttribute(t *testing.T) {
+	clause := newClause("country", featurev1alpha1.OperatorNotIn, "GB")
+	c, _ := compileClause(clause, nil, nil)
+	require.False(t, c.matches(Context{}))
+
+	clause.Negate = true
+	c, _ = compileClause(clause, nil, nil)
+	require.False(t, c.matches(Context{}))
+}
+
+// TestCompileErrors tests that every invalid value is reported with its field path
+func TestCompileErrors(t *testing.T) {
+	featureflag := newFeatureFlag(
+		newRule("green",
+			newClause("email", featurev1alpha1.OperatorMatches, "[a-"),
+			newClause("age", featurev1alpha1.OperatorGreaterThan, "eighteen"),
+		),
+		newRule("green",
+			newClause("appVersion", featurev1alpha1.OperatorSemVerEqual, "1.2.3", "1.x"),
+			newClause("country", "like", "GB"),
+			newClause("country", featurev1alpha1.OperatorIn),
+		),
+	)
+
+	_, err := Compile(featureflag)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "spec.rules[0].clauses[0].values[0]")
+	require.Contains(t, err.Error(), "spec.rules[0].clauses[1].values[0]")
+	require.Contains(t, err.Error(), "spec.rules[1].clauses[0].values[1]")
+	require.Contains(t, err.Error(), "spec.rules[1].clauses[1].operator")
+	require.Contains(t, err.Error(), "spec.rules[1].clauses[2].values")
+}
+
+// TestValidateRules tests that invalid clauses are reported without resolving segments
+func TestValidateRules(t *testing.T) {
+	featureflag := newFeatureFlag(
+		newRule("green",
+			newClause("country", "like", "GB"),
+			newClause("", featurev1alpha1.OperatorInSegment, "beta-testers"),
+		),
+		newRule("green",
+			newClause("email", featurev1alpha1.OperatorMatches, "[a-"),
+			newClause("", featurev1alpha1.OperatorNotInSegment),
+		),
+	)
+
+	errs := ValidateRules(featureflag)
+
+	require.Len(t, errs, 3)
+	require.Equal(t, "spec.rules[0].clauses[0].operator", errs[0].Field)
+	require.Equal(t, "spec.rules[1].clauses[0].values[0]", errs[1].Field)
+	require.Equal(t, "spec.rules[1].clauses[1].values", errs[2].Field)
+}
+
+// TestEvaluate tests the order in which the flag state and rules are evaluated
+func TestEvaluate(t *testing.T) {
+	featureflag := newFeatureFlag(
+		newRule("green",
+			newClause("country", featurev1alpha1.OperatorIn, "GB"),
+			newClause("appVersion", featurev1alpha1.OperatorSemVerGreaterThan, "2.0.0"),
+		),
+		newRule("red",
+			newClause("country", featurev1alpha1.OperatorIn, "GB"),
+		),
+	)
+
+	flag, err := Compile(featureflag)
+	require.NoError(t, err)
+
+	require.Equal(t, Result{Variation: "green", Value: "#00ff00", Reason: ReasonRuleMatch, RuleIndex: 0},
+		flag.Evaluate(Context{"country": "GB", "appVersion": "2.1.0"}))
+	require.Equal(t, Result{Variation: "red", Value: "#ff0000", Reason: ReasonRuleMatch, RuleIndex: 1},
+		flag.Evaluate(Context{"country": "GB", "appVersion": "1.0.0"}))
+	require.Equal(t, Result{Variation: "blue", Value: "#0000ff", Reason: ReasonDefault, RuleIndex: -1},
+		flag.Evaluate(Context{"country": "FR"}))
+
+	featureflag.Spec.Enabled = false
+	flag, err = Compile(featureflag)
+	require.NoError(t, err)
+	require.Equal(t, Result{Variation: "red", Value: "#ff0000", Reason: ReasonOff, RuleIndex: -1},
+		flag.Evaluate(Context{"country": "GB", "appVersion": "2.1.0"}))
+}
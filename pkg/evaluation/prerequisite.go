@@ -0,0 +1,120 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluation
+
+import (
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// Set holds compiled flags by key so their prerequisites can be resolved.
+// Prerequisites missing from the Set are never met.
+type Set map[string]*Flag
+
+// NewSet returns a Set of the flags.
+func NewSet(flags ...*Flag) Set {
+	set := Set{}
+	for _, flag := range flags {
+		set[flag.Key()] = flag
+	}
+	return set
+}
+
+// Evaluate returns the variation the flag with the key serves to the
+// evaluation context, or false if the Set does not hold the flag.
+func (s Set) Evaluate(key string, ctx Context) (Result, bool) {
+	flag, ok := s[key]
+	if !ok {
+		return Result{}, false
+	}
+	return flag.evaluate(ctx, s, map[string]bool{}, false), true
+}
+
+// Default returns the variation the flag with the key serves to contexts
+// matching no rule, which is what consumers that do not evaluate rules are
+// given. Prerequisites are resolved against their own default variations.
+func (s Set) Default(key string) (Result, bool) {
+	flag, ok := s[key]
+	if !ok {
+		return Result{}, false
+	}
+	return flag.evaluate(nil, s, map[string]bool{}, true), true
+}
+
+// prerequisitesMet reports whether every prerequisite of the flag resolves to
+// its required variation, returning the first one that does not. A
+// prerequisite already being evaluated, i.e. a cycle, is not met.
+func (f *Flag) prerequisitesMet(ctx Context, flags Set, visiting map[string]bool, defaultOnly bool) (string, bool) {
+	visiting[f.key] = true
+	defer delete(visiting, f.key)
+
+	for _, p := range f.spec.Prerequisites {
+		prerequisite, ok := flags[p.Flag]
+		if !ok || visiting[p.Flag] {
+			return p.Flag, false
+		}
+		if prerequisite.evaluate(ctx, flags, visiting, defaultOnly).Variation != p.Variation {
+			return p.Flag, false
+		}
+	}
+	return "", true
+}
+
+// PrerequisiteNames returns the names of the prerequisite flags of a
+// FeatureFlag.
+func PrerequisiteNames(featureflag *featurev1alpha1.FeatureFlag) []string {
+	var names []string
+	for _, p := range featureflag.Spec.Prerequisites {
+		names = append(names, p.Flag)
+	}
+	return names
+}
+
+// FindCycle returns a prerequisite cycle reachable from the flag with the key,
+// as the keys along it with the first one repeated at the end, e.g.
+// [a b a]. prerequisites returns the prerequisite keys of a flag. It returns
+// nil if there is no cycle.
+func FindCycle(key string, prerequisites func(key string) []string) []string {
+	var path []string
+	onPath := map[string]bool{}
+	done := map[string]bool{}
+
+	var visit func(key string) []string
+	visit = func(key string) []string {
+		if onPath[key] {
+			for i, k := range path {
+				if k == key {
+					return append(append([]string{}, path[i:]...), key)
+				}
+			}
+		}
+		if done[key] {
+			return nil
+		}
+
+		onPath[key] = true
+		path = append(path, key)
+		for _, next := range prerequisites(key) {
+			if cycle := visit(next); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[key] = false
+		done[key] = true
+		return nil
+	}
+
+	return visit(key)
+}
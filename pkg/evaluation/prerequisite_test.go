@@ -0,0 +1,97 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+func newPrerequisiteFlag(name string, prerequisites ...featurev1alpha1.Prerequisite) *featurev1alpha1.FeatureFlag {
+	featureflag := newFeatureFlag()
+	featureflag.Name = name
+	featureflag.Spec.Prerequisites = prerequisites
+	return featureflag
+}
+
+func compileSet(t *testing.T, featureflags ...*featurev1alpha1.FeatureFlag) Set {
+	var flags []*Flag
+	for _, featureflag := range featureflags {
+		flag, err := Compile(featureflag)
+		require.NoError(t, err)
+		flags = append(flags, flag)
+	}
+	return NewSet(flags...)
+}
+
+// TestPrerequisites tests that a flag serves its off variation until every prerequisite resolves to its variation
+func TestPrerequisites(t *testing.T) {
+	api := newPrerequisiteFlag("new-api")
+	api.Spec.Rules = []featurev1alpha1.Rule{newRule("green", newClause("country", featurev1alpha1.OperatorIn, "GB"))}
+	checkout := newPrerequisiteFlag("new-checkout", featurev1alpha1.Prerequisite{Flag: "new-api", Variation: "green"})
+
+	flags := compileSet(t, api, checkout)
+
+	result, ok := flags.Evaluate("new-checkout", Context{"country": "GB"})
+	require.True(t, ok)
+	require.Equal(t, Result{Variation: "blue", Value: "#0000ff", Reason: ReasonDefault, RuleIndex: -1}, result)
+
+	result, _ = flags.Evaluate("new-checkout", Context{"country": "FR"})
+	require.Equal(t, Result{Variation: "red", Value: "#ff0000", Reason: ReasonPrerequisiteFailed, RuleIndex: -1, Prerequisite: "new-api"}, result)
+
+	// Contexts matching no rule are served the default of the prerequisite
+	result, _ = flags.Default("new-checkout")
+	require.Equal(t, ReasonPrerequisiteFailed, result.Reason)
+
+	// Without the Set the prerequisite cannot be resolved
+	require.Equal(t, ReasonPrerequisiteFailed, flags["new-checkout"].Evaluate(Context{"country": "GB"}).Reason)
+
+	_, ok = flags.Evaluate("unknown", Context{})
+	require.False(t, ok)
+}
+
+// TestTransitivePrerequisites tests that prerequisites of prerequisites are resolved, and that missing or disabled ones are not met
+func TestTransitivePrerequisites(t *testing.T) {
+	api := newPrerequisiteFlag("new-api")
+	backend := newPrerequisiteFlag("new-backend", featurev1alpha1.Prerequisite{Flag: "new-api", Variation: "blue"})
+	checkout := newPrerequisiteFlag("new-checkout", featurev1alpha1.Prerequisite{Flag: "new-backend", Variation: "blue"})
+
+	result, _ := compileSet(t, api, backend, checkout).Default("new-checkout")
+	require.Equal(t, ReasonDefault, result.Reason)
+
+	result, _ = compileSet(t, backend, checkout).Default("new-checkout")
+	require.Equal(t, Result{Variation: "red", Value: "#ff0000", Reason: ReasonPrerequisiteFailed, RuleIndex: -1, Prerequisite: "new-backend"}, result)
+
+	api.Spec.Enabled = false
+	result, _ = compileSet(t, api, backend, checkout).Default("new-checkout")
+	require.Equal(t, ReasonPrerequisiteFailed, result.Reason)
+}
+
+// TestPrerequisiteCycleEvaluation tests that evaluating a cycle terminates with the prerequisite not met
+func TestPrerequisiteCycleEvaluation(t *testing.T) {
+	a := newPrerequisiteFlag("a", featurev1alpha1.Prerequisite{Flag: "b", Variation: "blue"})
+	b := newPrerequisiteFlag("b", featurev1alpha1.Prerequisite{Flag: "a", Variation: "blue"})
+
+	result, _ := compileSet(t, a, b).Evaluate("a", Context{})
+	require.Equal(t, ReasonPrerequisiteFailed, result.Reason)
+}
+
+// TestFindCycle tests that cycles reachable from a flag are found
+func TestFindCycle(t *testing.T) {
+	graph := map[string][]string{
+		"checkout": {"api", "ui"},
+		"api":      {"db"},
+		"ui":       {"api"},
+		"a":        {"b"},
+		"b":        {"c"},
+		"c":        {"a"},
+		"d":        {"b"},
+	}
+	prerequisites := func(key string) []string { return graph[key] }
+
+	require.Nil(t, FindCycle("checkout", prerequisites))
+	require.Nil(t, FindCycle("unknown", prerequisites))
+	require.Equal(t, []string{"a", "b", "c", "a"}, FindCycle("a", prerequisites))
+	require.Equal(t, []string{"b", "c", "a", "b"}, FindCycle("d", prerequisites))
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluation
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+const (
+	// BucketCount is the number of buckets contexts are hashed into. Each
+	// percent of a rollout covers BucketCount/100 buckets.
+	BucketCount = 100000
+
+	// DefaultBucketBy is the context attribute used when a Rollout does not
+	// set BucketBy.
+	DefaultBucketBy = "key"
+)
+
+// Bucket returns the bucket in [0, BucketCount) of a context attribute value
+// for a flag. It is the first four bytes of
+//
+//	sha256(flagKey + "." + salt + "." + value)
+//
+// read as a big-endian unsigned integer, modulo BucketCount. SDKs must use the
+// same computation so a context lands in the same bucket everywhere.
+func Bucket(flagKey, salt, value string) int {
+	sum := sha256.Sum256([]byte(flagKey + "." + salt + "." + value))
+	return int(binary.BigEndian.Uint32(sum[:4]) % BucketCount)
+}
+
+// rollout is a compiled Rollout. Variation i is served to the buckets in
+// [thresholds[i-1], thresholds[i]), so moving weight between variations i
+// and i+1 only moves contexts between them. Any other change of weight
+// shifts the thresholds of the variations in between as well.
+type rollout struct {
+	flagKey    string
+	bucketBy   string
+	salt       string
+	variations []string
+	thresholds []int
+}
+
+func compileRollout(flagKey string, r *featurev1alpha1.Rollout) *rollout {
+	compiled := &rollout{
+		flagKey:  flagKey,
+		bucketBy: r.BucketBy,
+		salt:     r.Salt,
+	}
+	if compiled.bucketBy == "" {
+		compiled.bucketBy = DefaultBucketBy
+	}
+	if compiled.salt == "" {
+		compiled.salt = flagKey
+	}
+
+	threshold := 0
+	for _, wv := range r.Variations {
+		threshold += int(wv.Weight) * (BucketCount / 100)
+		compiled.variations = append(compiled.variations, wv.Variation)
+		compiled.thresholds = append(compiled.thresholds, threshold)
+	}
+	return compiled
+}
+
+// variation returns the variation for the context, or false if the context
+// does not have the bucketing attribute.
+func (r *rollout) variation(ctx Context) (string, bool) {
+	value, ok := ctx[r.bucketBy]
+	if !ok {
+		return "", false
+	}
+
+	bucket := Bucket(r.flagKey, r.salt, value)
+	for i, threshold := range r.thresholds {
+		if bucket < threshold {
+			return r.variations[i], true
+		}
+	}
+	// Only reachable if the weights add up to less than 100, which
+	// validation rejects.
+	return "", false
+}
@@ -0,0 +1,159 @@
+package evaluation
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+const (
+	// distributionKeys is the number of keys bucketed by the distribution tests
+	distributionKeys = 100000
+	// distributionTolerance is the maximum difference allowed between the
+	// expected and actual share of keys served a variation, in percent
+	distributionTolerance = 0.5
+)
+
+func newRollout(weights ...int32) *featurev1alpha1.Rollout {
+	names := []string{"blue", "green", "red"}
+	r := &featurev1alpha1.Rollout{}
+	for i, w := range weights {
+		r.Variations = append(r.Variations, featurev1alpha1.WeightedVariation{Variation: names[i], Weight: w})
+	}
+	return r
+}
+
+// TestBucket tests that buckets never change. The expected values were
+// computed independently and must hold for every SDK; changing them would
+// reshuffle every rollout.
+func TestBucket(t *testing.T) {
+	require.Equal(t, 37572, Bucket("new-checkout", "new-checkout", "user-1"))
+	require.Equal(t, 56070, Bucket("new-checkout", "new-checkout", "user-2"))
+	require.Equal(t, 97536, Bucket("new-checkout", "new-checkout", "tenant-42"))
+	require.Equal(t, 35177, Bucket("new-checkout", "2020-04", "user-1"))
+}
+
+// TestRolloutDistribution tests that splits stay within tolerance over 100k keys
+func TestRolloutDistribution(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int32
+	}{
+		{"10/90", []int32{10, 90}},
+		{"1/99", []int32{1, 99}},
+		{"50/50", []int32{50, 50}},
+		{"20/30/50", []int32{20, 30, 50}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			featureflag := newFeatureFlag()
+			featureflag.Spec.DefaultRollout = newRollout(test.weights...)
+			flag, err := Compile(featureflag)
+			require.NoError(t, err)
+
+			counts := map[string]int{}
+			for i := 0; i < distributionKeys; i++ {
+				counts[flag.Evaluate(Context{"key": fmt.Sprintf("user-%d", i)}).Variation]++
+			}
+
+			for _, wv := range featureflag.Spec.DefaultRollout.Variations {
+				share := float64(counts[wv.Variation]) * 100 / distributionKeys
+				require.LessOrEqual(t, math.Abs(share-float64(wv.Weight)), distributionTolerance,
+					"variation %s served to %.2f%% of keys, expected %d%%", wv.Variation, share, wv.Weight)
+			}
+		})
+	}
+}
+
+// TestRolloutMonotonic tests that raising a percentage only moves keys to the
+// raised variation, whichever order the variations are listed in
+func TestRolloutMonotonic(t *testing.T) {
+	for _, onFirst := range []bool{true, false} {
+		t.Run(fmt.Sprintf("onFirst=%t", onFirst), func(t *testing.T) {
+			served := func(percent int32) map[string]bool {
+				featureflag := newFeatureFlag()
+				on := featurev1alpha1.WeightedVariation{Variation: "green", Weight: percent}
+				off := featurev1alpha1.WeightedVariation{Variation: "red", Weight: 100 - percent}
+				featureflag.Spec.DefaultRollout = &featurev1alpha1.Rollout{Variations: []featurev1alpha1.WeightedVariation{off, on}}
+				if onFirst {
+					featureflag.Spec.DefaultRollout.Variations = []featurev1alpha1.WeightedVariation{on, off}
+				}
+				flag, err := Compile(featureflag)
+				require.NoError(t, err)
+
+				result := map[string]bool{}
+				for i := 0; i < 10000; i++ {
+					key := fmt.Sprintf("user-%d", i)
+					result[key] = flag.Evaluate(Context{"key": key}).Variation == "green"
+				}
+				return result
+			}
+
+			previous := served(0)
+			for _, percent := range []int32{1, 5, 25, 50, 100} {
+				current := served(percent)
+				for key, on := range previous {
+					if on {
+						require.True(t, current[key], "%s was moved off when raising to %d%%", key, percent)
+					}
+				}
+				previous = current
+			}
+		})
+	}
+}
+
+// TestRolloutBucketBy tests bucketing by a configurable attribute and salt
+func TestRolloutBucketBy(t *testing.T) {
+	featureflag := newFeatureFlag()
+	featureflag.Spec.DefaultRollout = newRollout(50, 50)
+	featureflag.Spec.DefaultRollout.BucketBy = "tenantId"
+	flag, err := Compile(featureflag)
+	require.NoError(t, err)
+
+	// Every user of a tenant is served the same variation
+	tenants := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		tenant := fmt.Sprintf("tenant-%d", i%10)
+		variation := flag.Evaluate(Context{"key": fmt.Sprintf("user-%d", i), "tenantId": tenant}).Variation
+		tenants[tenant+"/"+variation] = true
+	}
+	require.Len(t, tenants, 10)
+
+	// Contexts without the attribute are served the default variation
+	require.Equal(t, Result{Variation: "blue", Value: "#0000ff", Reason: ReasonDefault, RuleIndex: -1},
+		flag.Evaluate(Context{"key": "user-1"}))
+
+	// A different salt reshuffles the buckets
+	salted := featureflag.DeepCopy()
+	salted.Spec.DefaultRollout.Salt = "2020-04"
+	saltedFlag, err := Compile(salted)
+	require.NoError(t, err)
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		ctx := Context{"tenantId": fmt.Sprintf("tenant-%d", i)}
+		if flag.Evaluate(ctx).Variation != saltedFlag.Evaluate(ctx).Variation {
+			moved++
+		}
+	}
+	require.InDelta(t, 500, moved, 100)
+}
+
+// TestRuleRollout tests that a matching rule can serve a rollout
+func TestRuleRollout(t *testing.T) {
+	rule := newRule("", newClause("country", featurev1alpha1.OperatorIn, "GB"))
+	rule.Rollout = newRollout(0, 100)
+	flag, err := Compile(newFeatureFlag(rule))
+	require.NoError(t, err)
+
+	require.Equal(t, Result{Variation: "green", Value: "#00ff00", Reason: ReasonRuleMatch, RuleIndex: 0},
+		flag.Evaluate(Context{"key": "user-1", "country": "GB"}))
+	require.Equal(t, Result{Variation: "blue", Value: "#0000ff", Reason: ReasonDefault, RuleIndex: -1},
+		flag.Evaluate(Context{"key": "user-1", "country": "FR"}))
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluation
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// SegmentKeyAttribute is the context attribute matched against the Included
+// and Excluded keys of a FeatureSegment.
+const SegmentKeyAttribute = "key"
+
+// segment is a compiled FeatureSegment.
+type segment struct {
+	included map[string]bool
+	excluded map[string]bool
+	rules    [][]clause
+}
+
+// CompileSegment compiles the rules of a FeatureSegment, returning every
+// invalid clause found.
+func CompileSegment(featuresegment *featurev1alpha1.FeatureSegment) error {
+	_, errs := compileSegment(featuresegment)
+	return errs.ToAggregate()
+}
+
+func compileSegment(featuresegment *featurev1alpha1.FeatureSegment) (*segment, field.ErrorList) {
+	allErrs := field.ErrorList{}
+	compiled := &segment{
+		included: map[string]bool{},
+		excluded: map[string]bool{},
+	}
+
+	for _, key := range featuresegment.Spec.Included {
+		compiled.included[key] = true
+	}
+	for _, key := range featuresegment.Spec.Excluded {
+		compiled.excluded[key] = true
+	}
+
+	rulesPath := field.NewPath("spec", "rules")
+	for i, r := range featuresegment.Spec.Rules {
+		var clauses []clause
+		for j, c := range r.Clauses {
+			clausePath := rulesPath.Index(i).Child("clauses").Index(j)
+			if isSegmentOperator(c.Operator) {
+				allErrs = append(allErrs, field.Forbidden(clausePath.Child("operator"), "segment rules cannot reference segments"))
+				continue
+			}
+			cl, errs := compileClause(c, clausePath, nil)
+			allErrs = append(allErrs, errs...)
+			clauses = append(clauses, cl)
+		}
+		compiled.rules = append(compiled.rules, clauses)
+	}
+
+	return compiled, allErrs
+}
+
+// contains reports whether the context is in the segment.
+func (s *segment) contains(ctx Context) bool {
+	if key, ok := ctx[SegmentKeyAttribute]; ok {
+		if s.included[key] {
+			return true
+		}
+		if s.excluded[key] {
+			return false
+		}
+	}
+
+	for _, clauses := range s.rules {
+		if (rule{clauses: clauses}).matches(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSegmentOperator(op featurev1alpha1.Operator) bool {
+	return op == featurev1alpha1.OperatorInSegment || op == featurev1alpha1.OperatorNotInSegment
+}
+
+// SegmentNames returns the names of the FeatureSegments referenced by the
+// rules of a FeatureFlag.
+func SegmentNames(featureflag *featurev1alpha1.FeatureFlag) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, r := range featureflag.Spec.Rules {
+		for _, c := range r.Clauses {
+			if !isSegmentOperator(c.Operator) {
+				continue
+			}
+			for _, name := range c.Values {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names
+}
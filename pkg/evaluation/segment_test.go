@@ -0,0 +1,95 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+func newSegment(name string, spec featurev1alpha1.FeatureSegmentSpec) *featurev1alpha1.FeatureSegment {
+	return &featurev1alpha1.FeatureSegment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault},
+		Spec:       spec,
+	}
+}
+
+// TestSegment tests that included keys win over excluded keys, which win over rules
+func TestSegment(t *testing.T) {
+	segment := newSegment("beta-testers", featurev1alpha1.FeatureSegmentSpec{
+		Included: []string{"user-1"},
+		Excluded: []string{"user-1", "user-2"},
+		Rules: []featurev1alpha1.SegmentRule{
+			{Clauses: []featurev1alpha1.Clause{newClause("email", featurev1alpha1.OperatorEndsWith, "@example.com")}},
+		},
+	})
+	featureflag := newFeatureFlag(newRule("green", newClause("", featurev1alpha1.OperatorInSegment, "beta-testers")))
+
+	flag, err := Compile(featureflag, segment)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		ctx          Context
+		expVariation string
+	}{
+		{"included key", Context{"key": "user-1"}, "green"},
+		{"excluded key matching a rule", Context{"key": "user-2", "email": "jo@example.com"}, "blue"},
+		{"key matching a rule", Context{"key": "user-3", "email": "jo@example.com"}, "green"},
+		{"context without a key matching a rule", Context{"email": "jo@example.com"}, "green"},
+		{"key matching nothing", Context{"key": "user-3", "email": "jo@example.org"}, "blue"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expVariation, flag.Evaluate(test.ctx).Variation)
+		})
+	}
+}
+
+// TestNotInSegment tests that notInSegment matches contexts in none of the segments
+func TestNotInSegment(t *testing.T) {
+	internal := newSegment("internal", featurev1alpha1.FeatureSegmentSpec{Included: []string{"user-1"}})
+	beta := newSegment("beta-testers", featurev1alpha1.FeatureSegmentSpec{Included: []string{"user-2"}})
+	featureflag := newFeatureFlag(newRule("green", newClause("", featurev1alpha1.OperatorNotInSegment, "internal", "beta-testers")))
+
+	flag, err := Compile(featureflag, internal, beta)
+	require.NoError(t, err)
+
+	require.Equal(t, "blue", flag.Evaluate(Context{"key": "user-1"}).Variation)
+	require.Equal(t, "blue", flag.Evaluate(Context{"key": "user-2"}).Variation)
+	require.Equal(t, "green", flag.Evaluate(Context{"key": "user-3"}).Variation)
+}
+
+// TestSegmentCompileErrors tests that missing and invalid segments are reported
+func TestSegmentCompileErrors(t *testing.T) {
+	nested := newSegment("nested", featurev1alpha1.FeatureSegmentSpec{
+		Rules: []featurev1alpha1.SegmentRule{
+			{Clauses: []featurev1alpha1.Clause{newClause("", featurev1alpha1.OperatorInSegment, "internal")}},
+		},
+	})
+	featureflag := newFeatureFlag(newRule("green", newClause("", featurev1alpha1.OperatorInSegment, "nested", "missing")))
+
+	_, err := Compile(featureflag, nested)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "segments[nested]")
+	require.Contains(t, err.Error(), "segment rules cannot reference segments")
+	require.Contains(t, err.Error(), "spec.rules[0].clauses[0].values[1]")
+	require.Error(t, CompileSegment(nested))
+}
+
+// TestSegmentNames tests that every referenced segment is returned once
+func TestSegmentNames(t *testing.T) {
+	featureflag := newFeatureFlag(
+		newRule("green", newClause("", featurev1alpha1.OperatorInSegment, "internal", "beta-testers")),
+		newRule("red",
+			newClause("country", featurev1alpha1.OperatorIn, "GB"),
+			newClause("", featurev1alpha1.OperatorNotInSegment, "internal"),
+		),
+	)
+
+	require.Equal(t, []string{"internal", "beta-testers"}, SegmentNames(featureflag))
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	featurecontrollerv1alpha1 "github.com/featured.io/pkg/generated/clientset/versioned/typed/feature/v1alpha1"
+	featurecontrollerv1beta1 "github.com/featured.io/pkg/generated/clientset/versioned/typed/feature/v1beta1"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -30,6 +31,7 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	FeaturecontrollerV1alpha1() featurecontrollerv1alpha1.FeaturecontrollerV1alpha1Interface
+	FeaturecontrollerV1beta1() featurecontrollerv1beta1.FeaturecontrollerV1beta1Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
@@ -37,6 +39,7 @@ type Interface interface {
 type Clientset struct {
 	*discovery.DiscoveryClient
 	featurecontrollerV1alpha1 *featurecontrollerv1alpha1.FeaturecontrollerV1alpha1Client
+	featurecontrollerV1beta1  *featurecontrollerv1beta1.FeaturecontrollerV1beta1Client
 }
 
 // FeaturecontrollerV1alpha1 retrieves the FeaturecontrollerV1alpha1Client
@@ -44,6 +47,11 @@ func (c *Clientset) FeaturecontrollerV1alpha1() featurecontrollerv1alpha1.Featur
 	return c.featurecontrollerV1alpha1
 }
 
+// FeaturecontrollerV1beta1 retrieves the FeaturecontrollerV1beta1Client
+func (c *Clientset) FeaturecontrollerV1beta1() featurecontrollerv1beta1.FeaturecontrollerV1beta1Interface {
+	return c.featurecontrollerV1beta1
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -69,6 +77,10 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs.featurecontrollerV1beta1, err = featurecontrollerv1beta1.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
 	if err != nil {
@@ -82,6 +94,7 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
 	cs.featurecontrollerV1alpha1 = featurecontrollerv1alpha1.NewForConfigOrDie(c)
+	cs.featurecontrollerV1beta1 = featurecontrollerv1beta1.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -91,6 +104,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.featurecontrollerV1alpha1 = featurecontrollerv1alpha1.New(c)
+	cs.featurecontrollerV1beta1 = featurecontrollerv1beta1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
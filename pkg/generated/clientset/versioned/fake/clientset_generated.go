@@ -22,6 +22,8 @@ import (
 	clientset "github.com/featured.io/pkg/generated/clientset/versioned"
 	featurecontrollerv1alpha1 "github.com/featured.io/pkg/generated/clientset/versioned/typed/feature/v1alpha1"
 	fakefeaturecontrollerv1alpha1 "github.com/featured.io/pkg/generated/clientset/versioned/typed/feature/v1alpha1/fake"
+	featurecontrollerv1beta1 "github.com/featured.io/pkg/generated/clientset/versioned/typed/feature/v1beta1"
+	fakefeaturecontrollerv1beta1 "github.com/featured.io/pkg/generated/clientset/versioned/typed/feature/v1beta1/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -80,3 +82,8 @@ var _ clientset.Interface = &Clientset{}
 func (c *Clientset) FeaturecontrollerV1alpha1() featurecontrollerv1alpha1.FeaturecontrollerV1alpha1Interface {
 	return &fakefeaturecontrollerv1alpha1.FakeFeaturecontrollerV1alpha1{Fake: &c.Fake}
 }
+
+// FeaturecontrollerV1beta1 retrieves the FeaturecontrollerV1beta1Client
+func (c *Clientset) FeaturecontrollerV1beta1() featurecontrollerv1beta1.FeaturecontrollerV1beta1Interface {
+	return &fakefeaturecontrollerv1beta1.FakeFeaturecontrollerV1beta1{Fake: &c.Fake}
+}
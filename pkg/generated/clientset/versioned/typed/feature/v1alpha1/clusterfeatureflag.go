@@ -0,0 +1,184 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	scheme "github.com/featured.io/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// ClusterFeatureFlagsGetter has a method to return a ClusterFeatureFlagInterface.
+// A group's client should implement this interface.
+type ClusterFeatureFlagsGetter interface {
+	ClusterFeatureFlags() ClusterFeatureFlagInterface
+}
+
+// ClusterFeatureFlagInterface has methods to work with ClusterFeatureFlag resources.
+type ClusterFeatureFlagInterface interface {
+	Create(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.CreateOptions) (*v1alpha1.ClusterFeatureFlag, error)
+	Update(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.UpdateOptions) (*v1alpha1.ClusterFeatureFlag, error)
+	UpdateStatus(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.UpdateOptions) (*v1alpha1.ClusterFeatureFlag, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.ClusterFeatureFlag, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.ClusterFeatureFlagList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.ClusterFeatureFlag, err error)
+	ClusterFeatureFlagExpansion
+}
+
+// clusterFeatureFlags implements ClusterFeatureFlagInterface
+type clusterFeatureFlags struct {
+	client rest.Interface
+}
+
+// newClusterFeatureFlags returns a ClusterFeatureFlags
+func newClusterFeatureFlags(c *FeaturecontrollerV1alpha1Client) *clusterFeatureFlags {
+	return &clusterFeatureFlags{
+		client: c.RESTClient(),
+	}
+}
+
+// Get takes name of the clusterFeatureFlag, and returns the corresponding clusterFeatureFlag object, and an error if there is any.
+func (c *clusterFeatureFlags) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	result = &v1alpha1.ClusterFeatureFlag{}
+	err = c.client.Get().
+		Resource("clusterfeatureflags").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of ClusterFeatureFlags that match those selectors.
+func (c *clusterFeatureFlags) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.ClusterFeatureFlagList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.ClusterFeatureFlagList{}
+	err = c.client.Get().
+		Resource("clusterfeatureflags").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested clusterFeatureFlags.
+func (c *clusterFeatureFlags) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("clusterfeatureflags").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a clusterFeatureFlag and creates it.  Returns the server's representation of the clusterFeatureFlag, and an error, if there is any.
+func (c *clusterFeatureFlags) Create(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.CreateOptions) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	result = &v1alpha1.ClusterFeatureFlag{}
+	err = c.client.Post().
+		Resource("clusterfeatureflags").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(clusterFeatureFlag).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a clusterFeatureFlag and updates it. Returns the server's representation of the clusterFeatureFlag, and an error, if there is any.
+func (c *clusterFeatureFlags) Update(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.UpdateOptions) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	result = &v1alpha1.ClusterFeatureFlag{}
+	err = c.client.Put().
+		Resource("clusterfeatureflags").
+		Name(clusterFeatureFlag.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(clusterFeatureFlag).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *clusterFeatureFlags) UpdateStatus(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.UpdateOptions) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	result = &v1alpha1.ClusterFeatureFlag{}
+	err = c.client.Put().
+		Resource("clusterfeatureflags").
+		Name(clusterFeatureFlag.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(clusterFeatureFlag).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the clusterFeatureFlag and deletes it. Returns an error if one occurs.
+func (c *clusterFeatureFlags) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("clusterfeatureflags").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *clusterFeatureFlags) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Resource("clusterfeatureflags").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched clusterFeatureFlag.
+func (c *clusterFeatureFlags) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	result = &v1alpha1.ClusterFeatureFlag{}
+	err = c.client.Patch(pt).
+		Resource("clusterfeatureflags").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
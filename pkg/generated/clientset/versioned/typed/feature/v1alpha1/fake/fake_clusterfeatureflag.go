@@ -0,0 +1,133 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeClusterFeatureFlags implements ClusterFeatureFlagInterface
+type FakeClusterFeatureFlags struct {
+	Fake *FakeFeaturecontrollerV1alpha1
+}
+
+var clusterfeatureflagsResource = schema.GroupVersionResource{Group: "featurecontroller.featured.io", Version: "v1alpha1", Resource: "clusterfeatureflags"}
+
+var clusterfeatureflagsKind = schema.GroupVersionKind{Group: "featurecontroller.featured.io", Version: "v1alpha1", Kind: "ClusterFeatureFlag"}
+
+// Get takes name of the clusterFeatureFlag, and returns the corresponding clusterFeatureFlag object, and an error if there is any.
+func (c *FakeClusterFeatureFlags) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(clusterfeatureflagsResource, name), &v1alpha1.ClusterFeatureFlag{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterFeatureFlag), err
+}
+
+// List takes label and field selectors, and returns the list of ClusterFeatureFlags that match those selectors.
+func (c *FakeClusterFeatureFlags) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.ClusterFeatureFlagList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(clusterfeatureflagsResource, clusterfeatureflagsKind, opts), &v1alpha1.ClusterFeatureFlagList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.ClusterFeatureFlagList{ListMeta: obj.(*v1alpha1.ClusterFeatureFlagList).ListMeta}
+	for _, item := range obj.(*v1alpha1.ClusterFeatureFlagList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested clusterFeatureFlags.
+func (c *FakeClusterFeatureFlags) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(clusterfeatureflagsResource, opts))
+}
+
+// Create takes the representation of a clusterFeatureFlag and creates it.  Returns the server's representation of the clusterFeatureFlag, and an error, if there is any.
+func (c *FakeClusterFeatureFlags) Create(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.CreateOptions) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(clusterfeatureflagsResource, clusterFeatureFlag), &v1alpha1.ClusterFeatureFlag{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterFeatureFlag), err
+}
+
+// Update takes the representation of a clusterFeatureFlag and updates it. Returns the server's representation of the clusterFeatureFlag, and an error, if there is any.
+func (c *FakeClusterFeatureFlags) Update(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.UpdateOptions) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(clusterfeatureflagsResource, clusterFeatureFlag), &v1alpha1.ClusterFeatureFlag{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterFeatureFlag), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeClusterFeatureFlags) UpdateStatus(ctx context.Context, clusterFeatureFlag *v1alpha1.ClusterFeatureFlag, opts v1.UpdateOptions) (*v1alpha1.ClusterFeatureFlag, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(clusterfeatureflagsResource, "status", clusterFeatureFlag), &v1alpha1.ClusterFeatureFlag{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterFeatureFlag), err
+}
+
+// Delete takes name of the clusterFeatureFlag and deletes it. Returns an error if one occurs.
+func (c *FakeClusterFeatureFlags) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(clusterfeatureflagsResource, name), &v1alpha1.ClusterFeatureFlag{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeClusterFeatureFlags) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(clusterfeatureflagsResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.ClusterFeatureFlagList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched clusterFeatureFlag.
+func (c *FakeClusterFeatureFlags) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.ClusterFeatureFlag, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(clusterfeatureflagsResource, name, pt, data, subresources...), &v1alpha1.ClusterFeatureFlag{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterFeatureFlag), err
+}
@@ -28,10 +28,18 @@ type FakeFeaturecontrollerV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeFeaturecontrollerV1alpha1) ClusterFeatureFlags() v1alpha1.ClusterFeatureFlagInterface {
+	return &FakeClusterFeatureFlags{c}
+}
+
 func (c *FakeFeaturecontrollerV1alpha1) FeatureFlags(namespace string) v1alpha1.FeatureFlagInterface {
 	return &FakeFeatureFlags{c, namespace}
 }
 
+func (c *FakeFeaturecontrollerV1alpha1) FeatureSegments(namespace string) v1alpha1.FeatureSegmentInterface {
+	return &FakeFeatureSegments{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeFeaturecontrollerV1alpha1) RESTClient() rest.Interface {
@@ -26,7 +26,9 @@ import (
 
 type FeaturecontrollerV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterFeatureFlagsGetter
 	FeatureFlagsGetter
+	FeatureSegmentsGetter
 }
 
 // FeaturecontrollerV1alpha1Client is used to interact with features provided by the featurecontroller.featured.io group.
@@ -34,10 +36,18 @@ type FeaturecontrollerV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *FeaturecontrollerV1alpha1Client) ClusterFeatureFlags() ClusterFeatureFlagInterface {
+	return newClusterFeatureFlags(c)
+}
+
 func (c *FeaturecontrollerV1alpha1Client) FeatureFlags(namespace string) FeatureFlagInterface {
 	return newFeatureFlags(c, namespace)
 }
 
+func (c *FeaturecontrollerV1alpha1Client) FeatureSegments(namespace string) FeatureSegmentInterface {
+	return newFeatureSegments(c, namespace)
+}
+
 // NewForConfig creates a new FeaturecontrollerV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*FeaturecontrollerV1alpha1Client, error) {
 	config := *c
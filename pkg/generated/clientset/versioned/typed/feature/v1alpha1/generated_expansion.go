@@ -18,4 +18,8 @@ limitations under the License.
 
 package v1alpha1
 
+type ClusterFeatureFlagExpansion interface{}
+
 type FeatureFlagExpansion interface{}
+
+type FeatureSegmentExpansion interface{}
@@ -0,0 +1,130 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1beta1 "github.com/featured.io/pkg/apis/feature/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeFeatureSegments implements FeatureSegmentInterface
+type FakeFeatureSegments struct {
+	Fake *FakeFeaturecontrollerV1beta1
+	ns   string
+}
+
+var featuresegmentsResource = schema.GroupVersionResource{Group: "featurecontroller.featured.io", Version: "v1beta1", Resource: "featuresegments"}
+
+var featuresegmentsKind = schema.GroupVersionKind{Group: "featurecontroller.featured.io", Version: "v1beta1", Kind: "FeatureSegment"}
+
+// Get takes name of the featureSegment, and returns the corresponding featureSegment object, and an error if there is any.
+func (c *FakeFeatureSegments) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1beta1.FeatureSegment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(featuresegmentsResource, c.ns, name), &v1beta1.FeatureSegment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.FeatureSegment), err
+}
+
+// List takes label and field selectors, and returns the list of FeatureSegments that match those selectors.
+func (c *FakeFeatureSegments) List(ctx context.Context, opts v1.ListOptions) (result *v1beta1.FeatureSegmentList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(featuresegmentsResource, featuresegmentsKind, c.ns, opts), &v1beta1.FeatureSegmentList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1beta1.FeatureSegmentList{ListMeta: obj.(*v1beta1.FeatureSegmentList).ListMeta}
+	for _, item := range obj.(*v1beta1.FeatureSegmentList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested featureSegments.
+func (c *FakeFeatureSegments) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(featuresegmentsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a featureSegment and creates it.  Returns the server's representation of the featureSegment, and an error, if there is any.
+func (c *FakeFeatureSegments) Create(ctx context.Context, featureSegment *v1beta1.FeatureSegment, opts v1.CreateOptions) (result *v1beta1.FeatureSegment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(featuresegmentsResource, c.ns, featureSegment), &v1beta1.FeatureSegment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.FeatureSegment), err
+}
+
+// Update takes the representation of a featureSegment and updates it. Returns the server's representation of the featureSegment, and an error, if there is any.
+func (c *FakeFeatureSegments) Update(ctx context.Context, featureSegment *v1beta1.FeatureSegment, opts v1.UpdateOptions) (result *v1beta1.FeatureSegment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(featuresegmentsResource, c.ns, featureSegment), &v1beta1.FeatureSegment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.FeatureSegment), err
+}
+
+// Delete takes name of the featureSegment and deletes it. Returns an error if one occurs.
+func (c *FakeFeatureSegments) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(featuresegmentsResource, c.ns, name), &v1beta1.FeatureSegment{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeFeatureSegments) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(featuresegmentsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1beta1.FeatureSegmentList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched featureSegment.
+func (c *FakeFeatureSegments) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.FeatureSegment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(featuresegmentsResource, c.ns, name, pt, data, subresources...), &v1beta1.FeatureSegment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.FeatureSegment), err
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"context"
+	"time"
+
+	v1beta1 "github.com/featured.io/pkg/apis/feature/v1beta1"
+	scheme "github.com/featured.io/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// FeatureFlagsGetter has a method to return a FeatureFlagInterface.
+// A group's client should implement this interface.
+type FeatureFlagsGetter interface {
+	FeatureFlags(namespace string) FeatureFlagInterface
+}
+
+// FeatureFlagInterface has methods to work with FeatureFlag resources.
+type FeatureFlagInterface interface {
+	Create(ctx context.Context, featureFlag *v1beta1.FeatureFlag, opts v1.CreateOptions) (*v1beta1.FeatureFlag, error)
+	Update(ctx context.Context, featureFlag *v1beta1.FeatureFlag, opts v1.UpdateOptions) (*v1beta1.FeatureFlag, error)
+	UpdateStatus(ctx context.Context, featureFlag *v1beta1.FeatureFlag, opts v1.UpdateOptions) (*v1beta1.FeatureFlag, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1beta1.FeatureFlag, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1beta1.FeatureFlagList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.FeatureFlag, err error)
+	FeatureFlagExpansion
+}
+
+// featureFlags implements FeatureFlagInterface
+type featureFlags struct {
+	client rest.Interface
+	ns     string
+}
+
+// newFeatureFlags returns a FeatureFlags
+func newFeatureFlags(c *FeaturecontrollerV1beta1Client, namespace string) *featureFlags {
+	return &featureFlags{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the featureFlag, and returns the corresponding featureFlag object, and an error if there is any.
+func (c *featureFlags) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1beta1.FeatureFlag, err error) {
+	result = &v1beta1.FeatureFlag{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("featureflags").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of FeatureFlags that match those selectors.
+func (c *featureFlags) List(ctx context.Context, opts v1.ListOptions) (result *v1beta1.FeatureFlagList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1beta1.FeatureFlagList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("featureflags").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested featureFlags.
+func (c *featureFlags) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("featureflags").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a featureFlag and creates it.  Returns the server's representation of the featureFlag, and an error, if there is any.
+func (c *featureFlags) Create(ctx context.Context, featureFlag *v1beta1.FeatureFlag, opts v1.CreateOptions) (result *v1beta1.FeatureFlag, err error) {
+	result = &v1beta1.FeatureFlag{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("featureflags").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(featureFlag).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a featureFlag and updates it. Returns the server's representation of the featureFlag, and an error, if there is any.
+func (c *featureFlags) Update(ctx context.Context, featureFlag *v1beta1.FeatureFlag, opts v1.UpdateOptions) (result *v1beta1.FeatureFlag, err error) {
+	result = &v1beta1.FeatureFlag{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("featureflags").
+		Name(featureFlag.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(featureFlag).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *featureFlags) UpdateStatus(ctx context.Context, featureFlag *v1beta1.FeatureFlag, opts v1.UpdateOptions) (result *v1beta1.FeatureFlag, err error) {
+	result = &v1beta1.FeatureFlag{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("featureflags").
+		Name(featureFlag.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(featureFlag).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the featureFlag and deletes it. Returns an error if one occurs.
+func (c *featureFlags) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("featureflags").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *featureFlags) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("featureflags").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched featureFlag.
+func (c *featureFlags) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.FeatureFlag, err error) {
+	result = &v1beta1.FeatureFlag{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("featureflags").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
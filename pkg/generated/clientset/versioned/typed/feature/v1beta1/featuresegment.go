@@ -0,0 +1,178 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"context"
+	"time"
+
+	v1beta1 "github.com/featured.io/pkg/apis/feature/v1beta1"
+	scheme "github.com/featured.io/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// FeatureSegmentsGetter has a method to return a FeatureSegmentInterface.
+// A group's client should implement this interface.
+type FeatureSegmentsGetter interface {
+	FeatureSegments(namespace string) FeatureSegmentInterface
+}
+
+// FeatureSegmentInterface has methods to work with FeatureSegment resources.
+type FeatureSegmentInterface interface {
+	Create(ctx context.Context, featureSegment *v1beta1.FeatureSegment, opts v1.CreateOptions) (*v1beta1.FeatureSegment, error)
+	Update(ctx context.Context, featureSegment *v1beta1.FeatureSegment, opts v1.UpdateOptions) (*v1beta1.FeatureSegment, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1beta1.FeatureSegment, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1beta1.FeatureSegmentList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.FeatureSegment, err error)
+	FeatureSegmentExpansion
+}
+
+// featureSegments implements FeatureSegmentInterface
+type featureSegments struct {
+	client rest.Interface
+	ns     string
+}
+
+// newFeatureSegments returns a FeatureSegments
+func newFeatureSegments(c *FeaturecontrollerV1beta1Client, namespace string) *featureSegments {
+	return &featureSegments{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the featureSegment, and returns the corresponding featureSegment object, and an error if there is any.
+func (c *featureSegments) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1beta1.FeatureSegment, err error) {
+	result = &v1beta1.FeatureSegment{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("featuresegments").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of FeatureSegments that match those selectors.
+func (c *featureSegments) List(ctx context.Context, opts v1.ListOptions) (result *v1beta1.FeatureSegmentList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1beta1.FeatureSegmentList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("featuresegments").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested featureSegments.
+func (c *featureSegments) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("featuresegments").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a featureSegment and creates it.  Returns the server's representation of the featureSegment, and an error, if there is any.
+func (c *featureSegments) Create(ctx context.Context, featureSegment *v1beta1.FeatureSegment, opts v1.CreateOptions) (result *v1beta1.FeatureSegment, err error) {
+	result = &v1beta1.FeatureSegment{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("featuresegments").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(featureSegment).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a featureSegment and updates it. Returns the server's representation of the featureSegment, and an error, if there is any.
+func (c *featureSegments) Update(ctx context.Context, featureSegment *v1beta1.FeatureSegment, opts v1.UpdateOptions) (result *v1beta1.FeatureSegment, err error) {
+	result = &v1beta1.FeatureSegment{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("featuresegments").
+		Name(featureSegment.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(featureSegment).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the featureSegment and deletes it. Returns an error if one occurs.
+func (c *featureSegments) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("featuresegments").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *featureSegments) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("featuresegments").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched featureSegment.
+func (c *featureSegments) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.FeatureSegment, err error) {
+	result = &v1beta1.FeatureSegment{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("featuresegments").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	featurev1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	versioned "github.com/featured.io/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/featured.io/pkg/generated/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/featured.io/pkg/generated/listers/feature/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// FeatureSegmentInformer provides access to a shared informer and lister for
+// FeatureSegments.
+type FeatureSegmentInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.FeatureSegmentLister
+}
+
+type featureSegmentInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewFeatureSegmentInformer constructs a new informer for FeatureSegment type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFeatureSegmentInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredFeatureSegmentInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredFeatureSegmentInformer constructs a new informer for FeatureSegment type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredFeatureSegmentInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.FeaturecontrollerV1alpha1().FeatureSegments(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.FeaturecontrollerV1alpha1().FeatureSegments(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&featurev1alpha1.FeatureSegment{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *featureSegmentInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredFeatureSegmentInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *featureSegmentInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&featurev1alpha1.FeatureSegment{}, f.defaultInformer)
+}
+
+func (f *featureSegmentInformer) Lister() v1alpha1.FeatureSegmentLister {
+	return v1alpha1.NewFeatureSegmentLister(f.Informer().GetIndexer())
+}
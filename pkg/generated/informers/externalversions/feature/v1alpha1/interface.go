@@ -24,8 +24,12 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterFeatureFlags returns a ClusterFeatureFlagInformer.
+	ClusterFeatureFlags() ClusterFeatureFlagInformer
 	// FeatureFlags returns a FeatureFlagInformer.
 	FeatureFlags() FeatureFlagInformer
+	// FeatureSegments returns a FeatureSegmentInformer.
+	FeatureSegments() FeatureSegmentInformer
 }
 
 type version struct {
@@ -39,7 +43,17 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterFeatureFlags returns a ClusterFeatureFlagInformer.
+func (v *version) ClusterFeatureFlags() ClusterFeatureFlagInformer {
+	return &clusterFeatureFlagInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // FeatureFlags returns a FeatureFlagInformer.
 func (v *version) FeatureFlags() FeatureFlagInformer {
 	return &featureFlagInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// FeatureSegments returns a FeatureSegmentInformer.
+func (v *version) FeatureSegments() FeatureSegmentInformer {
+	return &featureSegmentInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"context"
+	time "time"
+
+	featurev1beta1 "github.com/featured.io/pkg/apis/feature/v1beta1"
+	versioned "github.com/featured.io/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/featured.io/pkg/generated/informers/externalversions/internalinterfaces"
+	v1beta1 "github.com/featured.io/pkg/generated/listers/feature/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// FeatureFlagInformer provides access to a shared informer and lister for
+// FeatureFlags.
+type FeatureFlagInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1beta1.FeatureFlagLister
+}
+
+type featureFlagInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewFeatureFlagInformer constructs a new informer for FeatureFlag type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFeatureFlagInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredFeatureFlagInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredFeatureFlagInformer constructs a new informer for FeatureFlag type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredFeatureFlagInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.FeaturecontrollerV1beta1().FeatureFlags(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.FeaturecontrollerV1beta1().FeatureFlags(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&featurev1beta1.FeatureFlag{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *featureFlagInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredFeatureFlagInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *featureFlagInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&featurev1beta1.FeatureFlag{}, f.defaultInformer)
+}
+
+func (f *featureFlagInformer) Lister() v1beta1.FeatureFlagLister {
+	return v1beta1.NewFeatureFlagLister(f.Informer().GetIndexer())
+}
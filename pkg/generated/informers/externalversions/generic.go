@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	v1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	v1beta1 "github.com/featured.io/pkg/apis/feature/v1beta1"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -53,8 +54,18 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=featurecontroller.featured.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("clusterfeatureflags"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Featurecontroller().V1alpha1().ClusterFeatureFlags().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("featureflags"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Featurecontroller().V1alpha1().FeatureFlags().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("featuresegments"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Featurecontroller().V1alpha1().FeatureSegments().Informer()}, nil
+
+		// Group=featurecontroller.featured.io, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("featureflags"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Featurecontroller().V1beta1().FeatureFlags().Informer()}, nil
+	case v1beta1.SchemeGroupVersion.WithResource("featuresegments"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Featurecontroller().V1beta1().FeatureSegments().Informer()}, nil
 
 	}
 
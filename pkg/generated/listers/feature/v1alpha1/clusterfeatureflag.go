@@ -0,0 +1,68 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ClusterFeatureFlagLister helps list ClusterFeatureFlags.
+// All objects returned here must be treated as read-only.
+type ClusterFeatureFlagLister interface {
+	// List lists all ClusterFeatureFlags in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.ClusterFeatureFlag, err error)
+	// Get retrieves the ClusterFeatureFlag from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.ClusterFeatureFlag, error)
+	ClusterFeatureFlagListerExpansion
+}
+
+// clusterFeatureFlagLister implements the ClusterFeatureFlagLister interface.
+type clusterFeatureFlagLister struct {
+	indexer cache.Indexer
+}
+
+// NewClusterFeatureFlagLister returns a new ClusterFeatureFlagLister.
+func NewClusterFeatureFlagLister(indexer cache.Indexer) ClusterFeatureFlagLister {
+	return &clusterFeatureFlagLister{indexer: indexer}
+}
+
+// List lists all ClusterFeatureFlags in the indexer.
+func (s *clusterFeatureFlagLister) List(selector labels.Selector) (ret []*v1alpha1.ClusterFeatureFlag, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.ClusterFeatureFlag))
+	})
+	return ret, err
+}
+
+// Get retrieves the ClusterFeatureFlag from the index for a given name.
+func (s *clusterFeatureFlagLister) Get(name string) (*v1alpha1.ClusterFeatureFlag, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("clusterfeatureflag"), name)
+	}
+	return obj.(*v1alpha1.ClusterFeatureFlag), nil
+}
@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1alpha1
 
+// ClusterFeatureFlagListerExpansion allows custom methods to be added to
+// ClusterFeatureFlagLister.
+type ClusterFeatureFlagListerExpansion interface{}
+
 // FeatureFlagListerExpansion allows custom methods to be added to
 // FeatureFlagLister.
 type FeatureFlagListerExpansion interface{}
@@ -25,3 +29,11 @@ type FeatureFlagListerExpansion interface{}
 // FeatureFlagNamespaceListerExpansion allows custom methods to be added to
 // FeatureFlagNamespaceLister.
 type FeatureFlagNamespaceListerExpansion interface{}
+
+// FeatureSegmentListerExpansion allows custom methods to be added to
+// FeatureSegmentLister.
+type FeatureSegmentListerExpansion interface{}
+
+// FeatureSegmentNamespaceListerExpansion allows custom methods to be added to
+// FeatureSegmentNamespaceLister.
+type FeatureSegmentNamespaceListerExpansion interface{}
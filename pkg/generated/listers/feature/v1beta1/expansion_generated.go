@@ -0,0 +1,35 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1beta1
+
+// FeatureFlagListerExpansion allows custom methods to be added to
+// FeatureFlagLister.
+type FeatureFlagListerExpansion interface{}
+
+// FeatureFlagNamespaceListerExpansion allows custom methods to be added to
+// FeatureFlagNamespaceLister.
+type FeatureFlagNamespaceListerExpansion interface{}
+
+// FeatureSegmentListerExpansion allows custom methods to be added to
+// FeatureSegmentLister.
+type FeatureSegmentListerExpansion interface{}
+
+// FeatureSegmentNamespaceListerExpansion allows custom methods to be added to
+// FeatureSegmentNamespaceLister.
+type FeatureSegmentNamespaceListerExpansion interface{}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 Danvir Guram
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	v1beta1 "github.com/featured.io/pkg/apis/feature/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// FeatureSegmentLister helps list FeatureSegments.
+// All objects returned here must be treated as read-only.
+type FeatureSegmentLister interface {
+	// List lists all FeatureSegments in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1beta1.FeatureSegment, err error)
+	// FeatureSegments returns an object that can list and get FeatureSegments.
+	FeatureSegments(namespace string) FeatureSegmentNamespaceLister
+	FeatureSegmentListerExpansion
+}
+
+// featureSegmentLister implements the FeatureSegmentLister interface.
+type featureSegmentLister struct {
+	indexer cache.Indexer
+}
+
+// NewFeatureSegmentLister returns a new FeatureSegmentLister.
+func NewFeatureSegmentLister(indexer cache.Indexer) FeatureSegmentLister {
+	return &featureSegmentLister{indexer: indexer}
+}
+
+// List lists all FeatureSegments in the indexer.
+func (s *featureSegmentLister) List(selector labels.Selector) (ret []*v1beta1.FeatureSegment, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1beta1.FeatureSegment))
+	})
+	return ret, err
+}
+
+// FeatureSegments returns an object that can list and get FeatureSegments.
+func (s *featureSegmentLister) FeatureSegments(namespace string) FeatureSegmentNamespaceLister {
+	return featureSegmentNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// FeatureSegmentNamespaceLister helps list and get FeatureSegments.
+// All objects returned here must be treated as read-only.
+type FeatureSegmentNamespaceLister interface {
+	// List lists all FeatureSegments in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1beta1.FeatureSegment, err error)
+	// Get retrieves the FeatureSegment from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1beta1.FeatureSegment, error)
+	FeatureSegmentNamespaceListerExpansion
+}
+
+// featureSegmentNamespaceLister implements the FeatureSegmentNamespaceLister
+// interface.
+type featureSegmentNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all FeatureSegments in the indexer for a given namespace.
+func (s featureSegmentNamespaceLister) List(selector labels.Selector) (ret []*v1beta1.FeatureSegment, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1beta1.FeatureSegment))
+	})
+	return ret, err
+}
+
+// Get retrieves the FeatureSegment from the indexer for a given namespace and name.
+func (s featureSegmentNamespaceLister) Get(name string) (*v1beta1.FeatureSegment, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1beta1.Resource("featuresegment"), name)
+	}
+	return obj.(*v1beta1.FeatureSegment), nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	featurecontroller "github.com/featured.io/pkg/apis/feature"
+)
+
+// ConversionPath is the path the conversion webhook is served on
+const ConversionPath = "/convert"
+
+// Converter converts objects between the versions registered in a scheme
+// through their hub version.
+type Converter struct {
+	scheme  *runtime.Scheme
+	decoder runtime.Decoder
+}
+
+// NewConverter returns a Converter for the hub and spoke kinds of the scheme.
+func NewConverter(scheme *runtime.Scheme) *Converter {
+	return &Converter{
+		scheme:  scheme,
+		decoder: serializer.NewCodecFactory(scheme).UniversalDeserializer(),
+	}
+}
+
+// Convert converts the object to the desired version. Objects already in the
+// desired version are returned as is.
+func (c *Converter) Convert(obj runtime.Object, desired schema.GroupVersion) (runtime.Object, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.GroupVersion() == desired {
+		return obj, nil
+	}
+
+	hub, err := c.toHub(obj, gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.scheme.New(desired.WithKind(gvk.Kind))
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := out.(featurecontroller.Hub); ok {
+		return hub, nil
+	}
+	spoke, ok := out.(featurecontroller.Convertible)
+	if !ok {
+		return nil, fmt.Errorf("%s is neither a hub nor convertible", desired.WithKind(gvk.Kind))
+	}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		return nil, err
+	}
+	return spoke, nil
+}
+
+// toHub converts the object to the hub version of its kind.
+func (c *Converter) toHub(obj runtime.Object, gvk schema.GroupVersionKind) (featurecontroller.Hub, error) {
+	if hub, ok := obj.(featurecontroller.Hub); ok {
+		return hub, nil
+	}
+	spoke, ok := obj.(featurecontroller.Convertible)
+	if !ok {
+		return nil, fmt.Errorf("%s is neither a hub nor convertible", gvk)
+	}
+
+	for _, gv := range c.scheme.PrioritizedVersionsForGroup(gvk.Group) {
+		out, err := c.scheme.New(gv.WithKind(gvk.Kind))
+		if err != nil {
+			continue
+		}
+		if hub, ok := out.(featurecontroller.Hub); ok {
+			if err := spoke.ConvertTo(hub); err != nil {
+				return nil, err
+			}
+			return hub, nil
+		}
+	}
+	return nil, fmt.Errorf("no hub version registered for %s", gvk.GroupKind())
+}
+
+// ServeHTTP answers a ConversionReview. apiextensions.k8s.io/v1 and v1beta1
+// reviews share the same schema, so the response is sent in the version of
+// the request.
+func (c *Converter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &apiextensionsv1.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		http.Error(w, fmt.Sprintf("cannot decode ConversionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = c.review(review.Request)
+	review.Request = nil
+	writeJSON(w, review)
+}
+
+func (c *Converter) review(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	response := &apiextensionsv1.ConversionResponse{UID: request.UID}
+
+	desired, err := schema.ParseGroupVersion(request.DesiredAPIVersion)
+	if err != nil {
+		response.Result = failure(err)
+		return response
+	}
+
+	for _, raw := range request.Objects {
+		obj, _, err := c.decoder.Decode(raw.Raw, nil, nil)
+		if err != nil {
+			response.Result = failure(err)
+			return response
+		}
+		converted, err := c.Convert(obj, desired)
+		if err != nil {
+			response.Result = failure(err)
+			return response
+		}
+		out, err := json.Marshal(converted)
+		if err != nil {
+			response.Result = failure(err)
+			return response
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: out})
+	}
+
+	response.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return response
+}
+
+func failure(err error) metav1.Status {
+	return metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/generated/clientset/versioned/scheme"
+)
+
+const v1alpha1Manifest = `{
+	"apiVersion": "featurecontroller.featured.io/v1alpha1",
+	"kind": "FeatureFlag",
+	"metadata": {"name": "new-checkout", "namespace": "default", "uid": "1234"},
+	"spec": {
+		"configmapName": "checkout-flags",
+		"type": "boolean",
+		"enabled": true,
+		"variations": [{"name": "on", "value": "true"}, {"name": "off", "value": "false"}],
+		"defaultVariation": "on",
+		"offVariation": "off"
+	}
+}`
+
+func review(t *testing.T, desiredAPIVersion string, objects ...string) *apiextensionsv1.ConversionReview {
+	request := &apiextensionsv1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1beta1", Kind: "ConversionReview"},
+		Request:  &apiextensionsv1.ConversionRequest{UID: "review-1", DesiredAPIVersion: desiredAPIVersion},
+	}
+	for _, object := range objects {
+		request.Request.Objects = append(request.Request.Objects, runtime.RawExtension{Raw: []byte(object)})
+	}
+	body, err := json.Marshal(request)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	NewConverter(scheme.Scheme).ServeHTTP(w, httptest.NewRequest(http.MethodPost, ConversionPath, bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	response := &apiextensionsv1.ConversionReview{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), response))
+	require.Equal(t, "apiextensions.k8s.io/v1beta1", response.APIVersion)
+	require.Equal(t, "review-1", string(response.Response.UID))
+	return response
+}
+
+func spec(t *testing.T, object runtime.RawExtension) map[string]interface{} {
+	var converted map[string]interface{}
+	require.NoError(t, json.Unmarshal(object.Raw, &converted))
+	return converted["spec"].(map[string]interface{})
+}
+
+// TestConversionReview tests that a v1alpha1 manifest converts to v1beta1 and back through the webhook
+func TestConversionReview(t *testing.T) {
+	response := review(t, "featurecontroller.featured.io/v1beta1", v1alpha1Manifest)
+	require.Equal(t, metav1.StatusSuccess, response.Response.Result.Status)
+	require.Len(t, response.Response.ConvertedObjects, 1)
+	require.Equal(t, "checkout-flags", spec(t, response.Response.ConvertedObjects[0])["configMapName"])
+
+	back := review(t, "featurecontroller.featured.io/v1alpha1", string(response.Response.ConvertedObjects[0].Raw))
+	require.Equal(t, metav1.StatusSuccess, back.Response.Result.Status)
+	expected, actual := &v1alpha1.FeatureFlag{}, &v1alpha1.FeatureFlag{}
+	require.NoError(t, json.Unmarshal([]byte(v1alpha1Manifest), expected))
+	require.NoError(t, json.Unmarshal(back.Response.ConvertedObjects[0].Raw, actual))
+	require.Equal(t, expected, actual)
+}
+
+// TestConversionReviewFailure tests that objects that cannot be converted fail the review
+func TestConversionReviewFailure(t *testing.T) {
+	response := review(t, "featurecontroller.featured.io/v2", v1alpha1Manifest)
+	require.Equal(t, metav1.StatusFailure, response.Response.Result.Status)
+	require.Empty(t, response.Response.ConvertedObjects)
+
+	response = review(t, "featurecontroller.featured.io/v1beta1", `{"apiVersion": "featurecontroller.featured.io/v1alpha1", "kind": "Unknown"}`)
+	require.Equal(t, metav1.StatusFailure, response.Response.Result.Status)
+}
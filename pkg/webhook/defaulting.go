@@ -0,0 +1,103 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+)
+
+// DefaultingPath is the path the FeatureFlag defaulting webhook is served on
+const DefaultingPath = "/default-featureflags"
+
+// FeatureFlagDefaulter fills in the defaults of FeatureFlags before they are
+// stored, so the stored object is as explicit as what the controller publishes.
+type FeatureFlagDefaulter struct {
+	scheme    *runtime.Scheme
+	converter *Converter
+}
+
+// NewFeatureFlagDefaulter returns a FeatureFlagDefaulter for FeatureFlags of
+// any version registered in the scheme.
+func NewFeatureFlagDefaulter(scheme *runtime.Scheme) *FeatureFlagDefaulter {
+	return &FeatureFlagDefaulter{scheme: scheme, converter: NewConverter(scheme)}
+}
+
+// Default returns a copy of the object, in the same version, with the
+// v1alpha1 defaults set.
+func (d *FeatureFlagDefaulter) Default(obj runtime.Object) (runtime.Object, error) {
+	gv := obj.GetObjectKind().GroupVersionKind().GroupVersion()
+	converted, err := d.converter.Convert(obj.DeepCopyObject(), v1alpha1.SchemeGroupVersion)
+	if err != nil {
+		return nil, err
+	}
+	d.scheme.Default(converted)
+	return d.converter.Convert(converted, gv)
+}
+
+// ServeHTTP answers an AdmissionReview for a FeatureFlag with a JSON Patch
+// setting its defaults.
+func (d *FeatureFlagDefaulter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveAdmission(w, r, d.admit)
+}
+
+func (d *FeatureFlagDefaulter) admit(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation == admissionv1.Delete {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	patch, err := d.patch(request.Object.Raw)
+	if err != nil {
+		return &admissionv1.AdmissionResponse{Result: status(err)}
+	}
+	if patch == nil {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{Allowed: true, Patch: patch, PatchType: &patchType}
+}
+
+// patch returns the JSON Patch setting the defaults of the raw object, or nil
+// when it has none to set. Both sides are encoded from the decoded object, so
+// the patch only holds what defaulting changed.
+func (d *FeatureFlagDefaulter) patch(raw []byte) ([]byte, error) {
+	obj, _, err := d.converter.decoder.Decode(raw, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defaulted, err := d.Default(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	to, err := json.Marshal(defaulted)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := createPatch(from, to)
+	if err != nil || len(ops) == 0 {
+		return nil, err
+	}
+	return json.Marshal(ops)
+}
@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/v1beta1"
+)
+
+func patchOf(t *testing.T, response *admissionv1.AdmissionResponse) []patchOperation {
+	require.True(t, response.Allowed, "unexpected rejection: %v", response.Result)
+	if response.Patch == nil {
+		require.Nil(t, response.PatchType)
+		return nil
+	}
+	require.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
+	var ops []patchOperation
+	require.NoError(t, json.Unmarshal(response.Patch, &ops))
+	return ops
+}
+
+// TestDefaultingWebhook tests that a half-specified boolean FeatureFlag is patched to be fully explicit
+func TestDefaultingWebhook(t *testing.T) {
+	ts := newTestServer(t)
+
+	featureflag := newFeatureFlag()
+	featureflag.Spec.ConfigMapName = ""
+	featureflag.Spec.Variations = nil
+	featureflag.Spec.DefaultVariation = ""
+	featureflag.Spec.OffVariation = ""
+	featureflag.Spec.DefaultRollout = &v1alpha1.Rollout{Variations: []v1alpha1.WeightedVariation{
+		{Variation: "on", Weight: 50},
+		{Variation: "off", Weight: 50},
+	}}
+
+	ops := patchOf(t, admit(t, ts, DefaultingPath, admissionv1.Create, featureflag))
+	require.Equal(t, []patchOperation{
+		{Op: "add", Path: "/metadata/labels", Value: map[string]interface{}{v1alpha1.ManagedByLabel: v1alpha1.ManagedBy}},
+		{Op: "add", Path: "/spec/configmapName", Value: "new-checkout"},
+		{Op: "add", Path: "/spec/defaultRollout/salt", Value: "new-checkout"},
+		{Op: "add", Path: "/spec/defaultVariation", Value: "on"},
+		{Op: "add", Path: "/spec/format", Value: "json"},
+		{Op: "add", Path: "/spec/offVariation", Value: "off"},
+		{Op: "add", Path: "/spec/target", Value: "ConfigMap"},
+		{Op: "add", Path: "/spec/variations", Value: []interface{}{
+			map[string]interface{}{"name": "on", "value": "true"},
+			map[string]interface{}{"name": "off", "value": "false"},
+		}},
+	}, ops)
+}
+
+// TestDefaultingWebhookExplicit tests that FeatureFlags with nothing left to default are not patched
+func TestDefaultingWebhookExplicit(t *testing.T) {
+	ts := newTestServer(t)
+
+	featureflag := newFeatureFlag()
+	featureflag.Labels = map[string]string{v1alpha1.ManagedByLabel: "helm"}
+	featureflag.Spec.Format = v1alpha1.PayloadFormatYAML
+	featureflag.Spec.Target = v1alpha1.PublishTargetConfigMap
+
+	require.Empty(t, patchOf(t, admit(t, ts, DefaultingPath, admissionv1.Update, featureflag)))
+	require.Empty(t, patchOf(t, admit(t, ts, DefaultingPath, admissionv1.Delete, featureflag)))
+}
+
+// TestDefaultingWebhookVersions tests that v1beta1 FeatureFlags are patched using v1beta1 field names
+func TestDefaultingWebhookVersions(t *testing.T) {
+	ts := newTestServer(t)
+
+	featureflag := newFeatureFlag()
+	featureflag.Labels = map[string]string{"team": "checkout"}
+	featureflag.Spec.ConfigMapName = ""
+	featureflag.Spec.Format = v1alpha1.PayloadFormatJSON
+	spoke := &v1beta1.FeatureFlag{}
+	require.NoError(t, spoke.ConvertFrom(featureflag))
+
+	ops := patchOf(t, admit(t, ts, DefaultingPath, admissionv1.Create, spoke))
+	require.Equal(t, []patchOperation{
+		{Op: "add", Path: "/metadata/labels/app.kubernetes.io~1managed-by", Value: v1alpha1.ManagedBy},
+		{Op: "add", Path: "/spec/configMapName", Value: "new-checkout"},
+		{Op: "add", Path: "/spec/target", Value: "ConfigMap"},
+	}, ops)
+}
+
+// TestCreatePatch tests that member names are escaped and removed members are removed
+func TestCreatePatch(t *testing.T) {
+	ops, err := createPatch(
+		[]byte(`{"a/b":1,"c~d":[1,2],"e":{"f":true},"g":"h"}`),
+		[]byte(`{"a/b":2,"c~d":[1,2],"e":{"f":false,"i":null}}`))
+	require.NoError(t, err)
+	require.Equal(t, []patchOperation{
+		{Op: "remove", Path: "/g"},
+		{Op: "add", Path: "/a~1b", Value: float64(2)},
+		{Op: "add", Path: "/e/f", Value: false},
+		{Op: "add", Path: "/e/i"},
+	}, ops)
+}
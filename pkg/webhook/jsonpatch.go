@@ -0,0 +1,86 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// patchOperation is a JSON Patch (RFC 6902) operation.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// createPatch returns the JSON Patch turning the JSON document from into to.
+// Objects are compared member by member; any other changed value, including
+// an array, is replaced as a whole. Members are set with "add", which also
+// replaces existing members, so the patch applies to documents that omit
+// members from holds with their zero value.
+func createPatch(from, to []byte) ([]patchOperation, error) {
+	var fromDoc, toDoc interface{}
+	if err := json.Unmarshal(from, &fromDoc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to, &toDoc); err != nil {
+		return nil, err
+	}
+	return diff("", fromDoc, toDoc), nil
+}
+
+func diff(path string, from, to interface{}) []patchOperation {
+	fromObj, fromIsObj := from.(map[string]interface{})
+	toObj, toIsObj := to.(map[string]interface{})
+	if !fromIsObj || !toIsObj {
+		if reflect.DeepEqual(from, to) {
+			return nil
+		}
+		return []patchOperation{{Op: "add", Path: path, Value: to}}
+	}
+
+	var ops []patchOperation
+	for _, key := range sortedKeys(fromObj) {
+		if _, ok := toObj[key]; !ok {
+			ops = append(ops, patchOperation{Op: "remove", Path: path + "/" + escapePointer(key)})
+		}
+	}
+	for _, key := range sortedKeys(toObj) {
+		memberPath := path + "/" + escapePointer(key)
+		if fromValue, ok := fromObj[key]; ok {
+			ops = append(ops, diff(memberPath, fromValue, toObj[key])...)
+		} else {
+			ops = append(ops, patchOperation{Op: "add", Path: memberPath, Value: toObj[key]})
+		}
+	}
+	return ops
+}
+
+// escapePointer escapes a member name for use in a JSON Pointer (RFC 6901).
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
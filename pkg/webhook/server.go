@@ -0,0 +1,85 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CertFileName is the name of the serving certificate in the cert dir
+	CertFileName = "tls.crt"
+	// KeyFileName is the name of the serving key in the cert dir
+	KeyFileName = "tls.key"
+)
+
+// Server serves the webhooks of the operator over TLS. The API server only
+// calls webhooks over HTTPS.
+type Server struct {
+	addr    string
+	certDir string
+	mux     *http.ServeMux
+	server  *http.Server
+	logger  *log.Entry
+}
+
+// NewServer returns a Server listening on addr with the tls.crt and tls.key
+// found in certDir, such as a mounted kubernetes.io/tls Secret.
+func NewServer(addr, certDir string) *Server {
+	s := &Server{
+		addr:    addr,
+		certDir: certDir,
+		mux:     http.NewServeMux(),
+		logger:  log.WithFields(log.Fields{"service": "webhook"}),
+	}
+	s.server = &http.Server{Addr: addr, Handler: s}
+	return s
+}
+
+// Handle registers the handler for the webhook served on path.
+func (s *Server) Handle(path string, handler http.Handler) {
+	s.mux.Handle(path, handler)
+}
+
+// ServeHTTP dispatches the request to the webhook registered for its path,
+// which lets tests serve the webhooks in-process with net/http/httptest.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the registered webhooks. It always returns a non-nil
+// error, which is http.ErrServerClosed after Shutdown.
+func (s *Server) ListenAndServe() error {
+	s.logger.WithFields(log.Fields{"address": s.addr}).Info("serving webhooks")
+	return s.server.ListenAndServeTLS(filepath.Join(s.certDir, CertFileName), filepath.Join(s.certDir, KeyFileName))
+}
+
+// Shutdown stops serving the webhooks, waiting for the requests in flight
+// until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithFields(log.Fields{"service": "webhook"}).Errorf("cannot write response: %v", err)
+	}
+}
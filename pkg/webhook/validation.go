@@ -0,0 +1,100 @@
+// Copyright 2020 Danvir Guram. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/validation"
+	"github.com/featured.io/pkg/evaluation"
+)
+
+// ValidatingPath is the path the FeatureFlag validating webhook is served on
+const ValidatingPath = "/validate-featureflags"
+
+// FeatureFlagValidator rejects FeatureFlags that the controller would not
+// publish, so that mistakes are reported by kubectl rather than in the status.
+type FeatureFlagValidator struct {
+	converter *Converter
+}
+
+// NewFeatureFlagValidator returns a FeatureFlagValidator for FeatureFlags of
+// any version registered in the scheme.
+func NewFeatureFlagValidator(scheme *runtime.Scheme) *FeatureFlagValidator {
+	return &FeatureFlagValidator{converter: NewConverter(scheme)}
+}
+
+// Validate returns every error found in the FeatureFlag. Objects of other
+// versions are converted to v1alpha1 first, so field paths use the v1alpha1
+// field names.
+func (v *FeatureFlagValidator) Validate(obj runtime.Object) error {
+	converted, err := v.converter.Convert(obj, v1alpha1.SchemeGroupVersion)
+	if err != nil {
+		return err
+	}
+	featureflag, ok := converted.(*v1alpha1.FeatureFlag)
+	if !ok {
+		return fmt.Errorf("expected a FeatureFlag, got %T", converted)
+	}
+
+	// A FeatureFlag being deleted is only updated to remove its finalizer,
+	// which must not be blocked by a spec that was accepted before.
+	if featureflag.DeletionTimestamp != nil {
+		return nil
+	}
+
+	allErrs := validation.ValidateFeatureFlag(featureflag)
+	allErrs = append(allErrs, evaluation.ValidateRules(featureflag)...)
+	if len(allErrs) > 0 {
+		return errors.NewInvalid(v1alpha1.Kind("FeatureFlag"), featureflag.Name, allErrs)
+	}
+	return nil
+}
+
+// ServeHTTP answers an AdmissionReview for a FeatureFlag.
+func (v *FeatureFlagValidator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveAdmission(w, r, v.admit)
+}
+
+func (v *FeatureFlagValidator) admit(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation == admissionv1.Delete {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	obj, _, err := v.converter.decoder.Decode(request.Object.Raw, nil, nil)
+	if err == nil {
+		err = v.Validate(obj)
+	}
+	if err != nil {
+		return &admissionv1.AdmissionResponse{Result: status(err)}
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+// status returns the Status of an API error, and a BadRequest for others.
+func status(err error) *metav1.Status {
+	if statusErr, ok := err.(errors.APIStatus); ok {
+		status := statusErr.Status()
+		return &status
+	}
+	return &errors.NewBadRequest(err.Error()).ErrStatus
+}
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/featured.io/pkg/apis/feature/v1alpha1"
+	"github.com/featured.io/pkg/apis/feature/v1beta1"
+	"github.com/featured.io/pkg/generated/clientset/versioned/scheme"
+)
+
+// newTestServer serves the webhooks of the operator in-process over TLS.
+func newTestServer(t *testing.T) *httptest.Server {
+	server := NewServer("", "")
+	server.Handle(ConversionPath, NewConverter(scheme.Scheme))
+	server.Handle(ValidatingPath, NewFeatureFlagValidator(scheme.Scheme))
+	server.Handle(DefaultingPath, NewFeatureFlagDefaulter(scheme.Scheme))
+	ts := httptest.NewTLSServer(server)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newFeatureFlag() *v1alpha1.FeatureFlag {
+	return &v1alpha1.FeatureFlag{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "FeatureFlag"},
+		ObjectMeta: metav1.ObjectMeta{Name: "new-checkout", Namespace: metav1.NamespaceDefault},
+		Spec: v1alpha1.FeatureFlagSpec{
+			ConfigMapName: "checkout-flags",
+			FlagSpec: v1alpha1.FlagSpec{
+				Type:    v1alpha1.FlagTypeBoolean,
+				Enabled: true,
+				Variations: []v1alpha1.Variation{
+					{Name: "on", Value: "true"},
+					{Name: "off", Value: "false"},
+				},
+				DefaultVariation: "on",
+				OffVariation:     "off",
+			},
+		},
+	}
+}
+
+func admit(t *testing.T, ts *httptest.Server, path string, operation admissionv1.Operation, obj runtime.Object) *admissionv1.AdmissionResponse {
+	raw, err := json.Marshal(obj)
+	require.NoError(t, err)
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "review-1",
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := ts.Client().Post(ts.URL+path, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	review := &admissionv1.AdmissionReview{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(review))
+	require.Equal(t, "admission.k8s.io/v1", review.APIVersion)
+	require.Equal(t, "review-1", string(review.Response.UID))
+	return review.Response
+}
+
+// TestValidatingWebhook tests that invalid FeatureFlags are rejected with the path of every invalid field
+func TestValidatingWebhook(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name      string
+		mutate    func(featureflag *v1alpha1.FeatureFlag)
+		expFields []string
+	}{
+		{
+			name:   "A valid flag should be allowed.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {},
+		},
+		{
+			name: "An empty ConfigMap name should be rejected.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {
+				featureflag.Spec.ConfigMapName = ""
+			},
+			expFields: []string{"spec.configmapName"},
+		},
+		{
+			name: "Duplicate variation names should be rejected.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {
+				featureflag.Spec.Variations[1].Name = "on"
+				featureflag.Spec.OffVariation = "on"
+			},
+			expFields: []string{"spec.variations[1].name"},
+		},
+		{
+			name: "An unknown default variation should be rejected.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {
+				featureflag.Spec.DefaultVariation = "maybe"
+			},
+			expFields: []string{"spec.defaultVariation"},
+		},
+		{
+			name: "An unknown rule operator should be rejected.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {
+				featureflag.Spec.Rules = []v1alpha1.Rule{{
+					Clauses:   []v1alpha1.Clause{{Attribute: "country", Operator: "like", Values: []string{"GB"}}},
+					Variation: "off",
+				}}
+			},
+			expFields: []string{"spec.rules[0].clauses[0].operator"},
+		},
+		{
+			name: "Rollout weights that do not add up to 100 should be rejected.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {
+				featureflag.Spec.DefaultRollout = &v1alpha1.Rollout{Variations: []v1alpha1.WeightedVariation{
+					{Variation: "on", Weight: 60},
+					{Variation: "off", Weight: 30},
+				}}
+			},
+			expFields: []string{"spec.defaultRollout.variations"},
+		},
+		{
+			name: "An invalid flag being deleted should be allowed to drop its finalizer.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {
+				featureflag.Spec.ConfigMapName = ""
+				featureflag.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			},
+		},
+		{
+			name: "Rules referencing segments that do not exist yet should be allowed.",
+			mutate: func(featureflag *v1alpha1.FeatureFlag) {
+				featureflag.Spec.Rules = []v1alpha1.Rule{{
+					Clauses:   []v1alpha1.Clause{{Operator: v1alpha1.OperatorInSegment, Values: []string{"beta-testers"}}},
+					Variation: "off",
+				}}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			featureflag := newFeatureFlag()
+			test.mutate(featureflag)
+
+			response := admit(t, ts, ValidatingPath, admissionv1.Create, featureflag)
+
+			if len(test.expFields) == 0 {
+				require.True(t, response.Allowed, "unexpected rejection: %v", response.Result)
+				return
+			}
+			require.False(t, response.Allowed)
+			require.Equal(t, metav1.StatusReasonInvalid, response.Result.Reason)
+			require.Len(t, response.Result.Details.Causes, len(test.expFields))
+			for i, field := range test.expFields {
+				require.Equal(t, field, response.Result.Details.Causes[i].Field)
+			}
+		})
+	}
+}
+
+// TestValidatingWebhookVersions tests that v1beta1 FeatureFlags are validated and deletes are allowed
+func TestValidatingWebhookVersions(t *testing.T) {
+	ts := newTestServer(t)
+
+	featureflag := newFeatureFlag()
+	featureflag.Spec.ConfigMapName = ""
+	spoke := &v1beta1.FeatureFlag{}
+	require.NoError(t, spoke.ConvertFrom(featureflag))
+
+	response := admit(t, ts, ValidatingPath, admissionv1.Update, spoke)
+	require.False(t, response.Allowed)
+	require.Contains(t, response.Result.Message, `FeatureFlag.featurecontroller.featured.io "new-checkout" is invalid`)
+
+	response = admit(t, ts, ValidatingPath, admissionv1.Delete, featureflag)
+	require.True(t, response.Allowed)
+}